@@ -0,0 +1,236 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func getAddressCollection() *mongo.Collection {
+	return Database.Collection("addresses")
+}
+
+func GetAddresses(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var addresses []Models.SavedAddress
+	opts := options.Find().SetSort(bson.D{{Key: "is_default", Value: -1}, {Key: "created_at", Value: -1}})
+	cursor, err := getAddressCollection().Find(ctx, bson.M{"user_id": claims.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get addresses"})
+		return
+	}
+	if err := cursor.All(ctx, &addresses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+func CreateAddress(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var address Models.SavedAddress
+	if err := c.ShouldBindJSON(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateSavedAddress(address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := getAddressCollection().CountDocuments(ctx, bson.M{"user_id": claims.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+
+	address.ID = primitive.NewObjectID()
+	address.UserID = claims.ID
+	address.IsDefault = address.IsDefault || count == 0
+	address.CreatedAt = time.Now()
+	address.UpdatedAt = time.Now()
+
+	if address.IsDefault {
+		if err := clearDefaultAddress(ctx, claims.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+			return
+		}
+	}
+
+	if _, err := getAddressCollection().InsertOne(ctx, address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+func UpdateAddress(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	addressID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	var address Models.SavedAddress
+	if err := c.ShouldBindJSON(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateSavedAddress(address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := loadSavedAddress(ctx, claims.ID, addressID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	// An address stops being the default only by making another one default.
+	address.IsDefault = address.IsDefault || existing.IsDefault
+	if address.IsDefault && !existing.IsDefault {
+		if err := clearDefaultAddress(ctx, claims.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+			return
+		}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"label":      address.Label,
+		"recipient":  address.Recipient,
+		"phone":      address.Phone,
+		"location":   address.Location,
+		"is_default": address.IsDefault,
+		"updated_at": time.Now(),
+	}}
+	if _, err := getAddressCollection().UpdateOne(ctx, bson.M{"_id": addressID, "user_id": claims.ID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		return
+	}
+
+	address.ID = existing.ID
+	address.UserID = existing.UserID
+	address.CreatedAt = existing.CreatedAt
+	address.UpdatedAt = time.Now()
+	c.JSON(http.StatusOK, address)
+}
+
+func DeleteAddress(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	addressID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := loadSavedAddress(ctx, claims.ID, addressID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	if _, err := getAddressCollection().DeleteOne(ctx, bson.M{"_id": addressID, "user_id": claims.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+
+	if existing.IsDefault {
+		opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		err := getAddressCollection().FindOneAndUpdate(ctx, bson.M{"user_id": claims.ID}, bson.M{"$set": bson.M{"is_default": true}}, opts).Err()
+		if err != nil && err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Address deleted but failed to set a new default address"})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func SetDefaultAddress(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	addressID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := loadSavedAddress(ctx, claims.ID, addressID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	if err := clearDefaultAddress(ctx, claims.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default address"})
+		return
+	}
+	update := bson.M{"$set": bson.M{"is_default": true, "updated_at": time.Now()}}
+	if _, err := getAddressCollection().UpdateOne(ctx, bson.M{"_id": addressID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Default address updated"})
+}
+
+func validateSavedAddress(address Models.SavedAddress) error {
+	if address.Recipient == "" || address.Phone == "" {
+		return errors.New("Recipient and phone are required")
+	}
+	if address.Location.Street == "" || address.Location.District == "" || address.Location.Province == "" {
+		return errors.New("Street, district and province are required")
+	}
+	return nil
+}
+
+func loadSavedAddress(ctx context.Context, userID, addressID primitive.ObjectID) (Models.SavedAddress, error) {
+	var address Models.SavedAddress
+	err := getAddressCollection().FindOne(ctx, bson.M{"_id": addressID, "user_id": userID}).Decode(&address)
+	return address, err
+}
+
+func loadDefaultAddress(ctx context.Context, userID primitive.ObjectID) (Models.SavedAddress, error) {
+	var address Models.SavedAddress
+	err := getAddressCollection().FindOne(ctx, bson.M{"user_id": userID, "is_default": true}).Decode(&address)
+	return address, err
+}
+
+func clearDefaultAddress(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := getAddressCollection().UpdateMany(ctx, bson.M{"user_id": userID, "is_default": true}, bson.M{"$set": bson.M{"is_default": false}})
+	return err
+}
@@ -0,0 +1,73 @@
+package Controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Server/Middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDeleteAddressReportsFailedDefaultPromotion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	run := func(mt *mtest.T, promotion bson.D) int {
+		Database = mt.DB
+		userID := primitive.NewObjectID()
+		addressID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.addresses", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: addressID},
+				{Key: "user_id", Value: userID},
+				{Key: "is_default", Value: true},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			promotion,
+		)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodDelete, "/addresses/"+addressID.Hex(), nil)
+		c.Params = gin.Params{{Key: "id", Value: addressID.Hex()}}
+		c.Set("user", &Middleware.UserClaims{ID: userID, Role: Middleware.Customer})
+		DeleteAddress(c)
+		return c.Writer.Status()
+	}
+
+	mt.Run("promotion fails", func(mt *mtest.T) {
+		failure := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"})
+		if code := run(mt, failure); code != http.StatusInternalServerError {
+			mt.Fatalf("status = %d, want %d", code, http.StatusInternalServerError)
+		}
+	})
+
+	mt.Run("last address", func(mt *mtest.T) {
+		none := bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}}
+		if code := run(mt, none); code != http.StatusNoContent {
+			mt.Fatalf("status = %d, want %d", code, http.StatusNoContent)
+		}
+	})
+}
+
+func TestCreateOrderValidatesInlineAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	body := `{"recipient": "Lan", "phone": "0900000000", "address": {"street": "12 Le Loi", "province": "Ho Chi Minh"}}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", &Middleware.UserClaims{ID: primitive.NewObjectID(), Role: Middleware.Customer})
+
+	CreateOrder(c)
+
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "district") {
+		t.Fatalf("status = %d, body %s; want a 400 about the missing district", recorder.Code, recorder.Body)
+	}
+}
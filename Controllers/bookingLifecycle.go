@@ -0,0 +1,73 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	BookingPending              = "pending"
+	BookingConfirmed            = "confirmed"
+	BookingInProgress           = "in-progress"
+	BookingAwaitingConfirmation = "awaiting-confirmation"
+	BookingCompleted            = "completed"
+	BookingCancelled            = "cancelled"
+)
+
+var bookingTransitions = map[string][]string{
+	BookingPending:              {BookingConfirmed, BookingCancelled},
+	BookingConfirmed:            {BookingInProgress, BookingCancelled},
+	BookingInProgress:           {BookingAwaitingConfirmation},
+	BookingAwaitingConfirmation: {BookingCompleted},
+}
+
+var errBookingStatusChanged = errors.New("Booking status has changed, please reload")
+
+func canTransitionBooking(from, to string) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionBooking moves a booking from one status to the next and applies
+// any extra fields in the same write. The status is part of the filter so a
+// concurrent update is reported instead of silently overwritten.
+func transitionBooking(ctx context.Context, bookingID primitive.ObjectID, from, to string, set bson.M) error {
+	if !canTransitionBooking(from, to) {
+		return errors.New("Cannot change booking status from " + from + " to " + to)
+	}
+
+	fields := bson.M{
+		"status":     to,
+		"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+	}
+	for key, value := range set {
+		fields[key] = value
+	}
+
+	result, err := getOrderBookingServiceCollection().UpdateOne(ctx, bson.M{"_id": bookingID, "status": from}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errBookingStatusChanged
+	}
+
+	if to == BookingCancelled {
+		if err := releaseBookingSlots(ctx, bson.M{"_id": bookingID}); err != nil {
+			log.Println("Error releasing booking slots:", err)
+		}
+		if err := releaseCoupon(ctx, PaymentTargetBooking, bookingID); err != nil {
+			log.Println("Error releasing coupon:", err)
+		}
+	}
+	return nil
+}
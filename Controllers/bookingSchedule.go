@@ -0,0 +1,385 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultServiceDuration = 120 * time.Minute
+
+// legacyBookingWindow bounds how long before a range a booking without a
+// stored end may start and still overlap it.
+const legacyBookingWindow = 24 * time.Hour
+
+var bookingLocation = time.FixedZone("ICT", 7*60*60)
+
+var errSlotFull = errors.New("Selected time slot is fully booked")
+
+func getBookingSlotCollection() *mongo.Collection {
+	return Database.Collection("booking_slots")
+}
+
+type bookingSlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Remaining int       `json:"remaining"`
+}
+
+type dayAvailability struct {
+	Date  string        `json:"date"`
+	Slots []bookingSlot `json:"slots"`
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func validateBookingSettings(settings Models.BookingSettings) error {
+	open, err := parseClock(settings.OpenTime)
+	if err != nil {
+		return err
+	}
+	closing, err := parseClock(settings.CloseTime)
+	if err != nil {
+		return err
+	}
+	if closing <= open {
+		return errors.New("Close time must be after open time")
+	}
+	if settings.SlotMinutes <= 0 || settings.SlotCapacity <= 0 {
+		return errors.New("Slot length and capacity must be positive")
+	}
+	for _, day := range settings.WorkingDays {
+		if day < 0 || day > 6 {
+			return errors.New("Working days must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	for _, date := range settings.BlackoutDates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("Invalid blackout date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	return nil
+}
+
+// serviceDuration is how long a booking of the service takes. Hourly priced
+// services last the hours booked, with the service minimum applied the same
+// way as when they are priced.
+func serviceDuration(service Models.Service, hours float64) time.Duration {
+	if service.Pricing != nil && service.Pricing.HourlyRate.Sign() > 0 {
+		hours = math.Max(hours, service.Pricing.MinHours)
+	}
+	if hours > 0 {
+		return time.Duration(math.Round(hours*60)) * time.Minute
+	}
+	if service.DurationMinutes > 0 {
+		return time.Duration(service.DurationMinutes) * time.Minute
+	}
+	return defaultServiceDuration
+}
+
+// slotCapacity is the number of bookings of a service that may share a slot.
+func slotCapacity(settings Models.BookingSettings, service Models.Service) int {
+	if service.SlotCapacity > 0 {
+		return service.SlotCapacity
+	}
+	return settings.SlotCapacity
+}
+
+func isWorkingDay(settings Models.BookingSettings, day time.Time) bool {
+	date := day.Format("2006-01-02")
+	for _, blackout := range settings.BlackoutDates {
+		if blackout == date {
+			return false
+		}
+	}
+	for _, weekday := range settings.WorkingDays {
+		if time.Weekday(weekday) == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func validateBookingTime(settings Models.BookingSettings, start time.Time, duration time.Duration) error {
+	local := start.In(bookingLocation)
+	if start.Before(time.Now().Add(time.Duration(settings.MinLeadMinutes) * time.Minute)) {
+		return fmt.Errorf("Bookings must be made at least %d minutes in advance", settings.MinLeadMinutes)
+	}
+	if !isWorkingDay(settings, local) {
+		return errors.New("We are closed on the selected day")
+	}
+
+	open, _ := parseClock(settings.OpenTime)
+	closing, _ := parseClock(settings.CloseTime)
+	startMinute := local.Hour()*60 + local.Minute()
+	endMinute := startMinute + int(duration/time.Minute)
+	if local.Second() != 0 || startMinute < open || endMinute > closing {
+		return fmt.Errorf("Bookings must start and finish between %s and %s", settings.OpenTime, settings.CloseTime)
+	}
+	if (startMinute-open)%settings.SlotMinutes != 0 {
+		return fmt.Errorf("Bookings must start on a %d minute slot", settings.SlotMinutes)
+	}
+	return nil
+}
+
+// overlapFilter matches bookings that overlap [from, to). Bookings stored
+// before booking_end existed are matched on their start alone and their end
+// is filled in by findOverlappingBookings.
+func overlapFilter(from, to time.Time) bson.M {
+	return bson.M{
+		"booking_date": bson.M{"$lt": primitive.NewDateTimeFromTime(to)},
+		"$or": []bson.M{
+			{"booking_end": bson.M{"$gt": primitive.NewDateTimeFromTime(from)}},
+			{
+				"booking_end":  bson.M{"$in": bson.A{nil, primitive.DateTime(0)}},
+				"booking_date": bson.M{"$lt": primitive.NewDateTimeFromTime(to), "$gt": primitive.NewDateTimeFromTime(from.Add(-legacyBookingWindow))},
+			},
+		},
+	}
+}
+
+// findOverlappingBookings returns the bookings matching filter that overlap
+// [from, to). A booking without a stored end is taken to last the duration of
+// its service.
+func findOverlappingBookings(ctx context.Context, filter bson.M, from, to time.Time) ([]Models.OrderBookingService, error) {
+	for key, value := range overlapFilter(from, to) {
+		filter[key] = value
+	}
+
+	var candidates []Models.OrderBookingService
+	cursor, err := getOrderBookingServiceCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	services := make(map[primitive.ObjectID]Models.Service)
+	bookings := candidates[:0]
+	for _, booking := range candidates {
+		if booking.BookingEnd == 0 {
+			service, ok := services[booking.ServiceID]
+			if !ok {
+				err := getServiceCollection().FindOne(ctx, bson.M{"_id": booking.ServiceID}).Decode(&service)
+				if err != nil && err != mongo.ErrNoDocuments {
+					return nil, err
+				}
+				services[booking.ServiceID] = service
+			}
+			end := booking.BookingDate.Time().Add(serviceDuration(service, booking.Hours))
+			if !end.After(from) {
+				continue
+			}
+			booking.BookingEnd = primitive.NewDateTimeFromTime(end)
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, nil
+}
+
+func findActiveBookings(ctx context.Context, serviceID primitive.ObjectID, from, to time.Time, excludeID primitive.ObjectID) ([]Models.OrderBookingService, error) {
+	filter := bson.M{
+		"service_id": serviceID,
+		"status":     bson.M{"$ne": BookingCancelled},
+	}
+	if excludeID != primitive.NilObjectID {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+	return findOverlappingBookings(ctx, filter, from, to)
+}
+
+func bookingsAt(bookings []Models.OrderBookingService, t time.Time) int {
+	used := 0
+	for _, booking := range bookings {
+		if !booking.BookingDate.Time().After(t) && booking.BookingEnd.Time().After(t) {
+			used++
+		}
+	}
+	return used
+}
+
+func remainingCapacity(settings Models.BookingSettings, capacity int, bookings []Models.OrderBookingService, start, end time.Time) int {
+	remaining := capacity
+	step := time.Duration(settings.SlotMinutes) * time.Minute
+	for t := start; t.Before(end); t = t.Add(step) {
+		if free := capacity - bookingsAt(bookings, t); free < remaining {
+			remaining = free
+		}
+	}
+	return remaining
+}
+
+// scheduleBooking validates the requested slot of a booking against business
+// hours, reserves a place in every slot it covers and fills in its end time.
+// Callers release booking.SlotKeys if the booking is not written after all.
+func scheduleBooking(ctx context.Context, booking *Models.OrderBookingService, service Models.Service) error {
+	settings, err := loadBookingSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := booking.BookingDate.Time()
+	if booking.BookingDate == 0 {
+		return errors.New("Booking date is required")
+	}
+	duration := serviceDuration(service, booking.Hours)
+	if err := validateBookingTime(settings, start, duration); err != nil {
+		return err
+	}
+
+	end := start.Add(duration)
+	keys, err := reserveBookingSlots(ctx, settings, service, booking.ID, start, end)
+	if err != nil {
+		return err
+	}
+
+	booking.BookingEnd = primitive.NewDateTimeFromTime(end)
+	booking.SlotKeys = keys
+	return nil
+}
+
+func slotKey(serviceID primitive.ObjectID, start time.Time) string {
+	return fmt.Sprintf("%s:%d", serviceID.Hex(), start.Unix())
+}
+
+// reserveBookingSlots takes a place in each slot between start and end. Every
+// slot of a service has a counter document and the increment only matches
+// while the counter is below capacity, so requests on different instances
+// cannot both take the last place. Places already taken are given back when a
+// later slot is full.
+func reserveBookingSlots(ctx context.Context, settings Models.BookingSettings, service Models.Service, bookingID primitive.ObjectID, start, end time.Time) ([]string, error) {
+	capacity := slotCapacity(settings, service)
+	step := time.Duration(settings.SlotMinutes) * time.Minute
+
+	var keys []string
+	for t := start; t.Before(end); t = t.Add(step) {
+		key := slotKey(service.ID, t)
+		err := seedBookingSlot(ctx, key, service.ID, bookingID, t)
+		if err == nil {
+			var result *mongo.UpdateResult
+			filter := bson.M{"_id": key, "used": bson.M{"$lt": capacity}}
+			result, err = getBookingSlotCollection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"used": 1}})
+			if err == nil && result.MatchedCount == 0 {
+				err = errSlotFull
+			}
+		}
+		if err != nil {
+			if releaseErr := releaseSlotKeys(ctx, keys); releaseErr != nil {
+				log.Println("Error releasing booking slots:", releaseErr)
+			}
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// seedBookingSlot creates a missing slot counter from the bookings already in
+// the slot, so bookings made before counters existed are still counted.
+func seedBookingSlot(ctx context.Context, key string, serviceID, excludeID primitive.ObjectID, start time.Time) error {
+	err := getBookingSlotCollection().FindOne(ctx, bson.M{"_id": key}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	bookings, err := findActiveBookings(ctx, serviceID, start, start.Add(time.Second), excludeID)
+	if err != nil {
+		return err
+	}
+	counter := bson.M{"_id": key, "service_id": serviceID, "start": start, "used": bookingsAt(bookings, start)}
+	if _, err := getBookingSlotCollection().InsertOne(ctx, counter); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func releaseSlotKeys(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		filter := bson.M{"_id": key, "used": bson.M{"$gt": 0}}
+		if _, err := getBookingSlotCollection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"used": -1}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseBookingSlots gives back the slots of cancelled bookings matching the
+// filter. The slot keys are removed from each booking before the counters are
+// decremented, so a slot is released once even if this runs concurrently.
+func releaseBookingSlots(ctx context.Context, filter bson.M) error {
+	claim := bson.M{"status": BookingCancelled, "slot_keys": bson.M{"$exists": true}}
+	for key, value := range filter {
+		claim[key] = value
+	}
+
+	for {
+		var booking Models.OrderBookingService
+		err := getOrderBookingServiceCollection().FindOneAndUpdate(ctx, claim, bson.M{"$unset": bson.M{"slot_keys": ""}}).Decode(&booking)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := releaseSlotKeys(ctx, booking.SlotKeys); err != nil {
+			return err
+		}
+	}
+}
+
+func serviceAvailability(ctx context.Context, service Models.Service, hours float64, from, to time.Time) ([]dayAvailability, error) {
+	settings, err := loadBookingSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	open, _ := parseClock(settings.OpenTime)
+	closing, _ := parseClock(settings.CloseTime)
+	duration := serviceDuration(service, hours)
+	step := time.Duration(settings.SlotMinutes) * time.Minute
+
+	capacity := slotCapacity(settings, service)
+	bookings, err := findActiveBookings(ctx, service.ID, from, to.AddDate(0, 0, 1), primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []dayAvailability
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		availability := dayAvailability{Date: day.Format("2006-01-02"), Slots: []bookingSlot{}}
+		if isWorkingDay(settings, day) {
+			dayStart := day.Add(time.Duration(open) * time.Minute)
+			dayEnd := day.Add(time.Duration(closing) * time.Minute)
+			for start := dayStart; !start.Add(duration).After(dayEnd); start = start.Add(step) {
+				if validateBookingTime(settings, start, duration) != nil {
+					continue
+				}
+				end := start.Add(duration)
+				if remaining := remainingCapacity(settings, capacity, bookings, start, end); remaining > 0 {
+					availability.Slots = append(availability.Slots, bookingSlot{Start: start, End: end, Remaining: remaining})
+				}
+			}
+		}
+		days = append(days, availability)
+	}
+	return days, nil
+}
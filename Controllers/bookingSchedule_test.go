@@ -0,0 +1,76 @@
+package Controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestReserveBookingSlotsGivesBackPlacesWhenASlotIsFull(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("full", func(mt *mtest.T) {
+		Database = mt.DB
+		settings := Models.BookingSettings{SlotMinutes: 60, SlotCapacity: 2}
+		service := Models.Service{ID: primitive.NewObjectID(), SlotCapacity: 1}
+		start := time.Date(2030, 1, 7, 9, 0, 0, 0, bookingLocation)
+		ns := "test.booking_slots"
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: slotKey(service.ID, start)}, {Key: "used", Value: 0}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: slotKey(service.ID, start.Add(time.Hour))}, {Key: "used", Value: 1}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		keys, err := reserveBookingSlots(context.Background(), settings, service, primitive.NewObjectID(), start, start.Add(2*time.Hour))
+		if err != errSlotFull || keys != nil {
+			mt.Fatalf("reserveBookingSlots = %v, %v; want errSlotFull", keys, err)
+		}
+
+		var updates []bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				updates = append(updates, started.Command)
+			}
+		}
+		if len(updates) != 3 {
+			mt.Fatalf("got %d updates, want reserve, reserve and release", len(updates))
+		}
+		if limit := updates[1].Lookup("updates", "0", "q", "used", "$lt").Int32(); limit != 1 {
+			mt.Fatalf("capacity in filter = %d, want the service capacity 1", limit)
+		}
+		release := updates[2].Lookup("updates", "0")
+		if key := release.Document().Lookup("q", "_id").StringValue(); key != slotKey(service.ID, start) {
+			mt.Fatalf("released %q, want the first slot", key)
+		}
+		if inc := release.Document().Lookup("u", "$inc", "used").Int32(); inc != -1 {
+			mt.Fatalf("release increment = %d, want -1", inc)
+		}
+	})
+}
+
+func TestServiceAvailabilityLimitsRangeTo31Days(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Params = gin.Params{{Key: "id", Value: primitive.NewObjectID().Hex()}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/services/x/availability?from=2030-01-01&to=2030-02-01", nil)
+
+	GetServiceAvailability(c)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("32 day range got status %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
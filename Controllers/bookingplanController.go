@@ -0,0 +1,638 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PlanActive    = "active"
+	PlanPaused    = "paused"
+	PlanCancelled = "cancelled"
+	PlanFinished  = "finished"
+
+	bookingPlanHorizon  = 14 * 24 * time.Hour
+	bookingPlanInterval = time.Hour
+)
+
+var rruleWeekdays = map[string]int{"SU": 0, "MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6}
+
+func getBookingPlanCollection() *mongo.Collection {
+	return Database.Collection("booking_plans")
+}
+
+func CreateBookingPlan(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var plan Models.BookingPlan
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if plan.RRule != "" {
+		if err := parseRRule(plan.RRule, &plan); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := validateBookingPlan(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var service Models.Service
+	if err := getServiceCollection().FindOne(ctx, bson.M{"_id": plan.ServiceID}).Decode(&service); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if _, err := quoteService(service, quoteRequest{Quantity: plan.Quantity, Area: plan.Area, Hours: plan.Hours, AddOns: plan.AddOns}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if result, err := checkCoverage(ctx, service.ServiceCategory, plan.Location, plan.Address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check coverage"})
+		return
+	} else if !result.Covered {
+		c.JSON(http.StatusBadRequest, gin.H{"error": result.Reason})
+		return
+	}
+
+	plan.ID = primitive.NewObjectID()
+	plan.UserID = claims.ID
+	plan.Status = PlanActive
+	plan.SkippedDates = []string{}
+	plan.Conflicts = nil
+	plan.GeneratedUntil = time.Time{}
+	plan.CreatedAt = time.Now()
+	plan.UpdatedAt = time.Now()
+
+	if _, err := getBookingPlanCollection().InsertOne(ctx, plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking plan"})
+		return
+	}
+
+	if err := materializePlan(ctx, &plan); err != nil {
+		log.Println("Error materializing booking plan:", err)
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func GetBookingPlans(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var plans []Models.BookingPlan
+	cursor, err := getBookingPlanCollection().Find(ctx, bson.M{"user_id": claims.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get booking plans"})
+		return
+	}
+	if err := cursor.All(ctx, &plans); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode booking plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+func GetBookingPlan(c *gin.Context) {
+	plan, ok := loadOwnBookingPlan(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var bookings []Models.OrderBookingService
+	opts := options.Find().SetSort(bson.D{{Key: "booking_date", Value: 1}})
+	cursor, err := getOrderBookingServiceCollection().Find(ctx, bson.M{"plan_id": plan.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plan bookings"})
+		return
+	}
+	if err := cursor.All(ctx, &bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode plan bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plan": plan, "bookings": bookings})
+}
+
+func PauseBookingPlan(c *gin.Context) {
+	changeBookingPlanStatus(c, PlanActive, PlanPaused)
+}
+
+func ResumeBookingPlan(c *gin.Context) {
+	changeBookingPlanStatus(c, PlanPaused, PlanActive)
+}
+
+func CancelBookingPlan(c *gin.Context) {
+	plan, ok := loadOwnBookingPlan(c)
+	if !ok {
+		return
+	}
+	if plan.Status != PlanActive && plan.Status != PlanPaused {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking plan is already " + plan.Status})
+		return
+	}
+	changeBookingPlanStatus(c, plan.Status, PlanCancelled)
+}
+
+func changeBookingPlanStatus(c *gin.Context, from, to string) {
+	plan, ok := loadOwnBookingPlan(c)
+	if !ok {
+		return
+	}
+	if plan.Status != from {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking plan is " + plan.Status})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updated_at": time.Now()}
+	if to != PlanActive {
+		if err := cancelPlanBookings(ctx, bson.M{"plan_id": plan.ID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upcoming bookings"})
+			return
+		}
+		set["generated_until"] = time.Now()
+	}
+
+	result, err := getBookingPlanCollection().UpdateOne(ctx, bson.M{"_id": plan.ID, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking plan"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking plan has changed, please reload"})
+		return
+	}
+
+	plan.Status = to
+	if to == PlanActive {
+		if err := materializePlan(ctx, &plan); err != nil {
+			log.Println("Error materializing booking plan:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking plan " + to, "plan": plan})
+}
+
+func SkipBookingPlanOccurrence(c *gin.Context) {
+	plan, ok := loadOwnBookingPlan(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Date string `json:"date"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if _, err := time.Parse("2006-01-02", request.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	if plan.Status != PlanActive && plan.Status != PlanPaused {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking plan is " + plan.Status})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$addToSet": bson.M{"skipped_dates": request.Date},
+		"$set":      bson.M{"updated_at": time.Now()},
+	}
+	if _, err := getBookingPlanCollection().UpdateOne(ctx, bson.M{"_id": plan.ID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip occurrence"})
+		return
+	}
+
+	if err := cancelPlanBookings(ctx, bson.M{"plan_id": plan.ID, "occurrence": request.Date}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel occurrence booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence skipped", "date": request.Date})
+}
+
+func RunBookingPlanScheduler(ctx context.Context) {
+	ticker := time.NewTicker(bookingPlanInterval)
+	defer ticker.Stop()
+
+	for {
+		materializeActivePlans(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func materializeActivePlans(ctx context.Context) {
+	cursor, err := getBookingPlanCollection().Find(ctx, bson.M{"status": PlanActive})
+	if err != nil {
+		log.Println("Error loading booking plans:", err)
+		return
+	}
+
+	var plans []Models.BookingPlan
+	if err := cursor.All(ctx, &plans); err != nil {
+		log.Println("Error decoding booking plans:", err)
+		return
+	}
+
+	for i := range plans {
+		if err := materializePlan(ctx, &plans[i]); err != nil {
+			log.Println("Error materializing booking plan:", err)
+		}
+	}
+}
+
+// materializePlan creates the concrete bookings of a plan that fall inside
+// the scheduling horizon and have not been generated yet. An occurrence that
+// fails is reported as a conflict and GeneratedUntil stops before it, so it
+// is tried again on the next run until it succeeds or is in the past.
+func materializePlan(ctx context.Context, plan *Models.BookingPlan) error {
+	if plan.Status != PlanActive {
+		return nil
+	}
+
+	var service Models.Service
+	if err := getServiceCollection().FindOne(ctx, bson.M{"_id": plan.ServiceID}).Decode(&service); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	failed := false
+	for _, start := range planOccurrences(*plan, now.Add(bookingPlanHorizon)) {
+		if !start.After(plan.GeneratedUntil) {
+			continue
+		}
+		date := start.In(bookingLocation).Format("2006-01-02")
+		if start.Before(now) || containsString(plan.SkippedDates, date) {
+			if !failed {
+				plan.GeneratedUntil = start
+			}
+			continue
+		}
+
+		booking := Models.OrderBookingService{
+			ID:           primitive.NewObjectID(),
+			UserID:       plan.UserID,
+			ServiceID:    plan.ServiceID,
+			Quantity:     plan.Quantity,
+			Area:         plan.Area,
+			Hours:        plan.Hours,
+			AddOns:       plan.AddOns,
+			BookingDate:  primitive.NewDateTimeFromTime(start),
+			ContactName:  plan.ContactName,
+			ContactPhone: plan.ContactPhone,
+			Address:      plan.Address,
+			Location:     plan.Location,
+			Note:         plan.Note,
+			Status:       BookingPending,
+			PlanID:       plan.ID,
+			Occurrence:   date,
+			CreatedAt:    primitive.NewDateTimeFromTime(now),
+			UpdatedAt:    primitive.NewDateTimeFromTime(now),
+		}
+		err := priceBooking(ctx, &booking, service)
+		if err == nil {
+			err = createPlanBooking(ctx, &booking, service, plan.PreferredStaffID)
+		}
+		plan.Conflicts = setPlanConflict(plan.Conflicts, date, err)
+		if err != nil {
+			failed = true
+		} else if !failed {
+			plan.GeneratedUntil = start
+		}
+	}
+
+	if planExhausted(*plan) {
+		plan.Status = PlanFinished
+	}
+
+	update := bson.M{"$set": bson.M{
+		"generated_until": plan.GeneratedUntil,
+		"conflicts":       plan.Conflicts,
+		"status":          plan.Status,
+		"updated_at":      time.Now(),
+	}}
+	_, err := getBookingPlanCollection().UpdateOne(ctx, bson.M{"_id": plan.ID, "status": PlanActive}, update)
+	return err
+}
+
+func createPlanBooking(ctx context.Context, booking *Models.OrderBookingService, service Models.Service, preferredStaffID primitive.ObjectID) error {
+	collection := getOrderBookingServiceCollection()
+	filter := bson.M{"plan_id": booking.PlanID, "occurrence": booking.Occurrence, "status": bson.M{"$ne": BookingCancelled}}
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := scheduleBooking(ctx, booking, service); err != nil {
+		return err
+	}
+
+	if preferredStaffID != primitive.NilObjectID {
+		var profile Models.StaffProfile
+		if err := getStaffProfileCollection().FindOne(ctx, bson.M{"user_id": preferredStaffID}).Decode(&profile); err == nil {
+			if unlock, err := lockStaff(ctx, preferredStaffID); err == nil {
+				defer unlock()
+				if reason, err := staffUnavailableReason(ctx, profile, *booking, service); err == nil && reason == "" {
+					booking.StaffID = preferredStaffID
+					booking.AssignedAt = primitive.NewDateTimeFromTime(time.Now())
+				}
+			}
+		}
+	}
+
+	if _, err := collection.InsertOne(ctx, booking); err != nil {
+		if releaseErr := releaseSlotKeys(ctx, booking.SlotKeys); releaseErr != nil {
+			log.Println("Error releasing booking slots:", releaseErr)
+		}
+		// Another run generated this occurrence in the meantime.
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// setPlanConflict records why an occurrence could not be booked, replacing an
+// earlier reason for the same date, and clears it once the date succeeds.
+func setPlanConflict(conflicts []Models.PlanConflict, date string, err error) []Models.PlanConflict {
+	kept := conflicts[:0]
+	for _, conflict := range conflicts {
+		if conflict.Date != date {
+			kept = append(kept, conflict)
+		}
+	}
+	if err != nil {
+		kept = append(kept, Models.PlanConflict{Date: date, Reason: err.Error()})
+	}
+	return kept
+}
+
+func cancelPlanBookings(ctx context.Context, filter bson.M) error {
+	released := bson.M{}
+	for key, value := range filter {
+		released[key] = value
+	}
+
+	filter["status"] = bson.M{"$in": []string{BookingPending, BookingConfirmed}}
+	filter["booking_date"] = bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())}
+
+	update := bson.M{"$set": bson.M{
+		"status":     BookingCancelled,
+		"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+	}}
+	if _, err := getOrderBookingServiceCollection().UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+	return releaseBookingSlots(ctx, released)
+}
+
+func loadOwnBookingPlan(c *gin.Context) (Models.BookingPlan, bool) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var plan Models.BookingPlan
+	planID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return plan, false
+	}
+
+	if err := getBookingPlanCollection().FindOne(context.Background(), bson.M{"_id": planID, "user_id": claims.ID}).Decode(&plan); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking plan not found"})
+		return plan, false
+	}
+	return plan, true
+}
+
+func parseRRule(rule string, plan *Models.BookingPlan) error {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("Invalid rule part %q", part)
+		}
+
+		switch key {
+		case "FREQ":
+			plan.Frequency = strings.ToLower(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid INTERVAL")
+			}
+			plan.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid COUNT")
+			}
+			plan.Count = count
+		case "UNTIL":
+			if len(value) < 8 {
+				return errors.New("Invalid UNTIL")
+			}
+			until, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return errors.New("Invalid UNTIL")
+			}
+			plan.EndDate = until.Format("2006-01-02")
+		case "BYDAY":
+			plan.ByWeekday = nil
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return fmt.Errorf("Invalid BYDAY value %q", day)
+				}
+				plan.ByWeekday = append(plan.ByWeekday, weekday)
+			}
+		default:
+			return fmt.Errorf("Unsupported rule part %s", key)
+		}
+	}
+	return nil
+}
+
+func validateBookingPlan(plan *Models.BookingPlan) error {
+	if plan.Frequency == "biweekly" {
+		plan.Frequency = "weekly"
+		plan.Interval = 2
+	}
+	if plan.Frequency != "daily" && plan.Frequency != "weekly" && plan.Frequency != "monthly" {
+		return errors.New("Frequency must be daily, weekly, biweekly or monthly")
+	}
+	if plan.Interval <= 0 {
+		plan.Interval = 1
+	}
+	if plan.Quantity <= 0 {
+		plan.Quantity = 1
+	}
+	if plan.Count < 0 {
+		return errors.New("Count cannot be negative")
+	}
+
+	start, err := time.Parse("2006-01-02", plan.StartDate)
+	if err != nil {
+		return errors.New("Invalid start date, expected YYYY-MM-DD")
+	}
+	if plan.EndDate != "" {
+		end, err := time.Parse("2006-01-02", plan.EndDate)
+		if err != nil {
+			return errors.New("Invalid end date, expected YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return errors.New("End date must be after start date")
+		}
+	}
+	if _, err := parseClock(plan.PreferredTime); err != nil {
+		return err
+	}
+	for _, weekday := range plan.ByWeekday {
+		if weekday < 0 || weekday > 6 {
+			return errors.New("Weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if plan.ServiceID == primitive.NilObjectID {
+		return errors.New("Service is required")
+	}
+	if plan.Location != nil {
+		plan.Address = plan.Location.String()
+	}
+	if plan.Address == "" || plan.ContactName == "" || plan.ContactPhone == "" {
+		return errors.New("Contact name, phone and address are required")
+	}
+	return nil
+}
+
+// planOccurrences lists the start times of a plan from its start date up to
+// until, honouring COUNT and UNTIL the way an RRULE would.
+func planOccurrences(plan Models.BookingPlan, until time.Time) []time.Time {
+	start, err := time.ParseInLocation("2006-01-02", plan.StartDate, bookingLocation)
+	if err != nil {
+		return nil
+	}
+	minute, err := parseClock(plan.PreferredTime)
+	if err != nil {
+		return nil
+	}
+
+	var end time.Time
+	if plan.EndDate != "" {
+		if end, err = time.ParseInLocation("2006-01-02", plan.EndDate, bookingLocation); err != nil {
+			return nil
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+
+	interval := plan.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	weekdays := plan.ByWeekday
+	if len(weekdays) == 0 {
+		weekdays = []int{int(start.Weekday())}
+	}
+	firstWeek := start.AddDate(0, 0, -int(start.Weekday()))
+
+	var occurrences []time.Time
+	for day := start; !day.After(until); day = day.AddDate(0, 0, 1) {
+		if !end.IsZero() && !day.Before(end) {
+			break
+		}
+
+		matches := false
+		switch plan.Frequency {
+		case "daily":
+			matches = int(day.Sub(start).Hours()/24)%interval == 0
+		case "weekly":
+			weeks := int(day.Sub(firstWeek).Hours()/24) / 7
+			matches = weeks%interval == 0 && containsInt(weekdays, int(day.Weekday()))
+		case "monthly":
+			months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+			matches = day.Day() == start.Day() && months%interval == 0
+		}
+		if !matches {
+			continue
+		}
+
+		occurrences = append(occurrences, day.Add(time.Duration(minute)*time.Minute))
+		if plan.Count > 0 && len(occurrences) >= plan.Count {
+			break
+		}
+	}
+	return occurrences
+}
+
+func planExhausted(plan Models.BookingPlan) bool {
+	if plan.Count == 0 && plan.EndDate == "" {
+		return false
+	}
+
+	limit := time.Now().AddDate(50, 0, 0)
+	if plan.EndDate != "" {
+		if end, err := time.ParseInLocation("2006-01-02", plan.EndDate, bookingLocation); err == nil {
+			limit = end.AddDate(0, 0, 1)
+		}
+	}
+
+	occurrences := planOccurrences(plan, limit)
+	return len(occurrences) == 0 || !occurrences[len(occurrences)-1].After(plan.GeneratedUntil)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
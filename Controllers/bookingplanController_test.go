@@ -0,0 +1,318 @@
+package Controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSetPlanConflictKeepsOneReasonPerDate(t *testing.T) {
+	conflicts := []Models.PlanConflict{{Date: "2030-01-07", Reason: "old"}, {Date: "2030-01-14", Reason: "full"}}
+
+	conflicts = setPlanConflict(conflicts, "2030-01-07", errors.New("closed"))
+	if len(conflicts) != 2 || conflicts[1].Date != "2030-01-07" || conflicts[1].Reason != "closed" {
+		t.Fatalf("retrying a date should replace its reason, got %+v", conflicts)
+	}
+
+	conflicts = setPlanConflict(conflicts, "2030-01-14", nil)
+	if len(conflicts) != 1 || conflicts[0].Date != "2030-01-07" {
+		t.Fatalf("a date that succeeds should be cleared, got %+v", conflicts)
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    Models.BookingPlan
+		wantErr bool
+	}{
+		{
+			name: "weekly by day",
+			rule: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			want: Models.BookingPlan{Frequency: "weekly", Interval: 2, ByWeekday: []int{1, 4}},
+		},
+		{
+			name: "lower case with count",
+			rule: "freq=daily;count=5",
+			want: Models.BookingPlan{Frequency: "daily", Count: 5},
+		},
+		{
+			name: "until with time",
+			rule: "FREQ=MONTHLY;UNTIL=20300615T000000Z",
+			want: Models.BookingPlan{Frequency: "monthly", EndDate: "2030-06-15"},
+		},
+		{name: "missing value", rule: "FREQ", wantErr: true},
+		{name: "bad interval", rule: "FREQ=WEEKLY;INTERVAL=x", wantErr: true},
+		{name: "bad count", rule: "FREQ=WEEKLY;COUNT=many", wantErr: true},
+		{name: "short until", rule: "FREQ=WEEKLY;UNTIL=2030", wantErr: true},
+		{name: "bad day", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "unsupported part", rule: "FREQ=WEEKLY;BYMONTH=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plan Models.BookingPlan
+			err := parseRRule(tt.rule, &plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(plan, tt.want) {
+				t.Fatalf("parseRRule(%q) = %+v, want %+v", tt.rule, plan, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBookingPlan(t *testing.T) {
+	valid := func() Models.BookingPlan {
+		return Models.BookingPlan{
+			ServiceID:     primitive.NewObjectID(),
+			Frequency:     "weekly",
+			StartDate:     "2030-01-07",
+			PreferredTime: "09:00",
+			ContactName:   "Lan",
+			ContactPhone:  "0900000000",
+			Address:       "1 Lê Lợi, Quận 1",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*Models.BookingPlan)
+		wantErr bool
+		check   func(Models.BookingPlan) bool
+	}{
+		{
+			name:   "defaults interval and quantity",
+			change: func(p *Models.BookingPlan) {},
+			check:  func(p Models.BookingPlan) bool { return p.Interval == 1 && p.Quantity == 1 },
+		},
+		{
+			name:   "biweekly is weekly every two weeks",
+			change: func(p *Models.BookingPlan) { p.Frequency = "biweekly" },
+			check:  func(p Models.BookingPlan) bool { return p.Frequency == "weekly" && p.Interval == 2 },
+		},
+		{
+			name: "location fills the address",
+			change: func(p *Models.BookingPlan) {
+				p.Address = ""
+				p.Location = &Models.Address{Street: "1 Lê Lợi", District: "Quận 1", Province: "Hồ Chí Minh"}
+			},
+			check: func(p Models.BookingPlan) bool { return p.Address == p.Location.String() },
+		},
+		{name: "unknown frequency", change: func(p *Models.BookingPlan) { p.Frequency = "yearly" }, wantErr: true},
+		{name: "negative count", change: func(p *Models.BookingPlan) { p.Count = -1 }, wantErr: true},
+		{name: "bad start date", change: func(p *Models.BookingPlan) { p.StartDate = "07/01/2030" }, wantErr: true},
+		{name: "end before start", change: func(p *Models.BookingPlan) { p.EndDate = "2030-01-01" }, wantErr: true},
+		{name: "bad time", change: func(p *Models.BookingPlan) { p.PreferredTime = "9am" }, wantErr: true},
+		{name: "bad weekday", change: func(p *Models.BookingPlan) { p.ByWeekday = []int{7} }, wantErr: true},
+		{name: "no service", change: func(p *Models.BookingPlan) { p.ServiceID = primitive.NilObjectID }, wantErr: true},
+		{name: "no contact", change: func(p *Models.BookingPlan) { p.ContactPhone = "" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := valid()
+			tt.change(&plan)
+			err := validateBookingPlan(&plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateBookingPlan error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(plan) {
+				t.Fatalf("validateBookingPlan left %+v", plan)
+			}
+		})
+	}
+}
+
+func TestPlanOccurrences(t *testing.T) {
+	// 2030-01-07 is a Monday.
+	until := time.Date(2030, 3, 31, 0, 0, 0, 0, bookingLocation)
+	tests := []struct {
+		name string
+		plan Models.BookingPlan
+		want []string
+	}{
+		{
+			name: "weekly on the start weekday",
+			plan: Models.BookingPlan{Frequency: "weekly", Interval: 1, StartDate: "2030-01-07", EndDate: "2030-01-28"},
+			want: []string{"2030-01-07 09:00", "2030-01-14 09:00", "2030-01-21 09:00", "2030-01-28 09:00"},
+		},
+		{
+			name: "weekly on several days",
+			plan: Models.BookingPlan{Frequency: "weekly", Interval: 1, ByWeekday: []int{1, 4}, StartDate: "2030-01-07", Count: 4},
+			want: []string{"2030-01-07 09:00", "2030-01-10 09:00", "2030-01-14 09:00", "2030-01-17 09:00"},
+		},
+		{
+			name: "biweekly",
+			plan: Models.BookingPlan{Frequency: "weekly", Interval: 2, StartDate: "2030-01-07", Count: 3},
+			want: []string{"2030-01-07 09:00", "2030-01-21 09:00", "2030-02-04 09:00"},
+		},
+		{
+			name: "monthly",
+			plan: Models.BookingPlan{Frequency: "monthly", Interval: 1, StartDate: "2030-01-15", Count: 3},
+			want: []string{"2030-01-15 09:00", "2030-02-15 09:00", "2030-03-15 09:00"},
+		},
+		{
+			name: "daily every other day until",
+			plan: Models.BookingPlan{Frequency: "daily", Interval: 2, StartDate: "2030-01-07", EndDate: "2030-01-12"},
+			want: []string{"2030-01-07 09:00", "2030-01-09 09:00", "2030-01-11 09:00"},
+		},
+		{
+			name: "count is capped by until",
+			plan: Models.BookingPlan{Frequency: "weekly", Interval: 1, StartDate: "2030-03-18", Count: 10},
+			want: []string{"2030-03-18 09:00", "2030-03-25 09:00"},
+		},
+		{
+			name: "bad start date",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "soon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plan.PreferredTime = "09:00"
+			var got []string
+			for _, occurrence := range planOccurrences(tt.plan, until) {
+				got = append(got, occurrence.In(bookingLocation).Format("2006-01-02 15:04"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planOccurrences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanExhausted(t *testing.T) {
+	at := func(date string) time.Time {
+		day, _ := time.ParseInLocation("2006-01-02 15:04", date, bookingLocation)
+		return day
+	}
+	tests := []struct {
+		name string
+		plan Models.BookingPlan
+		want bool
+	}{
+		{
+			name: "open ended",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "2030-01-07", GeneratedUntil: at("2040-01-01 09:00")},
+			want: false,
+		},
+		{
+			name: "count not reached",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "2030-01-07", Count: 3, GeneratedUntil: at("2030-01-14 09:00")},
+			want: false,
+		},
+		{
+			name: "count reached",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "2030-01-07", Count: 3, GeneratedUntil: at("2030-01-21 09:00")},
+			want: true,
+		},
+		{
+			name: "end date reached",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "2030-01-07", EndDate: "2030-01-20", GeneratedUntil: at("2030-01-14 09:00")},
+			want: true,
+		},
+		{
+			name: "end date ahead",
+			plan: Models.BookingPlan{Frequency: "weekly", StartDate: "2030-01-07", EndDate: "2030-01-21", GeneratedUntil: at("2030-01-14 09:00")},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plan.Interval = 1
+			tt.plan.PreferredTime = "09:00"
+			if got := planExhausted(tt.plan); got != tt.want {
+				t.Fatalf("planExhausted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResumeBookingPlanRebooksPausedOccurrences(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("occurrence cancelled by the pause", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, planID, serviceID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		tomorrow := time.Now().In(bookingLocation).AddDate(0, 0, 1).Format("2006-01-02")
+		pausedAt := time.Now().Add(-time.Hour)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.booking_plans", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: planID},
+				{Key: "user_id", Value: userID},
+				{Key: "service_id", Value: serviceID},
+				{Key: "frequency", Value: "daily"},
+				{Key: "interval", Value: 1},
+				{Key: "start_date", Value: tomorrow},
+				{Key: "end_date", Value: tomorrow},
+				{Key: "preferred_time", Value: "10:00"},
+				{Key: "address", Value: "1 Lê Lợi, Quận 1"},
+				{Key: "status", Value: PlanPaused},
+				{Key: "generated_until", Value: pausedAt},
+			}),
+			okUpdate(),
+			mtest.CreateCursorResponse(0, "test.services", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: serviceID},
+				{Key: "name", Value: "Cleaning"},
+				{Key: "price", Value: 200000},
+				{Key: "duration_minutes", Value: 30},
+			}),
+			mtest.CreateCursorResponse(0, "test.service_areas", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.order_booking_service", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.booking_slots", mtest.FirstBatch, bson.D{{Key: "_id", Value: "slot"}, {Key: "used", Value: 0}}),
+			okUpdate(),
+			mtest.CreateSuccessResponse(),
+			okUpdate(),
+		)
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/booking-plans/x/resume", nil)
+		c.Params = gin.Params{{Key: "id", Value: planID.Hex()}}
+		c.Set("user", &Middleware.UserClaims{ID: userID, Role: Middleware.Customer})
+		ResumeBookingPlan(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d, body %s", recorder.Code, recorder.Body.String())
+		}
+
+		var counted, inserted bool
+		for _, started := range mt.GetAllStartedEvents() {
+			switch {
+			case started.CommandName == "aggregate" && started.Command.Lookup("aggregate").StringValue() == "order_booking_service":
+				counted = true
+				match := started.Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match").Document()
+				if status, err := match.LookupErr("status", "$ne"); err != nil || status.StringValue() != BookingCancelled {
+					mt.Errorf("occurrence check %v must ignore cancelled bookings", match)
+				}
+			case started.CommandName == "insert" && started.Command.Lookup("insert").StringValue() == "order_booking_service":
+				inserted = true
+				booking := started.Command.Lookup("documents").Array().Index(0).Value().Document()
+				if occurrence := booking.Lookup("occurrence").StringValue(); occurrence != tomorrow {
+					mt.Errorf("booked occurrence %q, want %q", occurrence, tomorrow)
+				}
+			}
+		}
+		if !counted || !inserted {
+			mt.Fatalf("counted = %v, inserted = %v; want the paused occurrence booked again", counted, inserted)
+		}
+	})
+}
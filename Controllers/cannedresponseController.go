@@ -0,0 +1,258 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func getCannedResponseCollection() *mongo.Collection {
+	return Database.Collection("canned_responses")
+}
+
+func CreateCannedResponse(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var response Models.CannedResponse
+	if err := c.ShouldBindJSON(&response); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	response.Shortcut = normalizeShortcut(response.Shortcut)
+	if response.Title == "" || response.Shortcut == "" || strings.TrimSpace(response.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title, shortcut and content are required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := getCannedResponseCollection()
+	count, err := collection.CountDocuments(ctx, bson.M{"shortcut": response.Shortcut})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check shortcut"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shortcut already exists"})
+		return
+	}
+
+	response.ID = primitive.NewObjectID()
+	response.CreatedBy = claims.ID
+	response.CreatedAt = time.Now()
+	response.UpdatedAt = time.Now()
+
+	if _, err := collection.InsertOne(ctx, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create canned response"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func GetAllCannedResponses(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var responses []Models.CannedResponse
+	cursor, err := getCannedResponseCollection().Find(ctx, bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get canned responses"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &responses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode canned responses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+func GetCannedResponseByID(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var response Models.CannedResponse
+	if err := getCannedResponseCollection().FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&response); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Canned response not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func UpdateCannedResponse(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var response Models.CannedResponse
+	if err := c.ShouldBindJSON(&response); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	response.Shortcut = normalizeShortcut(response.Shortcut)
+	if response.Title == "" || response.Shortcut == "" || strings.TrimSpace(response.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title, shortcut and content are required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := getCannedResponseCollection()
+	count, err := collection.CountDocuments(ctx, bson.M{"shortcut": response.Shortcut, "_id": bson.M{"$ne": objectID}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check shortcut"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shortcut already exists"})
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"title":      response.Title,
+			"shortcut":   response.Shortcut,
+			"content":    response.Content,
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update canned response"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Canned response not found"})
+		return
+	}
+
+	response.ID = objectID
+	c.JSON(http.StatusOK, response)
+}
+
+func DeleteCannedResponse(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getCannedResponseCollection().DeleteOne(context.Background(), bson.M{"_id": objectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete canned response"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Canned response not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func normalizeShortcut(shortcut string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(shortcut), "/"))
+}
+
+// expandSlashCommand replaces a "/shortcut" message with the matching canned
+// response. Messages that do not start with a slash are left untouched.
+func expandSlashCommand(ctx context.Context, msg *Models.Message) error {
+	content := strings.TrimSpace(msg.Content)
+	if msg.Type != Models.MessageText || !strings.HasPrefix(content, "/") {
+		return nil
+	}
+
+	shortcut := normalizeShortcut(strings.Fields(content)[0])
+	var response Models.CannedResponse
+	if err := getCannedResponseCollection().FindOne(ctx, bson.M{"shortcut": shortcut}).Decode(&response); err != nil {
+		return errors.New("Unknown command /" + shortcut)
+	}
+
+	return applyCannedResponse(ctx, msg, response)
+}
+
+func applyCannedResponse(ctx context.Context, msg *Models.Message, response Models.CannedResponse) error {
+	var chat Models.SupportChat
+	if err := Database.Collection("chats").FindOne(ctx, bson.M{"_id": msg.ChatID}).Decode(&chat); err != nil {
+		return errors.New("Chat not found")
+	}
+
+	content, err := renderCannedResponse(ctx, response.Content, chat, msg.ServiceID)
+	if err != nil {
+		return err
+	}
+
+	msg.Content = content
+	if msg.Type == "" {
+		msg.Type = Models.MessageText
+	}
+	return nil
+}
+
+func renderCannedResponse(ctx context.Context, content string, chat Models.SupportChat, serviceID primitive.ObjectID) (string, error) {
+	customerName := chat.GuestName
+	customerPhone := chat.GuestPhone
+	if chat.CustomerID != primitive.NilObjectID {
+		var user Models.User
+		if err := getUserCollection().FindOne(ctx, bson.M{"_id": chat.CustomerID}).Decode(&user); err == nil {
+			customerName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			customerPhone = user.Phone
+		}
+	}
+
+	replacements := []string{
+		"{{customer_name}}", customerName,
+		"{{customer_phone}}", customerPhone,
+	}
+
+	if strings.Contains(content, "{{service_") {
+		if serviceID == primitive.NilObjectID {
+			for i := len(chat.Messages) - 1; i >= 0; i-- {
+				if chat.Messages[i].ServiceID != primitive.NilObjectID {
+					serviceID = chat.Messages[i].ServiceID
+					break
+				}
+			}
+		}
+		if serviceID == primitive.NilObjectID {
+			return "", errors.New("No service referenced in this chat")
+		}
+
+		var service Models.Service
+		if err := getServiceCollection().FindOne(ctx, bson.M{"_id": serviceID}).Decode(&service); err != nil {
+			return "", errors.New("Service not found")
+		}
+		replacements = append(replacements,
+			"{{service_name}}", service.Name,
+			"{{service_price}}", strconv.FormatInt(service.Price.MinorUnits(), 10),
+		)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(content), nil
+}
@@ -0,0 +1,40 @@
+package Controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Server/Middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateCannedResponseFailsWhenShortcutCheckFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("count error", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		body := `{"title":"Hi","shortcut":"hi","content":"Hello {{name}}"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/canned-responses", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &Middleware.UserClaims{ID: primitive.NewObjectID(), Role: Middleware.Admin})
+
+		CreateCannedResponse(c)
+
+		if recorder.Code != http.StatusInternalServerError {
+			mt.Fatalf("status = %d, want %d; body %s", recorder.Code, http.StatusInternalServerError, recorder.Body)
+		}
+		if len(mt.GetAllStartedEvents()) != 1 {
+			mt.Fatal("the response must not be inserted when the shortcut check fails")
+		}
+	})
+}
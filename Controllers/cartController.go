@@ -2,6 +2,7 @@ package Controllers
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"Server/Middleware"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -30,9 +32,21 @@ func AddToCart(c *gin.Context) {
 		return
 	}
 
+	cart, err := addCartItem(context.Background(), userID, cartItem)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to add to cart"})
+		return
+	}
+
+	c.JSON(200, cart)
+}
+
+// addCartItem adds the item to the user's cart, creating the cart or
+// increasing the quantity of a product that is already in it.
+func addCartItem(ctx context.Context, userID primitive.ObjectID, cartItem Models.CartItem) (Models.Cart, error) {
 	cartCollection := getCartCollection()
 	var cart Models.Cart
-	err := cartCollection.FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&cart)
+	err := cartCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&cart)
 
 	if err == mongo.ErrNoDocuments {
 		cart = Models.Cart{
@@ -41,29 +55,46 @@ func AddToCart(c *gin.Context) {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
-		_, err = cartCollection.InsertOne(context.Background(), cart)
-	} else {
-		exists := false
-		for i, item := range cart.Items {
-			if item.ProductID == cartItem.ProductID {
-				cart.Items[i].Quantity += cartItem.Quantity
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			cart.Items = append(cart.Items, cartItem)
+		_, err = cartCollection.InsertOne(ctx, cart)
+		return cart, err
+	}
+	if err != nil {
+		return cart, err
+	}
+
+	exists := false
+	for i, item := range cart.Items {
+		if item.ProductID == cartItem.ProductID {
+			cart.Items[i].Quantity += cartItem.Quantity
+			exists = true
+			break
 		}
-		cart.UpdatedAt = time.Now()
-		_, err = cartCollection.UpdateOne(context.Background(), bson.M{"user_id": userID}, bson.M{"$set": cart})
 	}
+	if !exists {
+		cart.Items = append(cart.Items, cartItem)
+	}
+	cart.UpdatedAt = time.Now()
+	_, err = cartCollection.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{"$set": cart})
+	return cart, err
+}
 
+// cartQuantity is how many units of a product are already in the customer's
+// cart.
+func cartQuantity(ctx context.Context, userID, productID primitive.ObjectID) (int, error) {
+	var cart Models.Cart
+	err := getCartCollection().FindOne(ctx, bson.M{"user_id": userID}).Decode(&cart)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to add to cart"})
-		return
+		return 0, err
 	}
-
-	c.JSON(200, cart)
+	for _, item := range cart.Items {
+		if item.ProductID == productID {
+			return item.Quantity, nil
+		}
+	}
+	return 0, nil
 }
 
 func GetCart(c *gin.Context) {
@@ -80,6 +111,7 @@ func GetCart(c *gin.Context) {
 	}
 
 	productCollection := getProductCollection()
+	discountItems := discountCart{TargetType: PaymentTargetOrder}
 	for i, item := range cart.Items {
 		var product Models.Product
 		err := productCollection.FindOne(context.Background(), bson.M{"_id": item.ProductID}).Decode(&product)
@@ -91,7 +123,19 @@ func GetCart(c *gin.Context) {
 		cart.Items[i].Name = product.Name
 		cart.Items[i].Price = product.Price
 		cart.Items[i].ImageURL = product.ImageURL
+		discountItems.Items = append(discountItems.Items, productDiscountItem(product, item.Quantity))
+	}
+
+	// Show the cart at the price the customer would pay right now, with the
+	// automatic promotions that CreateOrder applies at checkout.
+	cart.Subtotal = discountItems.subtotal()
+	if _, discounts, promotions, err := evaluatePromotions(context.Background(), userID, discountItems); err != nil {
+		log.Println("Error evaluating promotions:", err)
+	} else {
+		cart.Discounts = discounts
+		cart.Promotions = promotions
 	}
+	cart.Total = cart.Subtotal.Sub(discountTotal(cart.Discounts)).NonNegative()
 
 	c.JSON(200, cart)
 }
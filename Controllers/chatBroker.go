@@ -0,0 +1,188 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Broker interface {
+	Publish(ctx context.Context, event Models.ChatEvent) error
+	Subscribe(ctx context.Context) (<-chan Models.ChatEvent, error)
+}
+
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Models.ChatEvent]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[chan Models.ChatEvent]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Models.ChatEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context) (<-chan Models.ChatEvent, error) {
+	events := make(chan Models.ChatEvent, 64)
+
+	b.mu.Lock()
+	b.subscribers[events] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, events)
+		close(events)
+		b.mu.Unlock()
+	}()
+
+	return events, nil
+}
+
+// MongoBroker fans chat events out between server instances through a
+// change stream on a shared collection. Change streams require a replica set.
+type MongoBroker struct {
+	collection *mongo.Collection
+}
+
+func NewMongoBroker(collection *mongo.Collection) *MongoBroker {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(3600),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		log.Println("Error creating chat event index:", err)
+	}
+
+	return &MongoBroker{collection: collection}
+}
+
+func (b *MongoBroker) Publish(ctx context.Context, event Models.ChatEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	_, err := b.collection.InsertOne(ctx, event)
+	return err
+}
+
+// Subscribe watches the event collection until ctx is done. When the change
+// stream fails it reopens it after the last event seen, backing off between
+// attempts, so a replica set election does not silently stop the hub.
+func (b *MongoBroker) Subscribe(ctx context.Context) (<-chan Models.ChatEvent, error) {
+	stream, err := b.watch(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Models.ChatEvent, 64)
+	go func() {
+		defer close(events)
+
+		var resumeToken bson.Raw
+		backoff := time.Second
+		for {
+			token, delivered := b.forward(ctx, stream, events)
+			if ctx.Err() != nil {
+				return
+			}
+			if token != nil {
+				resumeToken = token
+			}
+			if delivered {
+				backoff = time.Second
+			}
+
+			for {
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+				if backoff < 30*time.Second {
+					backoff *= 2
+				}
+
+				stream, err = b.watch(ctx, resumeToken)
+				if err == nil {
+					break
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				var serverErr mongo.ServerError
+				if errors.As(err, &serverErr) && serverErr.HasErrorCode(changeStreamHistoryLost) {
+					log.Println("Chat event history lost, resuming from now:", err)
+					resumeToken = nil
+					continue
+				}
+				log.Println("Error reopening chat event stream:", err)
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// changeStreamHistoryLost is returned when the resume token has fallen off
+// the oplog.
+const changeStreamHistoryLost = 286
+
+func (b *MongoBroker) watch(ctx context.Context, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}}}
+	opts := options.ChangeStream()
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+	return b.collection.Watch(ctx, pipeline, opts)
+}
+
+// forward sends events from the stream until it fails or ctx is done. It
+// returns the token to resume after and whether any event was read.
+func (b *MongoBroker) forward(ctx context.Context, stream *mongo.ChangeStream, events chan<- Models.ChatEvent) (bson.Raw, bool) {
+	defer stream.Close(context.Background())
+
+	delivered := false
+	for stream.Next(ctx) {
+		delivered = true
+		var change struct {
+			FullDocument Models.ChatEvent `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			log.Println("Error decoding chat event:", err)
+			continue
+		}
+		select {
+		case events <- change.FullDocument:
+		case <-ctx.Done():
+			return stream.ResumeToken(), delivered
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		log.Println("Chat event stream failed, reconnecting:", err)
+	}
+	return stream.ResumeToken(), delivered
+}
@@ -0,0 +1,74 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoBrokerResumesAfterStreamError(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("resume", func(mt *mtest.T) {
+		broker := &MongoBroker{collection: mt.Coll}
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		change := func(token string, content string) bson.D {
+			return bson.D{
+				{Key: "_id", Value: bson.D{{Key: "_data", Value: token}}},
+				{Key: "operationType", Value: "insert"},
+				{Key: "fullDocument", Value: bson.D{
+					{Key: "_id", Value: primitive.NewObjectID()},
+					{Key: "type", Value: "message"},
+					{Key: "message", Value: bson.D{{Key: "content", Value: content}}},
+				}},
+			}
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, change("first", "one")),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "stream failed"}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, change("second", "two")),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := broker.Subscribe(ctx)
+		if err != nil {
+			mt.Fatalf("subscribe: %v", err)
+		}
+		for _, want := range []string{"one", "two"} {
+			select {
+			case event := <-events:
+				if event.Message == nil || event.Message.Content != want {
+					mt.Fatalf("got %+v, want message %q", event, want)
+				}
+			case <-time.After(5 * time.Second):
+				mt.Fatalf("timed out waiting for %q", want)
+			}
+		}
+
+		cancel()
+		for range events {
+		}
+
+		var watches []string
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName != "aggregate" {
+				continue
+			}
+			token, err := started.Command.LookupErr("pipeline", "0", "$changeStream", "resumeAfter", "_data")
+			if err != nil {
+				watches = append(watches, "")
+				continue
+			}
+			watches = append(watches, token.StringValue())
+		}
+		if len(watches) < 2 || watches[0] != "" || watches[1] != "first" {
+			mt.Fatalf("watch resume tokens = %q, want a fresh watch then one resuming after %q", watches, "first")
+		}
+	})
+}
@@ -2,8 +2,14 @@ package Controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"Server/Middleware"
@@ -24,8 +30,19 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-var clients = make(map[*websocket.Conn]string)
-var broadcast = make(chan Models.Message)
+const maxAttachmentSize = 10 << 20
+
+var allowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+func getChatUploadCollection() *mongo.Collection {
+	return Database.Collection("chat_attachments")
+}
 
 func CreateChat(c *gin.Context) {
 	var chat Models.SupportChat
@@ -72,11 +89,15 @@ func CreateChat(c *gin.Context) {
 }
 
 func ReplyChat(c *gin.Context) {
-	var msg Models.Message
-	if err := c.ShouldBindJSON(&msg); err != nil {
+	var request struct {
+		Models.Message
+		MacroID string `json:"macro_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
+	msg := request.Message
 
 	user, _ := c.Get("user")
 	claims := user.(*Middleware.UserClaims)
@@ -86,21 +107,139 @@ func ReplyChat(c *gin.Context) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if request.MacroID != "" {
+		macroID, err := primitive.ObjectIDFromHex(request.MacroID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid macro ID"})
+			return
+		}
+
+		var response Models.CannedResponse
+		if err := getCannedResponseCollection().FindOne(ctx, bson.M{"_id": macroID}).Decode(&response); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Canned response not found"})
+			return
+		}
+
+		if err := applyCannedResponse(ctx, &msg, response); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	msg.SenderID = claims.ID
+	if err := resolveAttachments(ctx, &msg, claims.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateMessage(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := hydrateMessage(ctx, &msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	msg.ID = primitive.NewObjectID()
 	msg.Timestamp = time.Now()
 
-	collection := Database.Collection("chats")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := saveMessage(ctx, msg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending message"})
+		return
+	}
+
+	if err := ChatHub.Publish(ctx, msg); err != nil {
+		log.Println("Error publishing message:", err)
+	}
+
+	c.JSON(http.StatusOK, msg)
+}
+
+func UploadChatAttachment(c *gin.Context) {
+	chatID, err := primitive.ObjectIDFromHex(c.Param("chatId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	update := bson.M{"$push": bson.M{"messages": msg}}
-	_, err := collection.UpdateOne(ctx, bson.M{"_id": msg.ChatID}, update)
+	count, err := Database.Collection("chats").CountDocuments(ctx, bson.M{"_id": chatID, "is_active": true})
+	if err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+(1<<20))
+	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending message"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, msg)
+	if file.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds the 10MB limit"})
+		return
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read file"})
+		return
+	}
+	defer fileContent.Close()
+
+	mimeType, err := detectMimeType(fileContent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read file"})
+		return
+	}
+	if !allowedAttachmentTypes[mimeType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type"})
+		return
+	}
+
+	url, err := uploadToCloudinary(fileContent, fmt.Sprintf("%d-%s", time.Now().Unix(), file.Filename))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
+		return
+	}
+
+	upload := Models.ChatUpload{
+		ID:        primitive.NewObjectID(),
+		ChatID:    chatID,
+		URL:       url,
+		FileName:  file.Filename,
+		MimeType:  mimeType,
+		Size:      file.Size,
+		CreatedAt: time.Now(),
+	}
+	claims, err := optionalClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+	if claims != nil {
+		upload.UploaderID = claims.ID
+	}
+	if _, err := getChatUploadCollection().InsertOne(ctx, upload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, Models.Attachment{
+		ID:       upload.ID,
+		URL:      upload.URL,
+		FileName: upload.FileName,
+		MimeType: upload.MimeType,
+		Size:     upload.Size,
+	})
 }
 
 func GetAllChatsAndMessages(c *gin.Context) {
@@ -140,15 +279,32 @@ func ChatWebSocket(c *gin.Context) {
 	chatId := c.Query("chatId")
 	role := c.Query("role")
 
-	clients[conn] = role
+	var claims *Middleware.UserClaims
+	if token := c.Query("token"); token != "" {
+		claims, err = Middleware.ParseToken(token)
+		if err != nil {
+			conn.WriteJSON(gin.H{"error": "Invalid token"})
+			return
+		}
+		role = claims.Role.String()
+		Presence.Connect(claims.ID, Models.Role(claims.Role))
+		defer Presence.Disconnect(claims.ID)
+	} else if role == Middleware.Admin.String() || role == Middleware.Staff.String() {
+		role = Middleware.Customer.String()
+	}
+	isAdmin := claims != nil && claims.Role == Middleware.Admin
+
+	client := &wsClient{conn: conn}
+	ChatHub.Register(client, role)
+	defer ChatHub.Unregister(client)
 
-	if role == "Admin" {
+	if isAdmin {
 		collection := Database.Collection("chats")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		chatObjectID, _ := primitive.ObjectIDFromHex(chatId)
-		update := bson.M{"$set": bson.M{"admin_id": chatObjectID}}
+		update := bson.M{"$set": bson.M{"admin_id": claims.ID}}
 		_, err := collection.UpdateOne(ctx, bson.M{"_id": chatObjectID}, update)
 		if err != nil {
 			log.Println("Error updating admin_id:", err)
@@ -156,35 +312,62 @@ func ChatWebSocket(c *gin.Context) {
 		}
 	}
 
-	go handleMessages()
-
 	for {
 		var msg Models.Message
 		err := conn.ReadJSON(&msg)
 		if err != nil {
 			log.Printf("error: %v", err)
-			delete(clients, conn)
 			break
 		}
 
-		msg.Timestamp = time.Now()
-		broadcast <- msg
-	}
-}
+		if claims != nil {
+			Presence.Heartbeat(claims.ID, Models.Role(claims.Role))
+		}
+		if msg.Type == Models.MessageHeartbeat {
+			continue
+		}
 
-func handleMessages() {
-	for {
-		msg := <-broadcast
-		for client, role := range clients {
-			if role != msg.SenderRole {
-				err := client.WriteJSON(msg)
-				if err != nil {
-					log.Printf("WebSocket error: %v", err)
-					client.Close()
-					delete(clients, client)
-				}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		uploaderID := primitive.NilObjectID
+		if claims != nil {
+			uploaderID = claims.ID
+			msg.SenderID = claims.ID
+		}
+		if err := resolveAttachments(ctx, &msg, uploaderID); err != nil {
+			cancel()
+			client.WriteJSON(gin.H{"error": err.Error()})
+			continue
+		}
+
+		if err := validateMessage(&msg); err != nil {
+			cancel()
+			client.WriteJSON(gin.H{"error": err.Error()})
+			continue
+		}
+
+		if isAdmin {
+			if err := expandSlashCommand(ctx, &msg); err != nil {
+				cancel()
+				client.WriteJSON(gin.H{"error": err.Error()})
+				continue
 			}
 		}
+
+		if err := hydrateMessage(ctx, &msg); err != nil {
+			cancel()
+			client.WriteJSON(gin.H{"error": err.Error()})
+			continue
+		}
+
+		msg.ID = primitive.NewObjectID()
+		msg.Timestamp = time.Now()
+		if err := saveMessage(ctx, msg); err != nil {
+			log.Println("Error saving message:", err)
+		}
+		if err := ChatHub.Publish(ctx, msg); err != nil {
+			log.Println("Error publishing message:", err)
+		}
+		cancel()
 	}
 }
 
@@ -231,6 +414,12 @@ func GetChatMessages(c *gin.Context) {
 		return
 	}
 
+	for i := range messages {
+		if err := hydrateMessage(ctx, &messages[i]); err != nil {
+			log.Printf("Error hydrating message %s: %v", messages[i].ID.Hex(), err)
+		}
+	}
+
 	c.JSON(http.StatusOK, messages)
 }
 
@@ -255,3 +444,177 @@ func GetChatInfo(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"guest_name": chat.GuestName})
 }
+
+func validateMessage(msg *Models.Message) error {
+	if msg.Type == "" {
+		msg.Type = Models.MessageText
+	}
+
+	for _, attachment := range msg.Attachments {
+		if attachment.ID.IsZero() || attachment.URL == "" {
+			return errors.New("Attachment must be uploaded first")
+		}
+		if attachment.Size > maxAttachmentSize {
+			return errors.New("Attachment exceeds the 10MB limit")
+		}
+		if !allowedAttachmentTypes[attachment.MimeType] {
+			return errors.New("Unsupported attachment type")
+		}
+	}
+
+	switch msg.Type {
+	case Models.MessageText:
+		if strings.TrimSpace(msg.Content) == "" {
+			return errors.New("Message content is required")
+		}
+	case Models.MessageImage:
+		if len(msg.Attachments) == 0 {
+			return errors.New("Image message requires an attachment")
+		}
+		for _, attachment := range msg.Attachments {
+			if !strings.HasPrefix(attachment.MimeType, "image/") {
+				return errors.New("Image message only accepts image attachments")
+			}
+		}
+	case Models.MessageFile:
+		if len(msg.Attachments) == 0 {
+			return errors.New("File message requires an attachment")
+		}
+	case Models.MessageProductCard:
+		if msg.ProductID == primitive.NilObjectID {
+			return errors.New("Product card requires a product_id")
+		}
+	case Models.MessageServiceCard, Models.MessageBookingLink:
+		if msg.ServiceID == primitive.NilObjectID {
+			return errors.New("Service card requires a service_id")
+		}
+	default:
+		return errors.New("Invalid message type")
+	}
+
+	return nil
+}
+
+// resolveAttachments replaces the attachments of a message with the metadata
+// stored at upload time. Only uploads made to the same chat by the same
+// sender are accepted; guests are matched by the chat alone.
+func resolveAttachments(ctx context.Context, msg *Models.Message, uploaderID primitive.ObjectID) error {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(msg.Attachments))
+	for _, attachment := range msg.Attachments {
+		if attachment.ID.IsZero() {
+			return errors.New("Attachment ID is required")
+		}
+		ids = append(ids, attachment.ID)
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "chat_id": msg.ChatID}
+	if uploaderID.IsZero() {
+		filter["uploader_id"] = bson.M{"$exists": false}
+	} else {
+		filter["uploader_id"] = uploaderID
+	}
+	cursor, err := getChatUploadCollection().Find(ctx, filter)
+	if err != nil {
+		return errors.New("Failed to load attachments")
+	}
+	var uploads []Models.ChatUpload
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return errors.New("Failed to load attachments")
+	}
+	byID := make(map[primitive.ObjectID]Models.ChatUpload, len(uploads))
+	for _, upload := range uploads {
+		byID[upload.ID] = upload
+	}
+
+	attachments := make([]Models.Attachment, 0, len(ids))
+	for _, id := range ids {
+		upload, ok := byID[id]
+		if !ok {
+			return errors.New("Attachment not found")
+		}
+		attachments = append(attachments, Models.Attachment{
+			ID:       upload.ID,
+			URL:      upload.URL,
+			FileName: upload.FileName,
+			MimeType: upload.MimeType,
+			Size:     upload.Size,
+		})
+	}
+	msg.Attachments = attachments
+	return nil
+}
+
+func hydrateMessage(ctx context.Context, msg *Models.Message) error {
+	switch msg.Type {
+	case Models.MessageProductCard:
+		var product Models.Product
+		if err := getProductCollection().FindOne(ctx, bson.M{"_id": msg.ProductID}).Decode(&product); err != nil {
+			return errors.New("Product not found")
+		}
+		msg.Product = &product
+	case Models.MessageServiceCard, Models.MessageBookingLink:
+		var service Models.Service
+		if err := getServiceCollection().FindOne(ctx, bson.M{"_id": msg.ServiceID}).Decode(&service); err != nil {
+			return errors.New("Service not found")
+		}
+		if msg.Type == Models.MessageServiceCard {
+			msg.Service = &service
+		} else {
+			msg.Booking = &Models.BookingLink{
+				ServiceID:   service.ID,
+				ServiceName: service.Name,
+				Price:       service.Price,
+				URL:         clientURL("/booking/" + service.ID.Hex()),
+			}
+		}
+	}
+	return nil
+}
+
+func saveMessage(ctx context.Context, msg Models.Message) error {
+	update := bson.M{
+		"$push": bson.M{"messages": msg},
+		"$set":  bson.M{"updated_at": msg.Timestamp},
+	}
+	_, err := Database.Collection("chats").UpdateOne(ctx, bson.M{"_id": msg.ChatID}, update)
+	return err
+}
+
+// detectMimeType sniffs the type of an uploaded file from its first bytes,
+// ignoring whatever the client claimed, and rewinds it for the upload.
+func detectMimeType(file multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := file.Read(head)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Split(http.DetectContentType(head[:n]), ";")[0]), nil
+}
+
+// optionalClaims returns the claims of the bearer token sent with a request
+// on a route that also serves guests, or nil when there is none.
+func optionalClaims(c *gin.Context) (*Middleware.UserClaims, error) {
+	token := strings.TrimSpace(strings.Replace(c.GetHeader("Authorization"), "Bearer", "", 1))
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return Middleware.ParseToken(token)
+}
+
+func clientURL(path string) string {
+	base := os.Getenv("CLIENT_URL")
+	if base == "" {
+		base = "https://cleeny.onrender.com"
+	}
+	return strings.TrimRight(base, "/") + path
+}
@@ -0,0 +1,62 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestResolveAttachmentsUsesStoredMetadata(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("stored", func(mt *mtest.T) {
+		Database = mt.DB
+		chatID := primitive.NewObjectID()
+		senderID := primitive.NewObjectID()
+		uploadID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.chat_attachments", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: uploadID},
+			{Key: "chat_id", Value: chatID},
+			{Key: "uploader_id", Value: senderID},
+			{Key: "url", Value: "https://cdn.example/a.png"},
+			{Key: "file_name", Value: "a.png"},
+			{Key: "mime_type", Value: "image/png"},
+			{Key: "size", Value: int64(42)},
+		}))
+
+		msg := Models.Message{ChatID: chatID, Type: Models.MessageImage, Attachments: []Models.Attachment{
+			{ID: uploadID, URL: "https://evil.example/x.exe", MimeType: "image/png", Size: 1},
+		}}
+		if err := resolveAttachments(context.Background(), &msg, senderID); err != nil {
+			mt.Fatalf("resolveAttachments: %v", err)
+		}
+		if got := msg.Attachments[0]; got.URL != "https://cdn.example/a.png" || got.Size != 42 {
+			mt.Fatalf("attachment not loaded from the server: %+v", got)
+		}
+	})
+	mt.Run("unknown upload", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.chat_attachments", mtest.FirstBatch))
+
+		msg := Models.Message{ChatID: primitive.NewObjectID(), Type: Models.MessageFile, Attachments: []Models.Attachment{
+			{ID: primitive.NewObjectID(), URL: "https://evil.example/x.pdf", MimeType: "application/pdf"},
+		}}
+		if err := resolveAttachments(context.Background(), &msg, primitive.NewObjectID()); err == nil {
+			mt.Fatal("expected an upload of another sender or chat to be rejected")
+		}
+	})
+}
+
+func TestValidateMessageRequiresUploadedAttachments(t *testing.T) {
+	msg := Models.Message{Type: Models.MessageFile, Attachments: []Models.Attachment{
+		{URL: "https://cdn.example/a.pdf", MimeType: "application/pdf"},
+	}}
+	if err := validateMessage(&msg); err == nil {
+		t.Fatal("expected an attachment without an upload ID to be rejected")
+	}
+}
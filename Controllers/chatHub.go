@@ -0,0 +1,127 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gorilla/websocket"
+)
+
+type ChatClient interface {
+	WriteJSON(v interface{}) error
+	Close() error
+}
+
+type Hub struct {
+	broker  Broker
+	mu      sync.RWMutex
+	clients map[ChatClient]string
+}
+
+var ChatHub = NewHub(NewMemoryBroker())
+
+func NewHub(broker Broker) *Hub {
+	return &Hub{
+		broker:  broker,
+		clients: make(map[ChatClient]string),
+	}
+}
+
+func (h *Hub) Register(client ChatClient, role string) {
+	h.mu.Lock()
+	h.clients[client] = role
+	h.mu.Unlock()
+}
+
+func (h *Hub) Unregister(client ChatClient) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+}
+
+func (h *Hub) Publish(ctx context.Context, msg Models.Message) error {
+	return h.broker.Publish(ctx, Models.ChatEvent{
+		Type:      Models.ChatEventMessage,
+		Message:   &msg,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (h *Hub) PublishPresence(ctx context.Context, presence Models.PresenceEvent) error {
+	return h.broker.Publish(ctx, Models.ChatEvent{
+		Type:      Models.ChatEventPresence,
+		Presence:  &presence,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Run delivers broker events to the clients of this hub until ctx is done.
+// It only returns early when the subscription ends on its own.
+func (h *Hub) Run(ctx context.Context) error {
+	events, err := h.broker.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	for event := range events {
+		h.deliver(event)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.New("chat event subscription closed")
+}
+
+func (h *Hub) deliver(event Models.ChatEvent) {
+	var payload interface{}
+	var shouldSend func(role string) bool
+
+	switch {
+	case event.Message != nil:
+		payload = event.Message
+		shouldSend = func(role string) bool { return role != event.Message.SenderRole }
+	case event.Presence != nil:
+		payload = event
+		shouldSend = func(role string) bool { return role == "Admin" }
+	default:
+		return
+	}
+
+	h.mu.RLock()
+	var failed []ChatClient
+	for client, role := range h.clients {
+		if !shouldSend(role) {
+			continue
+		}
+		if err := client.WriteJSON(payload); err != nil {
+			log.Printf("WebSocket error: %v", err)
+			failed = append(failed, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range failed {
+		client.Close()
+		h.Unregister(client)
+	}
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsClient) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,117 @@
+package Controllers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"Server/Models"
+)
+
+type recordingClient struct {
+	mu       sync.Mutex
+	received []interface{}
+	notify   chan struct{}
+}
+
+func newRecordingClient() *recordingClient {
+	return &recordingClient{notify: make(chan struct{}, 16)}
+}
+
+func (c *recordingClient) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	c.received = append(c.received, v)
+	c.mu.Unlock()
+	c.notify <- struct{}{}
+	return nil
+}
+
+func (c *recordingClient) Close() error { return nil }
+
+func (c *recordingClient) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.notify:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a chat event")
+	}
+}
+
+func TestHubsShareMessagesThroughBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	first, second := NewHub(broker), NewHub(broker)
+
+	customer := newRecordingClient()
+	admin := newRecordingClient()
+	first.Register(customer, "Customer")
+	second.Register(admin, "Admin")
+
+	var wg sync.WaitGroup
+	for _, hub := range []*Hub{first, second} {
+		wg.Add(1)
+		go func(hub *Hub) {
+			defer wg.Done()
+			hub.Run(ctx)
+		}(hub)
+	}
+	waitForSubscribers(t, broker, 2)
+
+	if err := first.Publish(ctx, Models.Message{SenderRole: "Customer", Content: "hello"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	admin.wait(t)
+
+	admin.mu.Lock()
+	msg, ok := admin.received[0].(*Models.Message)
+	admin.mu.Unlock()
+	if !ok || msg.Content != "hello" {
+		t.Fatalf("admin on the other hub got %#v", admin.received[0])
+	}
+
+	customer.mu.Lock()
+	echoed := len(customer.received)
+	customer.mu.Unlock()
+	if echoed != 0 {
+		t.Fatalf("sender role should not receive its own message, got %d", echoed)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestHubRunReturnsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := NewHub(NewMemoryBroker())
+
+	done := make(chan error, 1)
+	go func() { done <- hub.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("Run returned %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not stop")
+	}
+}
+
+func waitForSubscribers(t *testing.T, broker *MemoryBroker, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		broker.mu.RLock()
+		got := len(broker.subscribers)
+		broker.mu.RUnlock()
+		if got >= want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("broker has fewer than %d subscribers", want)
+}
@@ -0,0 +1,289 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type transcriptEntry struct {
+	Sender      string
+	Timestamp   time.Time
+	Content     string
+	Attachments []Models.Attachment
+}
+
+type chatTranscript struct {
+	ChatID        primitive.ObjectID
+	CustomerName  string
+	CustomerEmail string
+	CustomerPhone string
+	StartedAt     time.Time
+	Entries       []transcriptEntry
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Chat transcript</title></head>
+<body style="font-family: Arial, sans-serif;">
+<h2>Chat transcript</h2>
+<p>Customer: {{.CustomerName}}{{if .CustomerPhone}} ({{.CustomerPhone}}){{end}}<br>
+Started: {{.StartedAt.Format "02/01/2006 15:04"}}</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+{{range .Entries}}<tr style="border-bottom: 1px solid #ddd;">
+<td style="white-space: nowrap; vertical-align: top;">{{.Timestamp.Format "02/01/2006 15:04"}}</td>
+<td style="vertical-align: top;"><b>{{.Sender}}</b></td>
+<td>{{.Content}}{{range .Attachments}}<br><a href="{{.URL}}">{{.FileName}}</a>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func loadTranscript(ctx context.Context, chatID primitive.ObjectID) (*chatTranscript, error) {
+	var chat Models.SupportChat
+	if err := Database.Collection("chats").FindOne(ctx, bson.M{"_id": chatID}).Decode(&chat); err != nil {
+		return nil, err
+	}
+
+	transcript := &chatTranscript{
+		ChatID:        chat.ID,
+		CustomerName:  chat.GuestName,
+		CustomerPhone: chat.GuestPhone,
+		StartedAt:     chat.CreatedAt,
+	}
+
+	names := make(map[primitive.ObjectID]string)
+	userName := func(id primitive.ObjectID) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		var user Models.User
+		if err := getUserCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&user); err != nil {
+			names[id] = ""
+			return ""
+		}
+		names[id] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		return names[id]
+	}
+
+	if chat.CustomerID != primitive.NilObjectID {
+		var customer Models.User
+		if err := getUserCollection().FindOne(ctx, bson.M{"_id": chat.CustomerID}).Decode(&customer); err == nil {
+			transcript.CustomerName = strings.TrimSpace(customer.FirstName + " " + customer.LastName)
+			transcript.CustomerEmail = customer.Email
+			transcript.CustomerPhone = customer.Phone
+		}
+	}
+	if transcript.CustomerName == "" {
+		transcript.CustomerName = "Guest"
+	}
+
+	for _, msg := range chat.Messages {
+		hydrateMessage(ctx, &msg)
+
+		sender := ""
+		if msg.SenderID != primitive.NilObjectID {
+			sender = userName(msg.SenderID)
+		}
+		if sender == "" {
+			switch {
+			case msg.SenderRole == "Admin" || msg.SenderRole == "Staff":
+				sender = "Support"
+			case msg.GuestName != "":
+				sender = msg.GuestName
+			default:
+				sender = transcript.CustomerName
+			}
+		}
+
+		transcript.Entries = append(transcript.Entries, transcriptEntry{
+			Sender:      sender,
+			Timestamp:   msg.Timestamp,
+			Content:     transcriptContent(msg),
+			Attachments: msg.Attachments,
+		})
+	}
+
+	return transcript, nil
+}
+
+func transcriptContent(msg Models.Message) string {
+	switch {
+	case msg.Product != nil:
+		return fmt.Sprintf("[Product] %s - %s", msg.Product.Name, strconv.FormatInt(msg.Product.Price.MinorUnits(), 10))
+	case msg.Service != nil:
+		return fmt.Sprintf("[Service] %s - %s", msg.Service.Name, strconv.FormatInt(msg.Service.Price.MinorUnits(), 10))
+	case msg.Booking != nil:
+		return fmt.Sprintf("[Booking] %s: %s", msg.Booking.ServiceName, msg.Booking.URL)
+	}
+	return msg.Content
+}
+
+func (t *chatTranscript) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Chat transcript\n")
+	fmt.Fprintf(&b, "Customer: %s", t.CustomerName)
+	if t.CustomerPhone != "" {
+		fmt.Fprintf(&b, " (%s)", t.CustomerPhone)
+	}
+	fmt.Fprintf(&b, "\nStarted: %s\n\n", t.StartedAt.Format("02/01/2006 15:04"))
+
+	for _, entry := range t.Entries {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", entry.Timestamp.Format("02/01/2006 15:04"), entry.Sender, entry.Content)
+		for _, attachment := range entry.Attachments {
+			fmt.Fprintf(&b, "    Attachment: %s (%s)\n", attachment.FileName, attachment.URL)
+		}
+	}
+	return b.String()
+}
+
+func (t *chatTranscript) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := transcriptTemplate.Execute(&buf, t); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (t *chatTranscript) PDF() []byte {
+	var doc pdfDocument
+	doc.Heading("Chat transcript")
+	customer := "Customer: " + t.CustomerName
+	if t.CustomerPhone != "" {
+		customer += " (" + t.CustomerPhone + ")"
+	}
+	doc.Text(customer)
+	doc.Text("Started: " + t.StartedAt.Format("02/01/2006 15:04"))
+	doc.Blank()
+
+	for _, entry := range t.Entries {
+		doc.Bold(fmt.Sprintf("%s  %s", entry.Timestamp.Format("02/01/2006 15:04"), entry.Sender))
+		if entry.Content != "" {
+			doc.Text(entry.Content)
+		}
+		for _, attachment := range entry.Attachments {
+			doc.Text("Attachment: " + attachment.FileName + " (" + attachment.URL + ")")
+		}
+		doc.Blank()
+	}
+	return doc.Bytes()
+}
+
+func GetChatTranscript(c *gin.Context) {
+	chatID, err := primitive.ObjectIDFromHex(c.Param("chatId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transcript, err := loadTranscript(ctx, chatID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	fileName := "transcript-" + chatID.Hex()
+	switch c.DefaultQuery("format", "text") {
+	case "text", "txt":
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".txt")
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(transcript.Text()))
+	case "html":
+		html, err := transcript.HTML()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render transcript"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".pdf")
+		c.Data(http.StatusOK, "application/pdf", transcript.PDF())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+	}
+}
+
+func CloseChat(c *gin.Context) {
+	chatID, err := primitive.ObjectIDFromHex(c.Param("chatId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	var request struct {
+		EmailTranscript bool   `json:"email_transcript"`
+		Email           string `json:"email"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"is_active": false, "updated_at": time.Now()}}
+	result, err := Database.Collection("chats").UpdateOne(ctx, bson.M{"_id": chatID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error closing chat"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	if !request.EmailTranscript {
+		c.JSON(http.StatusOK, gin.H{"message": "Chat closed"})
+		return
+	}
+
+	transcript, err := loadTranscript(ctx, chatID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transcript"})
+		return
+	}
+
+	recipient := request.Email
+	if recipient == "" {
+		recipient = transcript.CustomerEmail
+	}
+	if recipient == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Chat closed", "email_sent": false, "email_error": "No email address for this customer"})
+		return
+	}
+
+	html, err := transcript.HTML()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render transcript"})
+		return
+	}
+
+	attachment := mailAttachment{
+		FileName:    "transcript-" + chatID.Hex() + ".pdf",
+		ContentType: "application/pdf",
+		Data:        transcript.PDF(),
+	}
+	if err := sendMail(recipient, "Your support chat transcript", html, attachment); err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Chat closed", "email_sent": false, "email_error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat closed", "email_sent": true})
+}
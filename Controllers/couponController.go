@@ -0,0 +1,628 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	CouponPercentage   = "percentage"
+	CouponFixed        = "fixed"
+	CouponFreeShipping = "free_shipping"
+)
+
+var (
+	errCouponExhausted = errors.New("This coupon has been fully redeemed")
+	errCouponUserLimit = errors.New("You have already used this coupon")
+)
+
+// discountItem is one line of a cart, order or booking a discount can apply to.
+type discountItem struct {
+	ID       primitive.ObjectID
+	Name     string
+	Category primitive.ObjectID
+	Quantity int
+	Amount   Models.Money
+}
+
+// discountCart is the snapshot of what is being bought that coupons and
+// promotions are evaluated against.
+type discountCart struct {
+	TargetType  string
+	Items       []discountItem
+	ShippingFee Models.Money
+}
+
+func (cart discountCart) subtotal() Models.Money {
+	var total Models.Money
+	for _, item := range cart.Items {
+		total = total.Add(item.Amount)
+	}
+	return total
+}
+
+func getCouponCollection() *mongo.Collection {
+	return Database.Collection("coupons")
+}
+
+func getCouponRedemptionCollection() *mongo.Collection {
+	return Database.Collection("coupon_redemptions")
+}
+
+// getCouponUsageCollection holds one counter per coupon and customer, so the
+// per-customer limit can be enforced with a conditional update.
+func getCouponUsageCollection() *mongo.Collection {
+	return Database.Collection("coupon_usage")
+}
+
+func GetCoupons(c *gin.Context) {
+	var coupons []Models.Coupon
+	cursor, err := getCouponCollection().Find(context.Background(), bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get coupons"})
+		return
+	}
+	if err := cursor.All(context.Background(), &coupons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode coupons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupons)
+}
+
+func CreateCoupon(c *gin.Context) {
+	var coupon Models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if coupon.Type == CouponFixed {
+		coupon.Amount, coupon.Value = fixedDiscount(coupon.Amount, coupon.Value)
+	}
+	if err := validateCoupon(coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if count, err := getCouponCollection().CountDocuments(ctx, bson.M{"code": coupon.Code}); err != nil || count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+		return
+	}
+
+	coupon.ID = primitive.NewObjectID()
+	coupon.UsedCount = 0
+	coupon.CreatedAt = time.Now()
+	coupon.UpdatedAt = time.Now()
+
+	if _, err := getCouponCollection().InsertOne(ctx, coupon); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+func UpdateCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var coupon Models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if coupon.Type == CouponFixed {
+		coupon.Amount, coupon.Value = fixedDiscount(coupon.Amount, coupon.Value)
+	}
+	if err := validateCoupon(coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if count, err := getCouponCollection().CountDocuments(ctx, bson.M{"code": coupon.Code, "_id": bson.M{"$ne": id}}); err != nil || count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"code":               coupon.Code,
+		"description":        coupon.Description,
+		"type":               coupon.Type,
+		"value":              coupon.Value,
+		"amount":             coupon.Amount,
+		"max_discount":       coupon.MaxDiscount,
+		"min_spend":          coupon.MinSpend,
+		"starts_at":          coupon.StartsAt,
+		"ends_at":            coupon.EndsAt,
+		"usage_limit":        coupon.UsageLimit,
+		"per_user_limit":     coupon.PerUserLimit,
+		"product_categories": coupon.ProductCategories,
+		"service_categories": coupon.ServiceCategories,
+		"active":             coupon.Active,
+		"updated_at":         time.Now(),
+	}}
+	result, err := getCouponCollection().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	coupon.ID = id
+	c.JSON(http.StatusOK, coupon)
+}
+
+func DeleteCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getCouponCollection().DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ValidateCoupon previews a coupon against the customer's selected items
+// (target_type "order") or a service booking quote (target_type "booking").
+func ValidateCoupon(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		quoteRequest
+		Code             string             `json:"code"`
+		TargetType       string             `json:"target_type"`
+		ServiceID        primitive.ObjectID `json:"service_id"`
+		ShippingMethodID primitive.ObjectID `json:"shipping_method_id"`
+		AddressID        primitive.ObjectID `json:"address_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var cart discountCart
+	switch request.TargetType {
+	case PaymentTargetBooking:
+		var service Models.Service
+		if err := getServiceCollection().FindOne(ctx, bson.M{"_id": request.ServiceID}).Decode(&service); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		result, err := quoteService(service, request.quoteRequest)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cart = bookingDiscountCart(service, request.Quantity, result.Total)
+
+	case PaymentTargetOrder, "":
+		var err error
+		cart, err = selectedItemsDiscountCart(ctx, claims.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if request.ShippingMethodID != primitive.NilObjectID && request.AddressID != primitive.NilObjectID {
+			var method Models.ShippingMethod
+			saved, addressErr := loadSavedAddress(ctx, claims.ID, request.AddressID)
+			if addressErr == nil && getShippingMethodCollection().FindOne(ctx, bson.M{"_id": request.ShippingMethodID}).Decode(&method) == nil {
+				cart.ShippingFee, _ = shippingFee(ctx, method, Shipment{Address: saved.Location, Weight: cartWeight(ctx, claims.ID), Value: cart.subtotal()})
+			}
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target type must be order or booking"})
+		return
+	}
+
+	subtotal := cart.subtotal()
+	cart, lines, promotions, err := evaluatePromotions(ctx, claims.ID, cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate promotions"})
+		return
+	}
+
+	_, couponLines, err := evaluateCoupon(ctx, request.Code, claims.ID, cart)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lines = append(lines, couponLines...)
+
+	discount := discountTotal(lines)
+	c.JSON(http.StatusOK, gin.H{
+		"code":       strings.ToUpper(request.Code),
+		"subtotal":   subtotal,
+		"shipping":   cart.ShippingFee,
+		"discount":   discount,
+		"total":      subtotal.Add(cart.ShippingFee).Sub(discount).NonNegative(),
+		"discounts":  lines,
+		"promotions": promotions,
+	})
+}
+
+// fixedDiscount returns the amount of a fixed coupon or promotion, taking it
+// from value when an older client sent it there, and clears value.
+func fixedDiscount(amount Models.Money, value float64) (Models.Money, float64) {
+	if amount.IsZero() {
+		return Models.NewMoney(value), 0
+	}
+	return amount, 0
+}
+
+func validateCoupon(coupon Models.Coupon) error {
+	if coupon.Code == "" {
+		return errors.New("Code is required")
+	}
+	switch coupon.Type {
+	case CouponPercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("Percentage must be between 0 and 100")
+		}
+	case CouponFixed:
+		if coupon.Amount.Sign() <= 0 {
+			return errors.New("Amount must be positive")
+		}
+	case CouponFreeShipping:
+	default:
+		return errors.New("Type must be percentage, fixed or free_shipping")
+	}
+	if coupon.MaxDiscount.Sign() < 0 || coupon.MinSpend.Sign() < 0 || coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return errors.New("Limits cannot be negative")
+	}
+	if !coupon.EndsAt.IsZero() && coupon.EndsAt.Before(coupon.StartsAt) {
+		return errors.New("End date must be after start date")
+	}
+	return nil
+}
+
+// evaluateCoupon checks that a coupon can be used by the customer on the cart
+// and spreads its discount over the eligible lines.
+func evaluateCoupon(ctx context.Context, code string, userID primitive.ObjectID, cart discountCart) (Models.Coupon, []Models.DiscountLine, error) {
+	var coupon Models.Coupon
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if err := getCouponCollection().FindOne(ctx, bson.M{"code": code, "active": true}).Decode(&coupon); err != nil {
+		return coupon, nil, errors.New("Coupon not found")
+	}
+
+	now := time.Now()
+	if (!coupon.StartsAt.IsZero() && now.Before(coupon.StartsAt)) || (!coupon.EndsAt.IsZero() && now.After(coupon.EndsAt)) {
+		return coupon, nil, errors.New("This coupon is not valid at this time")
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return coupon, nil, errCouponExhausted
+	}
+	if coupon.PerUserLimit > 0 {
+		used, err := getCouponRedemptionCollection().CountDocuments(ctx, bson.M{"coupon_id": coupon.ID, "user_id": userID, "released_at": bson.M{"$exists": false}})
+		if err != nil {
+			return coupon, nil, err
+		}
+		if int(used) >= coupon.PerUserLimit {
+			return coupon, nil, errCouponUserLimit
+		}
+	}
+
+	var eligible []discountItem
+	for _, item := range cart.Items {
+		if couponCovers(coupon, cart.TargetType, item.Category) {
+			eligible = append(eligible, item)
+		}
+	}
+	if len(eligible) == 0 {
+		return coupon, nil, errors.New("This coupon does not apply to these items")
+	}
+
+	eligibleTotal := discountCart{Items: eligible}.subtotal()
+	if eligibleTotal.Cmp(coupon.MinSpend) < 0 {
+		return coupon, nil, fmt.Errorf("Spend at least %s to use this coupon", coupon.MinSpend)
+	}
+
+	label := coupon.Description
+	if label == "" {
+		label = "Coupon " + coupon.Code
+	}
+
+	switch coupon.Type {
+	case CouponFreeShipping:
+		if cart.ShippingFee.Sign() <= 0 {
+			return coupon, nil, errors.New("This coupon only applies to shipping fees")
+		}
+		return coupon, []Models.DiscountLine{{Source: "coupon", Code: coupon.Code, Label: label, Amount: cart.ShippingFee}}, nil
+	case CouponPercentage:
+		discount := eligibleTotal.Percent(coupon.Value)
+		if coupon.MaxDiscount.Sign() > 0 {
+			discount = Models.MinMoney(discount, coupon.MaxDiscount)
+		}
+		return coupon, allocateDiscount("coupon", coupon.Code, label, discount, eligible), nil
+	default:
+		return coupon, allocateDiscount("coupon", coupon.Code, label, Models.MinMoney(coupon.Amount, eligibleTotal), eligible), nil
+	}
+}
+
+func couponCovers(coupon Models.Coupon, targetType string, category primitive.ObjectID) bool {
+	return categoryScoped(coupon.ProductCategories, coupon.ServiceCategories, targetType, category)
+}
+
+// categoryScoped reports whether a rule limited to the given product and
+// service categories covers an item. A rule with no categories covers
+// everything; a rule scoped only to the other kind of target covers nothing.
+func categoryScoped(productCategories, serviceCategories []primitive.ObjectID, targetType string, category primitive.ObjectID) bool {
+	categories, other := productCategories, serviceCategories
+	if targetType == PaymentTargetBooking {
+		categories, other = serviceCategories, productCategories
+	}
+	if len(categories) == 0 {
+		return len(other) == 0
+	}
+	for _, id := range categories {
+		if id == category {
+			return true
+		}
+	}
+	return false
+}
+
+// allocateDiscount splits a discount over items in proportion to their
+// amounts, giving the rounding remainder to the last line so the lines
+// always add up to the discount.
+func allocateDiscount(source, code, label string, discount Models.Money, items []discountItem) []Models.DiscountLine {
+	total := discountCart{Items: items}.subtotal()
+	if discount.Sign() <= 0 || total.Sign() <= 0 {
+		return nil
+	}
+
+	lines := make([]Models.DiscountLine, 0, len(items))
+	remaining := discount
+	for i, item := range items {
+		amount := discount.Share(item.Amount, total)
+		if i == len(items)-1 || amount.Cmp(remaining) > 0 {
+			amount = remaining
+		}
+		remaining = remaining.Sub(amount)
+		lines = append(lines, Models.DiscountLine{Source: source, Code: code, Label: label + " - " + item.Name, ItemID: item.ID, Amount: amount})
+	}
+	return lines
+}
+
+func discountTotal(lines []Models.DiscountLine) Models.Money {
+	var total Models.Money
+	for _, line := range lines {
+		total = total.Add(line.Amount)
+	}
+	return total
+}
+
+// applyDiscountLines returns a copy of the cart with each line's amount
+// taken off the item it was allocated to, so the next discount in the chain
+// is computed on what is left.
+func applyDiscountLines(cart discountCart, lines []Models.DiscountLine) discountCart {
+	cart.Items = append([]discountItem(nil), cart.Items...)
+	for _, line := range lines {
+		for i := range cart.Items {
+			if cart.Items[i].ID == line.ItemID {
+				cart.Items[i].Amount = cart.Items[i].Amount.Sub(line.Amount).NonNegative()
+				break
+			}
+		}
+	}
+	return cart
+}
+
+func couponDiscount(lines []Models.DiscountLine) Models.Money {
+	var total Models.Money
+	for _, line := range lines {
+		if line.Source == "coupon" {
+			total = total.Add(line.Amount)
+		}
+	}
+	return total
+}
+
+// redeemCoupon claims one use of a coupon for an order or booking. The usage
+// limit and the per-customer limit are part of the update filters so
+// concurrent checkouts cannot exceed them.
+func redeemCoupon(ctx context.Context, coupon Models.Coupon, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID, discount Models.Money) error {
+	key := couponUsageKey(coupon.ID, userID)
+	if err := seedCouponUsage(ctx, key, coupon.ID, userID); err != nil {
+		return err
+	}
+	usage := bson.M{"_id": key}
+	if coupon.PerUserLimit > 0 {
+		usage["used"] = bson.M{"$lt": coupon.PerUserLimit}
+	}
+	result, err := getCouponUsageCollection().UpdateOne(ctx, usage, bson.M{"$inc": bson.M{"used": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errCouponUserLimit
+	}
+	undoUsage := func() {
+		if _, err := getCouponUsageCollection().UpdateOne(context.Background(), bson.M{"_id": key}, bson.M{"$inc": bson.M{"used": -1}}); err != nil {
+			log.Println("Error releasing coupon use:", err)
+		}
+	}
+
+	filter := bson.M{
+		"_id": coupon.ID,
+		"$expr": bson.M{"$or": bson.A{
+			bson.M{"$lte": bson.A{"$usage_limit", 0}},
+			bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}},
+		}},
+	}
+	result, err = getCouponCollection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"used_count": 1}})
+	if err == nil && result.MatchedCount == 0 {
+		err = errCouponExhausted
+	}
+	if err != nil {
+		undoUsage()
+		return err
+	}
+
+	redemption := Models.CouponRedemption{
+		ID:         primitive.NewObjectID(),
+		CouponID:   coupon.ID,
+		Code:       coupon.Code,
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Discount:   discount,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := getCouponRedemptionCollection().InsertOne(ctx, redemption); err != nil {
+		undoUsage()
+		if _, undoErr := getCouponCollection().UpdateOne(context.Background(), bson.M{"_id": coupon.ID}, bson.M{"$inc": bson.M{"used_count": -1}}); undoErr != nil {
+			log.Println("Error releasing coupon use:", undoErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// releaseCoupon gives back the coupon use of a cancelled order or booking, or
+// of one that could not be saved. The redemption is marked released first so
+// the use is only given back once.
+func releaseCoupon(ctx context.Context, targetType string, targetID primitive.ObjectID) error {
+	var redemption Models.CouponRedemption
+	filter := bson.M{"target_type": targetType, "target_id": targetID, "released_at": bson.M{"$exists": false}}
+	err := getCouponRedemptionCollection().FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"released_at": time.Now()}}).Decode(&redemption)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := getCouponCollection().UpdateOne(ctx, bson.M{"_id": redemption.CouponID, "used_count": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"used_count": -1}}); err != nil {
+		return err
+	}
+	key := couponUsageKey(redemption.CouponID, redemption.UserID)
+	_, err = getCouponUsageCollection().UpdateOne(ctx, bson.M{"_id": key, "used": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"used": -1}})
+	return err
+}
+
+func couponUsageKey(couponID, userID primitive.ObjectID) string {
+	return couponID.Hex() + ":" + userID.Hex()
+}
+
+// seedCouponUsage creates a missing per-customer counter from the customer's
+// redemptions, so uses from before counters existed are still counted.
+func seedCouponUsage(ctx context.Context, key string, couponID, userID primitive.ObjectID) error {
+	err := getCouponUsageCollection().FindOne(ctx, bson.M{"_id": key}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	used, err := getCouponRedemptionCollection().CountDocuments(ctx, bson.M{"coupon_id": couponID, "user_id": userID, "released_at": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	counter := bson.M{"_id": key, "coupon_id": couponID, "user_id": userID, "used": used}
+	if _, err := getCouponUsageCollection().InsertOne(ctx, counter); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func selectedItemsDiscountCart(ctx context.Context, userID primitive.ObjectID) (discountCart, error) {
+	cart := discountCart{TargetType: PaymentTargetOrder}
+
+	var selectedItems Models.SelectedItems
+	if err := getSelectedItemsCollection().FindOne(ctx, bson.M{"user_id": userID}).Decode(&selectedItems); err != nil {
+		return cart, errors.New("No selected items found")
+	}
+	for _, item := range selectedItems.Items {
+		var product Models.Product
+		if err := getProductCollection().FindOne(ctx, bson.M{"_id": item.ProductID}).Decode(&product); err != nil {
+			return cart, errors.New("Product not found")
+		}
+		cart.Items = append(cart.Items, productDiscountItem(product, item.Quantity))
+	}
+	return cart, nil
+}
+
+func productDiscountItem(product Models.Product, quantity int) discountItem {
+	return discountItem{
+		ID:       product.ID,
+		Name:     product.Name,
+		Category: product.ProductCategory,
+		Quantity: quantity,
+		Amount:   product.Price.Mul(quantity),
+	}
+}
+
+func bookingDiscountCart(service Models.Service, quantity int, total Models.Money) discountCart {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	return discountCart{
+		TargetType: PaymentTargetBooking,
+		Items: []discountItem{{
+			ID:       service.ID,
+			Name:     service.Name,
+			Category: service.ServiceCategory,
+			Quantity: quantity,
+			Amount:   total,
+		}},
+	}
+}
+
+func cartWeight(ctx context.Context, userID primitive.ObjectID) int {
+	var selectedItems Models.SelectedItems
+	if err := getSelectedItemsCollection().FindOne(ctx, bson.M{"user_id": userID}).Decode(&selectedItems); err != nil {
+		return 0
+	}
+	weight := 0
+	for _, item := range selectedItems.Items {
+		var product Models.Product
+		if err := getProductCollection().FindOne(ctx, bson.M{"_id": item.ProductID}).Decode(&product); err == nil {
+			weight += product.Weight * item.Quantity
+		}
+	}
+	return weight
+}
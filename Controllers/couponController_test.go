@@ -0,0 +1,119 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func updatesSent(mt *mtest.T) []bson.Raw {
+	var updates []bson.Raw
+	for _, started := range mt.GetAllStartedEvents() {
+		if started.CommandName == "update" {
+			updates = append(updates, started.Command)
+		}
+	}
+	return updates
+}
+
+func TestRedeemCouponEnforcesPerUserLimitAtomically(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	coupon := Models.Coupon{ID: primitive.NewObjectID(), Code: "ONCE", PerUserLimit: 1}
+	userID := primitive.NewObjectID()
+	counter := mtest.CreateCursorResponse(0, "test.coupon_usage", mtest.FirstBatch, bson.D{
+		{Key: "_id", Value: couponUsageKey(coupon.ID, userID)},
+		{Key: "used", Value: 1},
+	})
+
+	mt.Run("limit reached", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(counter, bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+
+		err := redeemCoupon(context.Background(), coupon, userID, PaymentTargetOrder, primitive.NewObjectID(), Models.Minor(10000))
+		if err != errCouponUserLimit {
+			mt.Fatalf("redeemCoupon = %v, want %v", err, errCouponUserLimit)
+		}
+		updates := updatesSent(mt)
+		if len(updates) != 1 {
+			mt.Fatalf("got %d updates, the coupon itself must not be touched", len(updates))
+		}
+		if limit := updates[0].Lookup("updates", "0", "q", "used", "$lt").Int32(); limit != 1 {
+			mt.Fatalf("per-user limit in filter = %d, want 1", limit)
+		}
+	})
+
+	mt.Run("coupon exhausted", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(
+			counter,
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		err := redeemCoupon(context.Background(), coupon, userID, PaymentTargetOrder, primitive.NewObjectID(), Models.Minor(10000))
+		if err != errCouponExhausted {
+			mt.Fatalf("redeemCoupon = %v, want %v", err, errCouponExhausted)
+		}
+		updates := updatesSent(mt)
+		if len(updates) != 3 || updates[2].Lookup("updates", "0", "u", "$inc", "used").Int32() != -1 {
+			mt.Fatal("the customer's use must be given back when the coupon is exhausted")
+		}
+	})
+}
+
+func TestReleaseCouponGivesTheUseBackOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	orderID := primitive.NewObjectID()
+
+	mt.Run("release", func(mt *mtest.T) {
+		Database = mt.DB
+		couponID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "coupon_id", Value: couponID},
+				{Key: "user_id", Value: userID},
+			}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		if err := releaseCoupon(context.Background(), PaymentTargetOrder, orderID); err != nil {
+			mt.Fatalf("releaseCoupon: %v", err)
+		}
+		updates := updatesSent(mt)
+		if len(updates) != 2 {
+			mt.Fatalf("got %d updates, want the coupon and the customer's counter", len(updates))
+		}
+		if key := updates[1].Lookup("updates", "0", "q", "_id").StringValue(); key != couponUsageKey(couponID, userID) {
+			mt.Fatalf("released counter %q, want the customer's counter", key)
+		}
+	})
+
+	mt.Run("already released", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		if err := releaseCoupon(context.Background(), PaymentTargetOrder, orderID); err != nil {
+			mt.Fatalf("releaseCoupon: %v", err)
+		}
+		if len(updatesSent(mt)) != 0 {
+			mt.Fatal("a released redemption must not give the use back again")
+		}
+	})
+}
+
+func TestFixedDiscountAcceptsTheLegacyValueField(t *testing.T) {
+	if amount, value := fixedDiscount(Models.Money{}, 50000); amount != Models.Minor(50000) || value != 0 {
+		t.Fatalf("fixedDiscount = %s, %g, want the amount moved out of value", amount, value)
+	}
+	if amount, _ := fixedDiscount(Models.Minor(20000), 50000); amount != Models.Minor(20000) {
+		t.Fatalf("fixedDiscount = %s, want the explicit amount", amount)
+	}
+}
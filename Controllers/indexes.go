@@ -0,0 +1,57 @@
+package Controllers
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes that back uniqueness rules the handlers
+// rely on. A failure is logged rather than fatal, the same as the chat event
+// index, so a server can still start against a database it cannot alter.
+func EnsureIndexes(ctx context.Context) {
+	indexes := map[string][]mongo.IndexModel{
+		"invoices": {
+			{
+				Keys:    bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"reviews": {
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"product_alert_deliveries": {
+			{
+				Keys:    bson.D{{Key: "alert_id", Value: 1}, {Key: "event_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		// A cancelled occurrence does not hold its date, so pausing and
+		// resuming a plan can book it again. Partial indexes cannot use $ne,
+		// hence the list of every other status.
+		"order_booking_service": {
+			{
+				Keys: bson.D{{Key: "plan_id", Value: 1}, {Key: "occurrence", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{
+						"plan_id": bson.M{"$exists": true},
+						"status": bson.M{"$in": bson.A{
+							BookingPending, BookingConfirmed, BookingInProgress, BookingAwaitingConfirmation, BookingCompleted,
+						}},
+					}),
+			},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := Database.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			log.Printf("Error creating %s indexes: %v", collection, err)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errInvoiceNotReady = errors.New("Invoices are issued once the order is completed")
+	errInvoicePending  = errors.New("The invoice is being issued, please try again")
+)
+
+// invoiceClaimTimeout is how long an unnumbered invoice blocks other
+// requests before it is treated as abandoned.
+const invoiceClaimTimeout = time.Minute
+
+func getInvoiceCollection() *mongo.Collection {
+	return Database.Collection("invoices")
+}
+
+// taxClass resolves a product or service tax class, falling back to the
+// default class when it is unset or no longer configured.
+func taxClass(settings Models.TaxSettings, code string) Models.TaxClass {
+	for _, class := range settings.Classes {
+		if class.Code == code {
+			return class
+		}
+	}
+	for _, class := range settings.Classes {
+		if class.Code == settings.DefaultClass {
+			return class
+		}
+	}
+	return Models.TaxClass{Code: settings.DefaultClass}
+}
+
+// taxAmount is the VAT contained in (inclusive) or owed on top of
+// (exclusive) an amount.
+func taxAmount(amount Models.Money, rate float64, inclusive bool) Models.Money {
+	if amount.Sign() <= 0 || rate <= 0 {
+		return Models.Money{}
+	}
+	if inclusive {
+		return amount.Sub(amount.Scale(1 / (1 + rate/100)))
+	}
+	return amount.Percent(rate)
+}
+
+// applyOrderTax works out VAT per order line on what the customer pays for
+// it after discounts. With tax-exclusive prices the tax is added to the total.
+func applyOrderTax(settings Models.TaxSettings, order *Models.Order) {
+	order.TaxInclusive = settings.PricesIncludeTax
+	order.TaxTotal = Models.Money{}
+	for i, item := range order.Items {
+		amount := item.Price.Mul(item.Quantity)
+		for _, line := range order.Discounts {
+			if line.ItemID == item.ProductID {
+				amount = amount.Sub(line.Amount)
+			}
+		}
+
+		class := taxClass(settings, item.TaxClass)
+		order.Items[i].TaxClass = class.Code
+		order.Items[i].TaxRate = class.Rate
+		order.Items[i].TaxAmount = taxAmount(amount, class.Rate, settings.PricesIncludeTax)
+		order.TaxTotal = order.TaxTotal.Add(order.Items[i].TaxAmount)
+	}
+	if !settings.PricesIncludeTax {
+		order.TotalPrice = order.TotalPrice.Add(order.TaxTotal)
+	}
+}
+
+// nextInvoiceNumber hands out sequential invoice numbers that restart
+// every year, e.g. INV-2026-000042.
+func nextInvoiceNumber(ctx context.Context, prefix string, issuedAt time.Time) (string, error) {
+	year := issuedAt.In(bookingLocation).Year()
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := Database.Collection("counters").FindOneAndUpdate(ctx,
+		bson.M{"_id": fmt.Sprintf("invoice-%d", year)},
+		bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, counter.Seq), nil
+}
+
+// issueInvoice returns the invoice of a completed order or booking, creating
+// it the first time. The unique target index makes inserting the invoice the
+// claim, so only the request that wins it takes a number from the sequence.
+func issueInvoice(ctx context.Context, targetType string, targetID primitive.ObjectID) (Models.Invoice, error) {
+	var invoice Models.Invoice
+	err := getInvoiceCollection().FindOne(ctx, bson.M{"target_type": targetType, "target_id": targetID}).Decode(&invoice)
+	switch {
+	case err == nil && invoice.Number != "":
+		return invoice, nil
+	case err == nil:
+		// Another request holds the claim. One left behind by a request that
+		// died before numbering the invoice is dropped once it is stale.
+		if time.Since(invoice.IssuedAt) < invoiceClaimTimeout {
+			return invoice, errInvoicePending
+		}
+		if _, err := getInvoiceCollection().DeleteOne(ctx, bson.M{"_id": invoice.ID, "number": ""}); err != nil {
+			return invoice, err
+		}
+	case err != mongo.ErrNoDocuments:
+		return invoice, err
+	}
+
+	settings, err := loadTaxSettings(ctx)
+	if err != nil {
+		return invoice, err
+	}
+
+	invoice, collection, err := buildInvoice(ctx, targetType, targetID)
+	if err != nil {
+		return invoice, err
+	}
+
+	invoice.ID = primitive.NewObjectID()
+	invoice.Company = settings.Company
+	invoice.IssuedAt = time.Now()
+	if _, err := getInvoiceCollection().InsertOne(ctx, invoice); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return invoice, errInvoicePending
+		}
+		return invoice, err
+	}
+
+	number, err := nextInvoiceNumber(ctx, settings.InvoicePrefix, invoice.IssuedAt)
+	if err == nil {
+		_, err = getInvoiceCollection().UpdateOne(ctx, bson.M{"_id": invoice.ID}, bson.M{"$set": bson.M{"number": number}})
+	}
+	if err != nil {
+		// Give up the claim so the next request can issue the invoice.
+		if _, deleteErr := getInvoiceCollection().DeleteOne(ctx, bson.M{"_id": invoice.ID}); deleteErr != nil {
+			log.Println("Error releasing invoice claim:", deleteErr)
+		}
+		return invoice, err
+	}
+	invoice.Number = number
+
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": targetID}, bson.M{"$set": bson.M{"invoice_number": number}}); err != nil {
+		log.Println("Error stamping invoice number:", err)
+	}
+	return invoice, nil
+}
+
+// buildInvoice snapshots the lines and customer details of an order or
+// booking. It does not number the invoice.
+func buildInvoice(ctx context.Context, targetType string, targetID primitive.ObjectID) (Models.Invoice, *mongo.Collection, error) {
+	invoice := Models.Invoice{TargetType: targetType, TargetID: targetID, Currency: Models.DefaultCurrency}
+
+	switch targetType {
+	case PaymentTargetOrder:
+		var order Models.Order
+		if err := getOrderCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&order); err != nil {
+			return invoice, nil, err
+		}
+		if order.Status != OrderCompleted {
+			return invoice, nil, errInvoiceNotReady
+		}
+
+		invoice.UserID = order.UserID
+		invoice.TaxInclusive = order.TaxInclusive
+		fillInvoiceCustomer(ctx, &invoice, order.Recipient, order.Phone, order.Address)
+		for _, item := range order.Items {
+			var discount Models.Money
+			for _, line := range order.Discounts {
+				if line.ItemID == item.ProductID {
+					discount = discount.Add(line.Amount)
+				}
+			}
+			amount := item.Price.Mul(item.Quantity).Sub(discount)
+			if !order.TaxInclusive {
+				amount = amount.Add(item.TaxAmount)
+			}
+			invoice.Lines = append(invoice.Lines, Models.InvoiceLine{
+				Description: item.Name,
+				Quantity:    item.Quantity,
+				UnitPrice:   item.Price,
+				Discount:    discount,
+				TaxRate:     item.TaxRate,
+				TaxAmount:   item.TaxAmount,
+				Amount:      amount,
+			})
+			invoice.Subtotal = invoice.Subtotal.Add(item.Price.Mul(item.Quantity))
+		}
+		if order.Shipping != nil {
+			invoice.Shipping = order.Shipping.Fee
+		}
+		invoice.Discount = order.DiscountTotal
+		invoice.TaxTotal = order.TaxTotal
+		invoice.Total = order.TotalPrice
+		return invoice, getOrderCollection(), nil
+
+	case PaymentTargetBooking:
+		var booking Models.OrderBookingService
+		if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&booking); err != nil {
+			return invoice, nil, err
+		}
+		if booking.Status != BookingCompleted {
+			return invoice, nil, errInvoiceNotReady
+		}
+
+		var service Models.Service
+		getServiceCollection().FindOne(ctx, bson.M{"_id": booking.ServiceID}).Decode(&service)
+
+		invoice.UserID = booking.UserID
+		invoice.TaxInclusive = booking.TaxInclusive
+		fillInvoiceCustomer(ctx, &invoice, booking.ContactName, booking.ContactPhone, booking.Location)
+		if invoice.Address == "" {
+			invoice.Address = booking.Address
+		}
+
+		quantity := booking.Quantity
+		if quantity <= 0 {
+			quantity = 1
+		}
+		discount := discountTotal(booking.Discounts)
+		gross := booking.TotalPrice.Add(discount)
+		if !booking.TaxInclusive {
+			gross = gross.Sub(booking.TaxAmount)
+		}
+		invoice.Lines = []Models.InvoiceLine{{
+			Description: service.Name + " - " + booking.BookingDate.Time().In(bookingLocation).Format("02/01/2006 15:04"),
+			Quantity:    quantity,
+			UnitPrice:   gross.Scale(1 / float64(quantity)),
+			Discount:    discount,
+			TaxRate:     booking.TaxRate,
+			TaxAmount:   booking.TaxAmount,
+			Amount:      booking.TotalPrice,
+		}}
+		invoice.Subtotal = gross
+		invoice.Discount = discount
+		invoice.TaxTotal = booking.TaxAmount
+		invoice.Total = booking.TotalPrice
+		return invoice, getOrderBookingServiceCollection(), nil
+	}
+
+	return invoice, nil, errors.New("Unknown invoice target")
+}
+
+func fillInvoiceCustomer(ctx context.Context, invoice *Models.Invoice, name, phone string, address *Models.Address) {
+	var user Models.User
+	if err := getUserCollection().FindOne(ctx, bson.M{"_id": invoice.UserID}).Decode(&user); err == nil {
+		invoice.CustomerName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		invoice.Email = user.Email
+		invoice.Phone = user.Phone
+		invoice.Address = user.Address
+	}
+	if name != "" {
+		invoice.CustomerName = name
+	}
+	if phone != "" {
+		invoice.Phone = phone
+	}
+	if address != nil {
+		invoice.Address = address.String()
+	}
+}
+
+func GetInvoices(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	filter := bson.M{}
+	if claims.Role == Middleware.Customer {
+		filter["user_id"] = claims.ID
+	}
+
+	var invoices []Models.Invoice
+	opts := options.Find().SetSort(bson.D{{Key: "issued_at", Value: -1}})
+	cursor, err := getInvoiceCollection().Find(context.Background(), filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoices"})
+		return
+	}
+	if err := cursor.All(context.Background(), &invoices); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode invoices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+// GetOrderInvoice returns the invoice of a completed order, issuing it on
+// first request.
+func GetOrderInvoice(c *gin.Context) {
+	getTargetInvoice(c, PaymentTargetOrder)
+}
+
+// GetBookingInvoice returns the invoice of a completed booking, issuing it
+// on first request.
+func GetBookingInvoice(c *gin.Context) {
+	getTargetInvoice(c, PaymentTargetBooking)
+}
+
+func getTargetInvoice(c *gin.Context, targetType string) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invoice, err := issueInvoice(ctx, targetType, targetID)
+	switch {
+	case err == errInvoiceNotReady:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err == errInvoicePending:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err == mongo.ErrNoDocuments:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+		return
+	}
+
+	if claims.Role == Middleware.Customer && invoice.UserID != claims.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to view this invoice"})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, invoice)
+	case "html":
+		html, err := invoiceHTML(invoice)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render invoice"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+invoice.Number+".pdf")
+		c.Data(http.StatusOK, "application/pdf", invoicePDF(invoice))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+	}
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": Models.Money.String,
+	"date":  func(t time.Time) string { return t.In(bookingLocation).Format("02/01/2006") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Invoice {{.Number}}</title></head>
+<body style="font-family: Arial, sans-serif;">
+<h2>{{.Company.Name}}</h2>
+<p>{{if .Company.TaxCode}}Tax code: {{.Company.TaxCode}}<br>{{end}}{{.Company.Address}}<br>
+{{.Company.Phone}}{{if .Company.Email}} - {{.Company.Email}}{{end}}</p>
+<h3>Invoice {{.Number}}</h3>
+<p>Date: {{date .IssuedAt}}<br>
+Customer: {{.CustomerName}}{{if .Phone}} ({{.Phone}}){{end}}<br>
+{{if .Address}}Address: {{.Address}}<br>{{end}}{{if .Email}}Email: {{.Email}}{{end}}</p>
+<table cellpadding="6" style="border-collapse: collapse; width: 100%;">
+<tr style="border-bottom: 1px solid #999;"><th align="left">Description</th><th>Qty</th><th align="right">Unit price</th><th align="right">Discount</th><th align="right">VAT</th><th align="right">Amount</th></tr>
+{{range .Lines}}<tr style="border-bottom: 1px solid #ddd;">
+<td>{{.Description}}</td><td align="center">{{.Quantity}}</td><td align="right">{{money .UnitPrice}}</td>
+<td align="right">{{money .Discount}}</td><td align="right">{{.TaxRate}}% ({{money .TaxAmount}})</td><td align="right">{{money .Amount}}</td>
+</tr>
+{{end}}</table>
+<p style="text-align: right;">Subtotal: {{money .Subtotal}}<br>
+Discount: -{{money .Discount}}<br>
+{{if not .Shipping.IsZero}}Shipping: {{money .Shipping}}<br>{{end}}
+VAT{{if .TaxInclusive}} (included){{end}}: {{money .TaxTotal}}<br>
+<b>Total: {{money .Total}}</b></p>
+</body>
+</html>
+`))
+
+func invoiceHTML(invoice Models.Invoice) (string, error) {
+	var buf bytes.Buffer
+	if err := invoiceTemplate.Execute(&buf, invoice); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func invoicePDF(invoice Models.Invoice) []byte {
+	var doc pdfDocument
+	doc.Heading(invoice.Company.Name)
+	if invoice.Company.TaxCode != "" {
+		doc.Text("Tax code: " + invoice.Company.TaxCode)
+	}
+	doc.Text(invoice.Company.Address)
+	doc.Text(strings.Trim(invoice.Company.Phone+" - "+invoice.Company.Email, " -"))
+	doc.Blank()
+
+	doc.Heading("Invoice " + invoice.Number)
+	doc.Text("Date: " + invoice.IssuedAt.In(bookingLocation).Format("02/01/2006"))
+	doc.Text("Customer: " + invoice.CustomerName + " " + invoice.Phone)
+	if invoice.Address != "" {
+		doc.Text("Address: " + invoice.Address)
+	}
+	doc.Blank()
+
+	for _, line := range invoice.Lines {
+		doc.Bold(line.Description)
+		doc.Mono(fmt.Sprintf("%4d x %15s  discount %15s  VAT %g%% %12s  = %15s",
+			line.Quantity, line.UnitPrice.String(), line.Discount.String(), line.TaxRate, line.TaxAmount.String(), line.Amount.String()))
+	}
+	doc.Blank()
+
+	doc.Mono(fmt.Sprintf("%-20s %20s", "Subtotal", invoice.Subtotal.String()))
+	doc.Mono(fmt.Sprintf("%-20s %20s", "Discount", "-"+invoice.Discount.String()))
+	if invoice.Shipping.Sign() > 0 {
+		doc.Mono(fmt.Sprintf("%-20s %20s", "Shipping", invoice.Shipping.String()))
+	}
+	vat := "VAT"
+	if invoice.TaxInclusive {
+		vat = "VAT (included)"
+	}
+	doc.Mono(fmt.Sprintf("%-20s %20s", vat, invoice.TaxTotal.String()))
+	doc.Bold(fmt.Sprintf("Total: %s", invoice.Total.String()))
+	return doc.Bytes()
+}
@@ -0,0 +1,80 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// invoiceLookups queues the reads issueInvoice makes before it claims the
+// invoice of a completed order.
+func invoiceLookups(mt *mtest.T, orderID primitive.ObjectID) {
+	mt.AddMockResponses(
+		mtest.CreateCursorResponse(0, "test.invoices", mtest.FirstBatch),
+		mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+		mtest.CreateCursorResponse(0, "test.orders", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: orderID},
+			{Key: "user_id", Value: primitive.NewObjectID()},
+			{Key: "status", Value: OrderCompleted},
+			{Key: "total_price", Value: int64(150000)},
+		}),
+		mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+	)
+}
+
+func TestIssueInvoiceReleasesClaimWhenNumberingFails(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("numbering fails", func(mt *mtest.T) {
+		Database = mt.DB
+		orderID := primitive.NewObjectID()
+		invoiceLookups(mt, orderID)
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		if _, err := issueInvoice(context.Background(), PaymentTargetOrder, orderID); err == nil {
+			mt.Fatal("issueInvoice should report the failed numbering")
+		}
+		var deleted, stamped bool
+		for _, started := range mt.GetAllStartedEvents() {
+			switch started.CommandName {
+			case "delete":
+				deleted = started.Command.Lookup("delete").StringValue() == "invoices"
+			case "update":
+				stamped = true
+			}
+		}
+		if !deleted {
+			mt.Fatal("the unnumbered invoice must be removed so a retry can issue it")
+		}
+		if stamped {
+			mt.Fatal("the order must not be stamped before the invoice is numbered")
+		}
+	})
+}
+
+func TestIssueInvoiceLosingTheClaimTakesNoNumber(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("duplicate", func(mt *mtest.T) {
+		Database = mt.DB
+		orderID := primitive.NewObjectID()
+		invoiceLookups(mt, orderID)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}))
+
+		if _, err := issueInvoice(context.Background(), PaymentTargetOrder, orderID); err != errInvoicePending {
+			mt.Fatalf("issueInvoice error = %v, want errInvoicePending", err)
+		}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "findAndModify" {
+				mt.Fatal("a request that lost the claim must not take an invoice number")
+			}
+		}
+	})
+}
@@ -0,0 +1,458 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PointsEarn    = "earn"
+	PointsRedeem  = "redeem"
+	PointsExpire  = "expire"
+	PointsReverse = "reverse"
+	PointsRestore = "restore"
+)
+
+func getPointsCollection() *mongo.Collection {
+	return Database.Collection("points_ledger")
+}
+
+// GetMyPoints returns the customer's balance and points history, newest first.
+func GetMyPoints(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := expirePoints(ctx, claims.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update points"})
+		return
+	}
+
+	balance, err := pointsBalance(ctx, claims.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get points balance"})
+		return
+	}
+
+	var entries []Models.PointsEntry
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := getPointsCollection().Find(ctx, bson.M{"user_id": claims.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get points history"})
+		return
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode points history"})
+		return
+	}
+
+	settings, _ := loadLoyaltySettings(ctx)
+	c.JSON(http.StatusOK, gin.H{
+		"balance":     balance,
+		"point_value": settings.PointValue,
+		"entries":     entries,
+	})
+}
+
+func pointsBalance(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "balance": bson.M{"$sum": "$points"}}}},
+	}
+	cursor, err := getPointsCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	var result []struct {
+		Balance int `bson:"balance"`
+	}
+	if err := cursor.All(ctx, &result); err != nil || len(result) == 0 {
+		return 0, err
+	}
+	return result[0].Balance, nil
+}
+
+// expirePoints writes off credits that passed their expiry date without
+// being spent.
+func expirePoints(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	var credits []Models.PointsEntry
+	cursor, err := getPointsCollection().Find(ctx, bson.M{
+		"user_id":    userID,
+		"remaining":  bson.M{"$gt": 0},
+		"expires_at": bson.M{"$lt": now, "$gt": time.Time{}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &credits); err != nil {
+		return err
+	}
+
+	for _, credit := range credits {
+		result, err := getPointsCollection().UpdateOne(ctx,
+			bson.M{"_id": credit.ID, "remaining": credit.Remaining},
+			bson.M{"$set": bson.M{"remaining": 0}})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		entry := Models.PointsEntry{
+			ID:          primitive.NewObjectID(),
+			UserID:      userID,
+			Type:        PointsExpire,
+			Points:      -credit.Remaining,
+			Description: "Points expired",
+			CreatedAt:   now,
+		}
+		if _, err := getPointsCollection().InsertOne(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumePoints takes points out of the customer's unspent credits, the
+// preferred credit first and then those closest to expiring. It returns the
+// credits it could take points from; with all set, a shortfall puts
+// everything back and fails instead.
+func consumePoints(ctx context.Context, userID primitive.ObjectID, points int, prefer primitive.ObjectID, all bool) ([]Models.PointsSource, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := getPointsCollection().Find(ctx, bson.M{"user_id": userID, "remaining": bson.M{"$gt": 0}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var credits []Models.PointsEntry
+	if err := cursor.All(ctx, &credits); err != nil {
+		return nil, err
+	}
+	for i, credit := range credits {
+		if credit.ID == prefer {
+			credits[0], credits[i] = credits[i], credits[0]
+			break
+		}
+	}
+
+	consumed := 0
+	var taken []Models.PointsSource
+	for _, credit := range credits {
+		if consumed == points {
+			break
+		}
+		take := credit.Remaining
+		if take > points-consumed {
+			take = points - consumed
+		}
+		result, err := getPointsCollection().UpdateOne(ctx,
+			bson.M{"_id": credit.ID, "remaining": bson.M{"$gte": take}},
+			bson.M{"$inc": bson.M{"remaining": -take}})
+		if err != nil {
+			if all {
+				if giveErr := giveBackPoints(ctx, taken); giveErr != nil {
+					log.Println("Error giving back points:", giveErr)
+				}
+				return nil, err
+			}
+			return taken, err
+		}
+		if result.ModifiedCount > 0 {
+			consumed += take
+			taken = append(taken, Models.PointsSource{CreditID: credit.ID, Points: take, ExpiresAt: credit.ExpiresAt})
+		}
+	}
+
+	if all && consumed < points {
+		if err := giveBackPoints(ctx, taken); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Not enough points")
+	}
+	return taken, nil
+}
+
+// giveBackPoints undoes consumePoints on the credits the points came from.
+func giveBackPoints(ctx context.Context, sources []Models.PointsSource) error {
+	for _, source := range sources {
+		if _, err := getPointsCollection().UpdateOne(ctx, bson.M{"_id": source.CreditID}, bson.M{"$inc": bson.M{"remaining": source.Points}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pointsDiscount turns the points a customer wants to spend into discount
+// lines, limited by their balance and the share of the total that may be
+// paid with points.
+func pointsDiscount(ctx context.Context, userID primitive.ObjectID, points int, cart discountCart) ([]Models.DiscountLine, error) {
+	if points <= 0 {
+		return nil, nil
+	}
+
+	settings, err := loadLoyaltySettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := expirePoints(ctx, userID); err != nil {
+		return nil, err
+	}
+	balance, err := pointsBalance(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if points > balance {
+		return nil, fmt.Errorf("You only have %d points", balance)
+	}
+
+	discount := Models.NewMoney(settings.PointValue * float64(points))
+	limit := cart.subtotal().Percent(settings.MaxRedeemPercent)
+	if discount.Cmp(limit) > 0 {
+		return nil, fmt.Errorf("Points can cover at most %s of this purchase", limit)
+	}
+	return allocateDiscount("points", "", fmt.Sprintf("%d loyalty points", points), discount, cart.Items), nil
+}
+
+// redeemPoints spends points on an order or booking.
+func redeemPoints(ctx context.Context, userID primitive.ObjectID, points int, targetType string, targetID primitive.ObjectID) error {
+	sources, err := consumePoints(ctx, userID, points, primitive.NilObjectID, true)
+	if err != nil {
+		return err
+	}
+
+	entry := Models.PointsEntry{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Type:        PointsRedeem,
+		Points:      -points,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Description: "Redeemed at checkout",
+		CreatedAt:   time.Now(),
+		Sources:     sources,
+	}
+	if _, err := getPointsCollection().InsertOne(ctx, entry); err != nil {
+		if giveErr := giveBackPoints(context.Background(), sources); giveErr != nil {
+			log.Println("Error giving back points:", giveErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// restorePoints returns the points of a redemption as new credits that
+// expire when the credits they were taken from would have. Redemptions
+// recorded before their sources were kept get the usual expiry.
+func restorePoints(ctx context.Context, redeem Models.PointsEntry) error {
+	sources := redeem.Sources
+	if len(sources) == 0 {
+		settings, err := loadLoyaltySettings(ctx)
+		if err != nil {
+			return err
+		}
+		source := Models.PointsSource{Points: -redeem.Points}
+		if settings.ExpiryDays > 0 {
+			source.ExpiresAt = time.Now().AddDate(0, 0, settings.ExpiryDays)
+		}
+		sources = []Models.PointsSource{source}
+	}
+
+	var entries []interface{}
+	for _, source := range sources {
+		entries = append(entries, Models.PointsEntry{
+			ID:          primitive.NewObjectID(),
+			UserID:      redeem.UserID,
+			Type:        PointsRestore,
+			Points:      source.Points,
+			Remaining:   source.Points,
+			TargetType:  redeem.TargetType,
+			TargetID:    redeem.TargetID,
+			Description: "Redeemed points returned",
+			ExpiresAt:   source.ExpiresAt,
+			CreatedAt:   time.Now(),
+		})
+	}
+	_, err := getPointsCollection().InsertMany(ctx, entries)
+	return err
+}
+
+// awardPoints credits the customer for a completed order or booking. It is
+// safe to call more than once for the same target.
+func awardPoints(ctx context.Context, targetType string, targetID primitive.ObjectID) error {
+	if count, err := getPointsCollection().CountDocuments(ctx, bson.M{"type": PointsEarn, "target_type": targetType, "target_id": targetID}); err != nil || count > 0 {
+		return err
+	}
+
+	settings, err := loadLoyaltySettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	var userID primitive.ObjectID
+	earned := 0.0
+	switch targetType {
+	case PaymentTargetOrder:
+		var order Models.Order
+		if err := getOrderCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&order); err != nil {
+			return err
+		}
+		userID = order.UserID
+		for _, item := range order.Items {
+			var product Models.Product
+			if err := getProductCollection().FindOne(ctx, bson.M{"_id": item.ProductID}).Decode(&product); err != nil {
+				product.ProductCategory = primitive.NilObjectID
+			}
+			amount := item.Price.Mul(item.Quantity)
+			for _, line := range order.Discounts {
+				if line.ItemID == item.ProductID {
+					amount = amount.Sub(line.Amount)
+				}
+			}
+			earned += amount.NonNegative().Float() / 1000 * earnRate(settings, product.ProductCategory)
+		}
+
+	case PaymentTargetBooking:
+		var booking Models.OrderBookingService
+		if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&booking); err != nil {
+			return err
+		}
+		var service Models.Service
+		if err := getServiceCollection().FindOne(ctx, bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil {
+			service.ServiceCategory = primitive.NilObjectID
+		}
+		userID = booking.UserID
+		earned = booking.TotalPrice.Float() / 1000 * earnRate(settings, service.ServiceCategory)
+	}
+
+	points := int(math.Floor(earned))
+	if points <= 0 {
+		return nil
+	}
+
+	entry := Models.PointsEntry{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Type:        PointsEarn,
+		Points:      points,
+		Remaining:   points,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Description: "Earned on " + targetType,
+		CreatedAt:   time.Now(),
+	}
+	if settings.ExpiryDays > 0 {
+		entry.ExpiresAt = entry.CreatedAt.AddDate(0, 0, settings.ExpiryDays)
+	}
+	_, err = getPointsCollection().InsertOne(ctx, entry)
+	return err
+}
+
+// earnRate is the points earned per 1,000 đồng spent in a category.
+func earnRate(settings Models.LoyaltySettings, category primitive.ObjectID) float64 {
+	for _, rate := range settings.CategoryRates {
+		if rate.Category == category {
+			return rate.EarnRate
+		}
+	}
+	return settings.EarnRate
+}
+
+// reverseLoyalty undoes the points side of an order or booking that was
+// cancelled or refunded: points it earned are taken back and points spent
+// on it are returned to the customer. Each entry is claimed before it is
+// undone, so a cancellation and a refund racing each other only undo it once.
+func reverseLoyalty(ctx context.Context, targetType string, targetID primitive.ObjectID) error {
+	var entries []Models.PointsEntry
+	cursor, err := getPointsCollection().Find(ctx, bson.M{"target_type": targetType, "target_id": targetID})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+
+	// Entries undone before claims existed only left a reverse or restore
+	// entry behind.
+	done := map[string]bool{}
+	for _, entry := range entries {
+		done[entry.Type] = true
+	}
+
+	for _, entry := range entries {
+		if (entry.Type != PointsEarn || done[PointsReverse]) && (entry.Type != PointsRedeem || done[PointsRestore]) {
+			continue
+		}
+		claimed, err := claimPointsEntry(ctx, entry.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if entry.Type == PointsEarn {
+			err = reverseEarnedPoints(ctx, entry)
+		} else {
+			err = restorePoints(ctx, entry)
+		}
+		if err != nil {
+			if _, unclaimErr := getPointsCollection().UpdateOne(context.Background(), bson.M{"_id": entry.ID}, bson.M{"$unset": bson.M{"reversed_at": ""}}); unclaimErr != nil {
+				log.Println("Error releasing points claim:", unclaimErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func claimPointsEntry(ctx context.Context, entryID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": entryID, "reversed_at": bson.M{"$exists": false}}
+	result, err := getPointsCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"reversed_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// reverseEarnedPoints takes back the points an order or booking earned.
+// Points already spent elsewhere are still owed, so the balance may go
+// negative until the customer earns more.
+func reverseEarnedPoints(ctx context.Context, earn Models.PointsEntry) error {
+	taken, err := consumePoints(ctx, earn.UserID, earn.Points, earn.ID, false)
+	if err == nil {
+		reversal := Models.PointsEntry{
+			ID:          primitive.NewObjectID(),
+			UserID:      earn.UserID,
+			Type:        PointsReverse,
+			Points:      -earn.Points,
+			TargetType:  earn.TargetType,
+			TargetID:    earn.TargetID,
+			Description: "Earned points reversed",
+			CreatedAt:   time.Now(),
+		}
+		_, err = getPointsCollection().InsertOne(ctx, reversal)
+	}
+	if err != nil {
+		if giveErr := giveBackPoints(context.Background(), taken); giveErr != nil {
+			log.Println("Error giving back points:", giveErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRedeemPointsGivesPointsBackWhenTheEntryFails(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("insert fails", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, creditID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.points_ledger", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: creditID},
+				{Key: "user_id", Value: userID},
+				{Key: "type", Value: PointsEarn},
+				{Key: "points", Value: 100},
+				{Key: "remaining", Value: 100},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		if err := redeemPoints(context.Background(), userID, 40, PaymentTargetOrder, primitive.NewObjectID()); err == nil {
+			mt.Fatal("redeemPoints should report the failed insert")
+		}
+		updates := updatesSent(mt)
+		if len(updates) != 2 {
+			mt.Fatalf("got %d updates, want the consume and the give-back", len(updates))
+		}
+		giveBack := updates[1].Lookup("updates", "0")
+		if giveBack.Document().Lookup("q", "_id").ObjectID() != creditID || giveBack.Document().Lookup("u", "$inc", "remaining").Int32() != 40 {
+			mt.Fatalf("give-back = %s, want 40 points back on the credit", giveBack)
+		}
+	})
+}
+
+func TestRestorePointsKeepsOriginalExpiry(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("restore", func(mt *mtest.T) {
+		Database = mt.DB
+		expiry := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+		redeem := Models.PointsEntry{
+			ID:      primitive.NewObjectID(),
+			UserID:  primitive.NewObjectID(),
+			Type:    PointsRedeem,
+			Points:  -30,
+			Sources: []Models.PointsSource{{CreditID: primitive.NewObjectID(), Points: 30, ExpiresAt: expiry}},
+		}
+		if err := restorePoints(context.Background(), redeem); err != nil {
+			mt.Fatalf("restorePoints: %v", err)
+		}
+
+		restored := mt.GetStartedEvent().Command.Lookup("documents", "0").Document()
+		if got := restored.Lookup("expires_at").Time().UTC(); !got.Equal(expiry) {
+			mt.Fatalf("restored points expire %s, want the original %s", got, expiry)
+		}
+		if restored.Lookup("remaining").AsInt64() != 30 {
+			mt.Fatalf("restored credit = %s, want 30 spendable points", restored)
+		}
+	})
+}
+
+func TestReverseLoyaltySkipsClaimedEntries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("claimed", func(mt *mtest.T) {
+		Database = mt.DB
+		orderID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.points_ledger", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "type", Value: PointsRedeem},
+				{Key: "points", Value: -50},
+				{Key: "target_type", Value: PaymentTargetOrder},
+				{Key: "target_id", Value: orderID},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+
+		if err := reverseLoyalty(context.Background(), PaymentTargetOrder, orderID); err != nil {
+			mt.Fatalf("reverseLoyalty: %v", err)
+		}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "insert" {
+				mt.Fatal("points claimed by a concurrent reversal must not be restored again")
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package Controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+var errMailerNotConfigured = errors.New("SMTP is not configured")
+
+type mailAttachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+func sendMail(to, subject, htmlBody string, attachments ...mailAttachment) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %v", to, err)
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return errors.New("mail subject must not contain line breaks")
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return errMailerNotConfigured
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	fmt.Fprintf(&body, "From: %s\r\n", from)
+	fmt.Fprintf(&body, "To: %s\r\n", recipient.String())
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&body, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	htmlPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	writeBase64Lines(htmlPart, []byte(htmlBody))
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.FileName)},
+		})
+		if err != nil {
+			return err
+		}
+		writeBase64Lines(part, attachment.Data)
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return smtp.SendMail(host+":"+port, auth, from, []string{recipient.Address}, body.Bytes())
+}
+
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	w.Write([]byte(encoded + "\r\n"))
+}
@@ -0,0 +1,27 @@
+package Controllers
+
+import "testing"
+
+func TestSendMailRejectsHeaderInjection(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+
+	cases := []struct {
+		name, to, subject string
+	}{
+		{"recipient with extra header", "a@example.com\r\nBcc: b@example.com", "Invoice"},
+		{"recipient list", "a@example.com, b@example.com", "Invoice"},
+		{"subject with line break", "a@example.com", "Invoice\r\nBcc: b@example.com"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sendMail(tc.to, tc.subject, "<p>hi</p>")
+			if err == nil || err == errMailerNotConfigured {
+				t.Fatalf("sendMail(%q, %q) = %v, want a validation error", tc.to, tc.subject, err)
+			}
+		})
+	}
+
+	if err := sendMail("Khách <a@example.com>", "Invoice", "<p>hi</p>"); err != errMailerNotConfigured {
+		t.Fatalf("valid mail got %v, want errMailerNotConfigured", err)
+	}
+}
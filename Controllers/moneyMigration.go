@@ -0,0 +1,151 @@
+package Controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	moneyMigrationKey         = "migration:money"
+	fixedDiscountMigrationKey = "migration:fixed_discount_amount"
+)
+
+// moneyFields lists, per collection, every amount that used to be stored as a
+// double. Entries of the form "array.field" are fields of the elements of an
+// array.
+var moneyFields = map[string][]string{
+	"products":               {"price"},
+	"services":               {"price", "pricing.hourly_rate", "pricing.area_tiers.price", "pricing.add_ons.price"},
+	"service_areas":          {"surcharge"},
+	"carts":                  {"items.price"},
+	"selected_items":         {"items.price"},
+	"product_order":          {"total_price", "subtotal", "discount_total", "tax_total", "shipping.fee", "items.price", "items.tax_amount", "discounts.amount", "promotions.discount"},
+	"order_booking_service":  {"total_price", "tax_amount", "cancellation_fee", "price_lines.amount", "discounts.amount", "promotions.discount"},
+	"coupons":                {"max_discount", "min_spend"},
+	"coupon_redemptions":     {"discount"},
+	"promotions":             {"max_discount", "min_spend"},
+	"payments":               {"amount", "refunded_amount"},
+	"payment_ledger":         {"amount"},
+	"settlements":            {"amount"},
+	"reconciliation_reports": {"ledger_total", "settled_total", "mismatches.ledger_amount", "mismatches.settlement_amount"},
+	"shipping_methods":       {"flat_fee", "free_above", "weight_rates.fee"},
+	"invoices":               {"subtotal", "discount", "shipping", "tax_total", "total", "lines.unit_price", "lines.discount", "lines.tax_amount", "lines.amount"},
+}
+
+// moneyArrays names the array part of the element fields above, since
+// "pricing.area_tiers.price" alone does not say where the array starts.
+var moneyArrays = []string{"pricing.area_tiers", "pricing.add_ons", "items", "discounts", "promotions", "price_lines", "mismatches", "weight_rates", "lines"}
+
+// MigrateMoney rewrites amounts stored as doubles into whole numbers of the
+// minor unit, rounding halves away from zero the same way Models.NewMoney
+// does, and moves the amount of fixed coupons and promotions out of value.
+func MigrateMoney(ctx context.Context) error {
+	if err := runMigration(ctx, moneyMigrationKey, migrateMoneyFields); err != nil {
+		return err
+	}
+	return runMigration(ctx, fixedDiscountMigrationKey, migrateFixedDiscounts)
+}
+
+// runMigration runs a migration unless the marker it records in the settings
+// collection says it already has. Migrations only touch documents still in
+// the old shape, so instances that start together may both run one; the
+// second marker is then a duplicate and the migration counts as done.
+func runMigration(ctx context.Context, key string, migrate func(context.Context) error) error {
+	err := getSettingsCollection().FindOne(ctx, bson.M{"_id": key}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if err := migrate(ctx); err != nil {
+		return err
+	}
+
+	_, err = getSettingsCollection().InsertOne(ctx, bson.M{"_id": key, "applied_at": time.Now()})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func migrateMoneyFields(ctx context.Context) error {
+	for collection, fields := range moneyFields {
+		for _, field := range fields {
+			filter, update := moneyFieldUpdate(field)
+			result, err := Database.Collection(collection).UpdateMany(ctx, filter, update)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", collection, field, err)
+			}
+			if result.ModifiedCount > 0 {
+				log.Printf("Money migration: converted %s.%s in %d documents", collection, field, result.ModifiedCount)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateFixedDiscounts moves the amount of fixed coupons and promotions,
+// which used to share the float value field with percentages, to amount.
+func migrateFixedDiscounts(ctx context.Context) error {
+	fixed := map[string]string{"coupons": CouponFixed, "promotions": PromotionFixed}
+	for collection, discountType := range fixed {
+		filter := bson.M{"type": discountType, "amount": bson.M{"$exists": false}}
+		update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"amount": roundedMoney("$value"), "value": 0}}}}
+		result, err := Database.Collection(collection).UpdateMany(ctx, filter, update)
+		if err != nil {
+			return fmt.Errorf("%s.amount: %v", collection, err)
+		}
+		if result.ModifiedCount > 0 {
+			log.Printf("Money migration: moved the fixed amount of %d %s", result.ModifiedCount, collection)
+		}
+	}
+	return nil
+}
+
+// moneyFieldUpdate builds the filter and pipeline update that convert one
+// field. Only documents that still hold a double are touched, so a field
+// whose parent is missing or null is never created.
+func moneyFieldUpdate(field string) (bson.M, mongo.Pipeline) {
+	filter := bson.M{field: bson.M{"$type": "double"}}
+	for _, array := range moneyArrays {
+		element, ok := strings.CutPrefix(field, array+".")
+		if !ok {
+			continue
+		}
+		converted := bson.M{"$map": bson.M{
+			"input": "$" + array,
+			"as":    "element",
+			"in": bson.M{"$mergeObjects": bson.A{
+				"$$element",
+				bson.M{element: roundedMoney("$$element." + element)},
+			}},
+		}}
+		return filter, mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			array: bson.M{"$cond": bson.A{bson.M{"$isArray": "$" + array}, converted, "$" + array}},
+		}}}}
+	}
+	return filter, mongo.Pipeline{{{Key: "$set", Value: bson.M{field: roundedMoney("$" + field)}}}}
+}
+
+// roundedMoney converts a double to a long, rounding halves away from zero,
+// and leaves any other value as it is.
+func roundedMoney(value string) bson.M {
+	rounded := bson.M{"$cond": bson.A{
+		bson.M{"$gte": bson.A{value, 0}},
+		bson.M{"$floor": bson.M{"$add": bson.A{value, 0.5}}},
+		bson.M{"$ceil": bson.M{"$subtract": bson.A{value, 0.5}}},
+	}}
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": value}, "double"}},
+		bson.M{"$toLong": rounded},
+		value,
+	}}
+}
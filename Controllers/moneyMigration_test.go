@@ -0,0 +1,46 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRunMigrationTreatsAConcurrentMarkerAsDone(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("duplicate marker", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}),
+		)
+
+		ran := false
+		err := runMigration(context.Background(), moneyMigrationKey, func(context.Context) error {
+			ran = true
+			return nil
+		})
+		if err != nil {
+			mt.Fatalf("runMigration: %v, want another instance's marker to count as done", err)
+		}
+		if !ran {
+			mt.Fatal("the migration should run when no marker exists")
+		}
+	})
+
+	mt.Run("already applied", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch, bson.D{{Key: "_id", Value: moneyMigrationKey}}))
+
+		err := runMigration(context.Background(), moneyMigrationKey, func(context.Context) error {
+			mt.Fatal("a migration with a marker must not run again")
+			return nil
+		})
+		if err != nil {
+			mt.Fatalf("runMigration: %v", err)
+		}
+	})
+}
@@ -2,6 +2,8 @@ package Controllers
 
 import (
 	"context"
+	"io"
+	"log"
 	"net/http"
 	"time"
 
@@ -26,6 +28,42 @@ func CreateOrder(c *gin.Context) {
 	claims := c.MustGet("user").(*Middleware.UserClaims)
 	userID := claims.ID
 
+	var request struct {
+		AddressID        primitive.ObjectID `json:"address_id"`
+		Recipient        string             `json:"recipient"`
+		Phone            string             `json:"phone"`
+		Address          *Models.Address    `json:"address"`
+		ShippingMethodID primitive.ObjectID `json:"shipping_method_id"`
+		CouponCode       string             `json:"coupon_code"`
+		Points           int                `json:"points"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		c.JSON(400, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// Orders snapshot the delivery address so later edits to the address
+	// book do not change where past orders were sent.
+	if request.AddressID != primitive.NilObjectID {
+		saved, err := loadSavedAddress(context.Background(), userID, request.AddressID)
+		if err != nil {
+			c.JSON(404, gin.H{"error": "Address not found"})
+			return
+		}
+		request.Recipient, request.Phone, request.Address = saved.Recipient, saved.Phone, &saved.Location
+	} else if request.Address != nil {
+		inline := Models.SavedAddress{Recipient: request.Recipient, Phone: request.Phone, Location: *request.Address}
+		if err := validateSavedAddress(inline); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		if saved, err := loadDefaultAddress(context.Background(), userID); err == nil {
+			request.AddressID = saved.ID
+			request.Recipient, request.Phone, request.Address = saved.Recipient, saved.Phone, &saved.Location
+		}
+	}
+
 	selectedItemsCollection := getSelectedItemsCollection()
 	var selectedItems Models.SelectedItems
 	err := selectedItemsCollection.FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&selectedItems)
@@ -36,7 +74,9 @@ func CreateOrder(c *gin.Context) {
 
 	productCollection := getProductCollection()
 	var orderItems []Models.OrderItem
-	totalPrice := 0.0
+	discountItems := discountCart{TargetType: PaymentTargetOrder}
+	var totalPrice Models.Money
+	weight := 0
 
 	for _, selectedItem := range selectedItems.Items {
 		var product Models.Product
@@ -52,24 +92,126 @@ func CreateOrder(c *gin.Context) {
 			Price:     product.Price,
 			Name:      product.Name,
 			ImageURL:  product.ImageURL,
+			TaxClass:  product.TaxClass,
 		}
 
 		orderItems = append(orderItems, orderItem)
-		totalPrice += product.Price * float64(selectedItem.Quantity)
+		discountItems.Items = append(discountItems.Items, productDiscountItem(product, selectedItem.Quantity))
+		totalPrice = totalPrice.Add(product.Price.Mul(selectedItem.Quantity))
+		weight += product.Weight * selectedItem.Quantity
+	}
+
+	var shipping *Models.OrderShipping
+	if request.ShippingMethodID != primitive.NilObjectID {
+		if request.Address == nil {
+			c.JSON(400, gin.H{"error": "Delivery address is required"})
+			return
+		}
+
+		var method Models.ShippingMethod
+		if err := getShippingMethodCollection().FindOne(context.Background(), bson.M{"_id": request.ShippingMethodID, "active": true}).Decode(&method); err != nil {
+			c.JSON(404, gin.H{"error": "Shipping method not found"})
+			return
+		}
+
+		fee, err := shippingFee(context.Background(), method, Shipment{Address: *request.Address, Weight: weight, Value: totalPrice})
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		shipping = &Models.OrderShipping{
+			MethodID: method.ID,
+			Method:   method.Name,
+			Carrier:  method.Carrier,
+			Fee:      fee,
+			Weight:   weight,
+		}
+		discountItems.ShippingFee = fee
+	}
+
+	discountItems, discounts, promotions, err := evaluatePromotions(context.Background(), userID, discountItems)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to evaluate promotions"})
+		return
+	}
+
+	var coupon Models.Coupon
+	if request.CouponCode != "" {
+		var couponLines []Models.DiscountLine
+		coupon, couponLines, err = evaluateCoupon(context.Background(), request.CouponCode, userID, discountItems)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		discounts = append(discounts, couponLines...)
+	}
+
+	pointLines, err := pointsDiscount(context.Background(), userID, request.Points, applyDiscountLines(discountItems, discounts))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
+	discounts = append(discounts, pointLines...)
 
 	order := Models.Order{
-		UserID:     userID,
-		Items:      orderItems,
-		TotalPrice: totalPrice,
-		Status:     "pending",
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		Items:         orderItems,
+		Subtotal:      totalPrice,
+		TotalPrice:    totalPrice,
+		AddressID:     request.AddressID,
+		Recipient:     request.Recipient,
+		Phone:         request.Phone,
+		Address:       request.Address,
+		Shipping:      shipping,
+		CouponCode:    coupon.Code,
+		Discounts:     discounts,
+		DiscountTotal: discountTotal(discounts),
+		Promotions:    promotions,
+		Currency:      Models.DefaultCurrency,
+		Status:        OrderPending,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if shipping != nil {
+		order.TotalPrice = order.TotalPrice.Add(shipping.Fee)
+	}
+	order.TotalPrice = order.TotalPrice.Sub(order.DiscountTotal).NonNegative()
+
+	taxSettings, err := loadTaxSettings(context.Background())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to calculate tax"})
+		return
+	}
+	applyOrderTax(taxSettings, &order)
+
+	if len(pointLines) > 0 {
+		if err := redeemPoints(context.Background(), userID, request.Points, PaymentTargetOrder, order.ID); err != nil {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+		order.PointsUsed = request.Points
+	}
+
+	if order.CouponCode != "" {
+		if err := redeemCoupon(context.Background(), coupon, userID, PaymentTargetOrder, order.ID, couponDiscount(discounts)); err != nil {
+			if reverseErr := reverseLoyalty(context.Background(), PaymentTargetOrder, order.ID); reverseErr != nil {
+				log.Println("Error returning redeemed points:", reverseErr)
+			}
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
 	orderCollection := getOrderCollection()
 	_, err = orderCollection.InsertOne(context.Background(), order)
 	if err != nil {
+		if releaseErr := releaseCoupon(context.Background(), PaymentTargetOrder, order.ID); releaseErr != nil {
+			log.Println("Error releasing coupon:", releaseErr)
+		}
+		if reverseErr := reverseLoyalty(context.Background(), PaymentTargetOrder, order.ID); reverseErr != nil {
+			log.Println("Error returning redeemed points:", reverseErr)
+		}
 		c.JSON(500, gin.H{"error": "Failed to create order"})
 		return
 	}
@@ -169,7 +311,9 @@ func GetAllOrders(c *gin.Context) {
 func UpdateOrderStatus(c *gin.Context) {
 	orderID := c.Param("id")
 	var requestBody struct {
-		Status string `json:"status"`
+		Status         string `json:"status"`
+		Carrier        string `json:"carrier"`
+		TrackingNumber string `json:"tracking_number"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
@@ -191,18 +335,47 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	if order.Status == "completed" || order.Status == "cancelled" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot update a completed or cancelled order"})
+	if !canTransitionOrder(order.Status, requestBody.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot change order status from " + order.Status + " to " + requestBody.Status})
 		return
 	}
 
-	update := bson.M{"$set": bson.M{"status": requestBody.Status, "updatedAt": time.Now()}}
-	_, err = orderCollection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
-	if err != nil {
+	set := bson.M{}
+	if requestBody.Status == OrderShipped {
+		shipping, err := shipOrder(context.Background(), order, requestBody.Carrier, requestBody.TrackingNumber)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set["shipping"] = shipping
+	}
+
+	if err := transitionOrder(context.Background(), objectID, order.Status, requestBody.Status, set); err != nil {
+		if err == errOrderStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
 		return
 	}
 
+	if requestBody.Status == OrderCompleted {
+		if err := captureCODPayment(context.Background(), PaymentTargetOrder, objectID); err != nil {
+			log.Println("Error capturing cash on delivery payment:", err)
+		}
+		if err := awardPoints(context.Background(), PaymentTargetOrder, objectID); err != nil {
+			log.Println("Error awarding loyalty points:", err)
+		}
+		if _, err := issueInvoice(context.Background(), PaymentTargetOrder, objectID); err != nil {
+			log.Println("Error issuing invoice:", err)
+		}
+	}
+	if requestBody.Status == OrderCancelled || requestBody.Status == OrderRefunded {
+		if err := reverseLoyalty(context.Background(), PaymentTargetOrder, objectID); err != nil {
+			log.Println("Error reversing loyalty points:", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully"})
 }
 
@@ -229,12 +402,25 @@ func CancelOrder(c *gin.Context) {
 		return
 	}
 
-	update := bson.M{"$set": bson.M{"status": "cancelled", "updatedAt": time.Now()}}
-	_, err = orderCollection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to cancel order"})
+	if claims.Role == Middleware.Customer && order.Status != OrderPending && order.Status != OrderConfirmed {
+		c.JSON(400, gin.H{"error": "Only pending or confirmed orders can be cancelled"})
 		return
 	}
+	if err := transitionOrder(context.Background(), objectID, order.Status, OrderCancelled, nil); err != nil {
+		switch {
+		case err == errOrderStatusChanged:
+			c.JSON(409, gin.H{"error": err.Error()})
+		case !canTransitionOrder(order.Status, OrderCancelled):
+			c.JSON(400, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "Failed to cancel order"})
+		}
+		return
+	}
+
+	if err := reverseLoyalty(context.Background(), PaymentTargetOrder, objectID); err != nil {
+		log.Println("Error reversing loyalty points:", err)
+	}
 
 	c.JSON(200, gin.H{"message": "Order cancelled successfully"})
 }
@@ -0,0 +1,69 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OrderPending   = "pending"
+	OrderConfirmed = "confirmed"
+	OrderShipped   = "shipped"
+	OrderCompleted = "completed"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// A completed order can still be refunded, which takes back the points it
+// earned.
+var orderTransitions = map[string][]string{
+	OrderPending:   {OrderConfirmed, OrderShipped, OrderCompleted, OrderCancelled},
+	OrderConfirmed: {OrderShipped, OrderCompleted, OrderCancelled, OrderRefunded},
+	OrderShipped:   {OrderCompleted, OrderCancelled, OrderRefunded},
+	OrderCompleted: {OrderRefunded},
+}
+
+var errOrderStatusChanged = errors.New("Order status has changed, please reload")
+
+func canTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionOrder moves an order from one status to the next and applies any
+// extra fields in the same write. The status is part of the filter so a
+// concurrent update is reported instead of silently overwritten.
+func transitionOrder(ctx context.Context, orderID primitive.ObjectID, from, to string, set bson.M) error {
+	if !canTransitionOrder(from, to) {
+		return errors.New("Cannot change order status from " + from + " to " + to)
+	}
+
+	fields := bson.M{"status": to, "updated_at": time.Now()}
+	for key, value := range set {
+		fields[key] = value
+	}
+
+	result, err := getOrderCollection().UpdateOne(ctx, bson.M{"_id": orderID, "status": from}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errOrderStatusChanged
+	}
+
+	if to == OrderCancelled {
+		if err := releaseCoupon(ctx, PaymentTargetOrder, orderID); err != nil {
+			log.Println("Error releasing coupon:", err)
+		}
+	}
+	return nil
+}
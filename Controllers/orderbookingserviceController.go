@@ -2,7 +2,10 @@ package Controllers
 
 import (
 	"context"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"Server/Middleware"
@@ -22,6 +25,94 @@ func getServiceCollection() *mongo.Collection {
 	return Database.Collection("services")
 }
 
+// priceBooking works out what the customer pays for a booking from the
+// service pricing, the address and the discounts already on the booking.
+func priceBooking(ctx context.Context, booking *Models.OrderBookingService, service Models.Service) error {
+	if err := priceBookingBase(ctx, booking, service); err != nil {
+		return err
+	}
+	return finishBookingPrice(ctx, booking, service)
+}
+
+func priceBookingBase(ctx context.Context, booking *Models.OrderBookingService, service Models.Service) error {
+	if err := quoteBooking(booking, service); err != nil {
+		return err
+	}
+	return applyCoverage(ctx, booking, service)
+}
+
+// quoteBooking prices the service itself, before the area surcharge.
+func quoteBooking(booking *Models.OrderBookingService, service Models.Service) error {
+	var date time.Time
+	if booking.BookingDate != 0 {
+		date = booking.BookingDate.Time()
+	}
+	result, err := quoteService(service, quoteRequest{
+		Quantity:    booking.Quantity,
+		Area:        booking.Area,
+		Hours:       booking.Hours,
+		AddOns:      booking.AddOns,
+		BookingDate: date,
+	})
+	if err != nil {
+		return err
+	}
+	booking.PriceLines = result.Lines
+	booking.TotalPrice = result.Total
+	return nil
+}
+
+// finishBookingPrice takes the booking's discounts off the base price and
+// adds VAT. Discounts are fixed when the booking is made, so repricing on a
+// reschedule keeps the coupon the customer already redeemed.
+func finishBookingPrice(ctx context.Context, booking *Models.OrderBookingService, service Models.Service) error {
+	settings, err := loadTaxSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	booking.TotalPrice = booking.TotalPrice.Sub(discountTotal(booking.Discounts)).NonNegative()
+	class := taxClass(settings, service.TaxClass)
+	booking.TaxClass = class.Code
+	booking.TaxRate = class.Rate
+	booking.TaxInclusive = settings.PricesIncludeTax
+	booking.Currency = booking.TotalPrice.Currency()
+	booking.TaxAmount = taxAmount(booking.TotalPrice, class.Rate, settings.PricesIncludeTax)
+	if !settings.PricesIncludeTax {
+		booking.TotalPrice = booking.TotalPrice.Add(booking.TaxAmount)
+	}
+	return nil
+}
+
+// clearBookingServerFields drops everything in a new booking that only the
+// server sets, so a customer cannot create a booking that already looks
+// discounted, paid, assigned, worked on or rated.
+func clearBookingServerFields(booking *Models.OrderBookingService) {
+	booking.Discounts = nil
+	booking.Promotions = nil
+	booking.PaymentMethod = ""
+	booking.PaymentStatus = ""
+	booking.InvoiceNumber = ""
+	booking.PlanID = primitive.NilObjectID
+	booking.Occurrence = ""
+	booking.StaffID = primitive.NilObjectID
+	booking.AssignedAt = 0
+	booking.FinishAt = 0
+	booking.CheckInAt = 0
+	booking.CheckOutAt = 0
+	booking.CompletionNote = ""
+	booking.BeforePhotos = nil
+	booking.AfterPhotos = nil
+	booking.ConfirmedAt = 0
+	booking.StaffRating = 0
+	booking.StaffComment = ""
+	booking.StaffRatedAt = 0
+	booking.RescheduleCount = 0
+	booking.CancelledAt = 0
+	booking.CancellationReason = ""
+	booking.CancellationFee = Models.Money{}
+}
+
 func CreateOrderBookingService(c *gin.Context) {
 	claims := c.MustGet("user").(*Middleware.UserClaims)
 	userID := claims.ID
@@ -32,6 +123,17 @@ func CreateOrderBookingService(c *gin.Context) {
 		return
 	}
 
+	if orderBookingService.AddressID != primitive.NilObjectID {
+		saved, err := loadSavedAddress(context.Background(), userID, orderBookingService.AddressID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		orderBookingService.ContactName = saved.Recipient
+		orderBookingService.ContactPhone = saved.Phone
+		orderBookingService.Location = &saved.Location
+	}
+
 	serviceCollection := getServiceCollection()
 	var service Models.Service
 	if err := serviceCollection.FindOne(context.Background(), bson.M{"_id": orderBookingService.ServiceID}).Decode(&service); err != nil {
@@ -39,15 +141,93 @@ func CreateOrderBookingService(c *gin.Context) {
 		return
 	}
 
+	orderBookingService.ID = primitive.NewObjectID()
 	orderBookingService.UserID = userID
-	orderBookingService.TotalPrice = float64(orderBookingService.Quantity) * service.Price
-	orderBookingService.Status = "pending"
+	clearBookingServerFields(&orderBookingService)
+	if err := priceBookingBase(context.Background(), &orderBookingService, service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, discounts, promotions, err := evaluatePromotions(context.Background(), userID, bookingDiscountCart(service, orderBookingService.Quantity, orderBookingService.TotalPrice))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate promotions"})
+		return
+	}
+
+	var coupon Models.Coupon
+	if orderBookingService.CouponCode != "" {
+		var couponLines []Models.DiscountLine
+		coupon, couponLines, err = evaluateCoupon(context.Background(), orderBookingService.CouponCode, userID, cart)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		orderBookingService.CouponCode = coupon.Code
+		discounts = append(discounts, couponLines...)
+	}
+	pointLines, err := pointsDiscount(context.Background(), userID, orderBookingService.PointsUsed, applyDiscountLines(cart, discounts))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(pointLines) == 0 {
+		orderBookingService.PointsUsed = 0
+	}
+	discounts = append(discounts, pointLines...)
+
+	orderBookingService.Discounts = discounts
+	orderBookingService.Promotions = promotions
+	if err := finishBookingPrice(context.Background(), &orderBookingService, service); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate tax"})
+		return
+	}
+	orderBookingService.Status = BookingPending
 	orderBookingService.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
 	orderBookingService.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
-	orderBookingService.BookingDate = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := scheduleBooking(context.Background(), &orderBookingService, service); err != nil {
+		status := http.StatusBadRequest
+		if err == errSlotFull {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	release := func() {
+		if err := releaseSlotKeys(context.Background(), orderBookingService.SlotKeys); err != nil {
+			log.Println("Error releasing booking slots:", err)
+		}
+	}
+
+	if orderBookingService.PointsUsed > 0 {
+		if err := redeemPoints(context.Background(), userID, orderBookingService.PointsUsed, PaymentTargetBooking, orderBookingService.ID); err != nil {
+			release()
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if orderBookingService.CouponCode != "" {
+		if err := redeemCoupon(context.Background(), coupon, userID, PaymentTargetBooking, orderBookingService.ID, couponDiscount(orderBookingService.Discounts)); err != nil {
+			if reverseErr := reverseLoyalty(context.Background(), PaymentTargetBooking, orderBookingService.ID); reverseErr != nil {
+				log.Println("Error returning redeemed points:", reverseErr)
+			}
+			release()
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	orderBookingServiceCollection := getOrderBookingServiceCollection()
 	if _, err := orderBookingServiceCollection.InsertOne(context.Background(), orderBookingService); err != nil {
+		release()
+		if releaseErr := releaseCoupon(context.Background(), PaymentTargetBooking, orderBookingService.ID); releaseErr != nil {
+			log.Println("Error releasing coupon:", releaseErr)
+		}
+		if reverseErr := reverseLoyalty(context.Background(), PaymentTargetBooking, orderBookingService.ID); reverseErr != nil {
+			log.Println("Error returning redeemed points:", reverseErr)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order booking service"})
 		return
 	}
@@ -106,9 +286,8 @@ func UpdateOrderBookingServiceStatus(c *gin.Context) {
 		return
 	}
 
-	if statusUpdate.Status != "pending" && statusUpdate.Status != "confirmed" &&
-		statusUpdate.Status != "in-progress" && statusUpdate.Status != "completed" &&
-		statusUpdate.Status != "cancelled" {
+	if statusUpdate.Status != BookingConfirmed && statusUpdate.Status != BookingCompleted &&
+		statusUpdate.Status != BookingCancelled {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status value"})
 		return
 	}
@@ -126,22 +305,281 @@ func UpdateOrderBookingServiceStatus(c *gin.Context) {
 		return
 	}
 
-	if orderBookingService.Status == "completed" || orderBookingService.Status == "cancelled" {
+	if orderBookingService.Status == BookingCompleted || orderBookingService.Status == BookingCancelled {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot update a completed or cancelled order"})
 		return
 	}
 
+	if !canTransitionBooking(orderBookingService.Status, statusUpdate.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change status from " + orderBookingService.Status + " to " + statusUpdate.Status})
+		return
+	}
+
+	set := bson.M{}
+	if statusUpdate.Status == BookingCompleted {
+		set["confirmed_at"] = primitive.NewDateTimeFromTime(time.Now())
+	}
+
+	if err := transitionBooking(context.Background(), orderIDObj, orderBookingService.Status, statusUpdate.Status, set); err != nil {
+		if err == errBookingStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		return
+	}
+
+	if statusUpdate.Status == BookingCompleted {
+		if err := captureCODPayment(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+			log.Println("Error capturing cash on delivery payment:", err)
+		}
+		if err := awardPoints(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+			log.Println("Error awarding loyalty points:", err)
+		}
+		if _, err := issueInvoice(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+			log.Println("Error issuing invoice:", err)
+		}
+	}
+	if statusUpdate.Status == BookingCancelled {
+		if err := reverseLoyalty(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+			log.Println("Error reversing loyalty points:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order status updated"})
+}
+
+func ConfirmOrderBookingServiceCompletion(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	orderIDObj, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var orderBookingService Models.OrderBookingService
+	if err := getOrderBookingServiceCollection().FindOne(context.Background(), bson.M{"_id": orderIDObj, "user_id": claims.ID}).Decode(&orderBookingService); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	if orderBookingService.Status != BookingAwaitingConfirmation {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This booking is not waiting for your confirmation"})
+		return
+	}
+
+	set := bson.M{"confirmed_at": primitive.NewDateTimeFromTime(time.Now())}
+	if err := transitionBooking(context.Background(), orderIDObj, BookingAwaitingConfirmation, BookingCompleted, set); err != nil {
+		if err == errBookingStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		return
+	}
+
+	if err := captureCODPayment(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+		log.Println("Error capturing cash on delivery payment:", err)
+	}
+	if err := awardPoints(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+		log.Println("Error awarding loyalty points:", err)
+	}
+	if _, err := issueInvoice(context.Background(), PaymentTargetBooking, orderIDObj); err != nil {
+		log.Println("Error issuing invoice:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking completed"})
+}
+
+func RescheduleOrderBookingService(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	orderIDObj, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		BookingDate primitive.DateTime `json:"booking_date"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.BookingDate == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	booking, service, err := loadBookingWithService(ctx, orderIDObj)
+	if err != nil || booking.UserID != claims.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if booking.Status != BookingPending && booking.Status != BookingConfirmed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only upcoming bookings can be rescheduled"})
+		return
+	}
+
+	policy, err := loadCancellationSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cancellation policy"})
+		return
+	}
+	if booking.RescheduleCount >= policy.MaxReschedules {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This booking cannot be rescheduled again"})
+		return
+	}
+	cutoff := booking.BookingDate.Time().Add(-time.Duration(policy.RescheduleCutoffHours) * time.Hour)
+	if time.Now().After(cutoff) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bookings can only be rescheduled up to " + strconv.Itoa(policy.RescheduleCutoffHours) + " hours before the start"})
+		return
+	}
+
+	previousDate, previousSlots := booking.BookingDate, booking.SlotKeys
+	booking.BookingDate = request.BookingDate
+	if err := scheduleBooking(ctx, &booking, service); err != nil {
+		status := http.StatusBadRequest
+		if err == errSlotFull {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	releaseNew := func() {
+		if err := releaseSlotKeys(ctx, booking.SlotKeys); err != nil {
+			log.Println("Error releasing booking slots:", err)
+		}
+	}
+
+	if err := priceBooking(ctx, &booking, service); err != nil {
+		releaseNew()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	update := bson.M{
 		"$set": bson.M{
-			"status":     statusUpdate.Status,
-			"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+			"booking_date":  booking.BookingDate,
+			"booking_end":   booking.BookingEnd,
+			"slot_keys":     booking.SlotKeys,
+			"total_price":   booking.TotalPrice,
+			"price_lines":   booking.PriceLines,
+			"tax_class":     booking.TaxClass,
+			"tax_rate":      booking.TaxRate,
+			"tax_amount":    booking.TaxAmount,
+			"tax_inclusive": booking.TaxInclusive,
+			"currency":      booking.Currency,
+			"updated_at":    primitive.NewDateTimeFromTime(time.Now()),
 		},
+		"$inc": bson.M{"reschedule_count": 1},
 	}
 
-	if _, err := orderBookingServiceCollection.UpdateOne(context.Background(), bson.M{"_id": orderIDObj}, update); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+	// The assigned cleaner keeps the job only if they are still free at the
+	// new time; otherwise the booking goes back to dispatch.
+	if booking.StaffID != primitive.NilObjectID {
+		var profile Models.StaffProfile
+		keep := false
+		if err := getStaffProfileCollection().FindOne(ctx, bson.M{"user_id": booking.StaffID}).Decode(&profile); err == nil {
+			if unlock, err := lockStaff(ctx, booking.StaffID); err == nil {
+				defer unlock()
+				reason, err := staffUnavailableReason(ctx, profile, booking, service)
+				keep = err == nil && reason == ""
+			}
+		}
+		if !keep {
+			update["$unset"] = bson.M{"staff_id": "", "assigned_at": ""}
+			booking.StaffID = primitive.NilObjectID
+			booking.AssignedAt = 0
+		}
+	}
+
+	filter := bson.M{"_id": orderIDObj, "status": booking.Status, "booking_date": previousDate}
+	result, err := getOrderBookingServiceCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		releaseNew()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reschedule booking"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		releaseNew()
+		c.JSON(http.StatusConflict, gin.H{"error": errBookingStatusChanged.Error()})
 		return
 	}
+	if err := releaseSlotKeys(ctx, previousSlots); err != nil {
+		log.Println("Error releasing booking slots:", err)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order status updated"})
+	booking.RescheduleCount++
+	c.JSON(http.StatusOK, booking)
+}
+
+func CancelOrderBookingService(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	orderIDObj, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var booking Models.OrderBookingService
+	if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": orderIDObj, "user_id": claims.ID}).Decode(&booking); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if booking.Status != BookingPending && booking.Status != BookingConfirmed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only upcoming bookings can be cancelled"})
+		return
+	}
+
+	policy, err := loadCancellationSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cancellation policy"})
+		return
+	}
+
+	now := time.Now()
+	fee := cancellationFee(policy, booking, now)
+	set := bson.M{
+		"cancelled_at":        primitive.NewDateTimeFromTime(now),
+		"cancellation_reason": request.Reason,
+		"cancellation_fee":    fee,
+	}
+	if err := transitionBooking(ctx, orderIDObj, booking.Status, BookingCancelled, set); err != nil {
+		if err == errBookingStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+
+	if err := reverseLoyalty(ctx, PaymentTargetBooking, orderIDObj); err != nil {
+		log.Println("Error reversing loyalty points:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled", "cancellation_fee": fee})
+}
+
+// cancellationFee is free until the policy window before the booking starts
+// and a percentage of the booking total afterwards.
+func cancellationFee(policy Models.CancellationSettings, booking Models.OrderBookingService, now time.Time) Models.Money {
+	freeUntil := booking.BookingDate.Time().Add(-time.Duration(policy.FreeCancelHours) * time.Hour)
+	if now.Before(freeUntil) {
+		return Models.Money{}
+	}
+	return booking.TotalPrice.Percent(policy.LateCancelFeePercent)
 }
@@ -0,0 +1,135 @@
+package Controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestClearBookingServerFieldsDropsForgedState(t *testing.T) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	booking := Models.OrderBookingService{
+		ServiceID:      primitive.NewObjectID(),
+		Quantity:       2,
+		ContactName:    "Lan",
+		StaffID:        primitive.NewObjectID(),
+		AssignedAt:     now,
+		CheckInAt:      now,
+		CheckOutAt:     now,
+		ConfirmedAt:    now,
+		FinishAt:       now,
+		BeforePhotos:   []string{"https://example.com/before.jpg"},
+		AfterPhotos:    []string{"https://example.com/after.jpg"},
+		CompletionNote: "Done",
+		StaffRating:    5,
+		StaffComment:   "Great",
+		StaffRatedAt:   now,
+		PlanID:         primitive.NewObjectID(),
+		Occurrence:     "2030-01-07",
+		PaymentStatus:  PaymentPaid,
+		InvoiceNumber:  "INV-2030-000001",
+	}
+
+	clearBookingServerFields(&booking)
+
+	want := Models.OrderBookingService{ServiceID: booking.ServiceID, Quantity: 2, ContactName: "Lan"}
+	if !reflect.DeepEqual(booking, want) {
+		t.Fatalf("booking = %+v, want only what the customer may choose", booking)
+	}
+}
+
+func TestRescheduleOrderBookingServiceStoresTheNewTax(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("moved onto a weekend", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, bookingID, serviceID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+		day := time.Now().In(bookingLocation).AddDate(0, 0, 2)
+		for day.Weekday() != time.Saturday {
+			day = day.AddDate(0, 0, 1)
+		}
+		saturday := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, bookingLocation)
+		monday := saturday.AddDate(0, 0, 2)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.order_booking_service", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: bookingID},
+				{Key: "user_id", Value: userID},
+				{Key: "service_id", Value: serviceID},
+				{Key: "quantity", Value: 1},
+				{Key: "address", Value: "1 Lê Lợi, Quận 1"},
+				{Key: "booking_date", Value: primitive.NewDateTimeFromTime(monday)},
+				{Key: "status", Value: BookingPending},
+				{Key: "total_price", Value: 110000},
+				{Key: "tax_amount", Value: 10000},
+			}),
+			mtest.CreateCursorResponse(0, "test.services", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: serviceID},
+				{Key: "name", Value: "Cleaning"},
+				{Key: "price", Value: 110000},
+				{Key: "duration_minutes", Value: 30},
+				{Key: "pricing", Value: bson.D{{Key: "weekend_surcharge_percent", Value: 100}}},
+			}),
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.booking_slots", mtest.FirstBatch, bson.D{{Key: "_id", Value: "slot"}, {Key: "used", Value: 0}}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateCursorResponse(0, "test.service_areas", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.settings", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		body := `{"booking_date":"` + saturday.UTC().Format(time.RFC3339) + `"}`
+		c.Request = httptest.NewRequest(http.MethodPut, "/order-booking-services/x/reschedule", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: bookingID.Hex()}}
+		c.Set("user", &Middleware.UserClaims{ID: userID, Role: Middleware.Customer})
+		RescheduleOrderBookingService(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d, body %s", recorder.Code, recorder.Body.String())
+		}
+
+		var set bson.Raw
+		for _, command := range updatesSent(mt) {
+			if command.Lookup("update").StringValue() == "order_booking_service" {
+				set = command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+			}
+		}
+		if set == nil {
+			mt.Fatal("the booking was not updated")
+		}
+		if total := set.Lookup("total_price").AsInt64(); total != 220000 {
+			mt.Fatalf("total = %d, want the weekend price 220000", total)
+		}
+		if tax := set.Lookup("tax_amount").AsInt64(); tax != 20000 {
+			mt.Errorf("tax = %d, want 20000 to match the new total", tax)
+		}
+		if rate := set.Lookup("tax_rate").Double(); rate != 10 {
+			mt.Errorf("tax rate = %v, want 10", rate)
+		}
+		if class := set.Lookup("tax_class").StringValue(); class != "standard" {
+			mt.Errorf("tax class = %q, want standard", class)
+		}
+		if !set.Lookup("tax_inclusive").Boolean() {
+			mt.Error("tax_inclusive should be stored")
+		}
+		if currency := set.Lookup("currency").StringValue(); currency != Models.DefaultCurrency {
+			mt.Errorf("currency = %q, want %q", currency, Models.DefaultCurrency)
+		}
+	})
+}
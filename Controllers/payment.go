@@ -0,0 +1,68 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+
+	"Server/Models"
+)
+
+// PaymentResult is a verified callback from a payment gateway.
+type PaymentResult struct {
+	TransactionRef string
+	ProviderRef    string
+	Amount         Models.Money
+	Success        bool
+	Message        string
+}
+
+// PaymentProvider is a payment gateway integration. Redirect gateways return
+// a URL the customer is sent to and report the outcome through signed
+// return and IPN callbacks; offline methods such as cash on delivery return
+// an empty URL.
+type PaymentProvider interface {
+	CreatePaymentURL(ctx context.Context, payment Models.Payment, returnURL, clientIP string) (string, error)
+	VerifyCallback(params url.Values) (PaymentResult, error)
+	Refund(ctx context.Context, payment Models.Payment, amount Models.Money, reason string) (string, error)
+}
+
+var (
+	errInvalidSignature  = errors.New("Invalid payment signature")
+	errCallbackNotUsable = errors.New("This payment method has no callbacks")
+)
+
+var (
+	paymentProvidersMu sync.RWMutex
+	paymentProviders   = map[string]PaymentProvider{"cod": CODProvider{}}
+)
+
+func RegisterPaymentProvider(name string, provider PaymentProvider) {
+	paymentProvidersMu.Lock()
+	defer paymentProvidersMu.Unlock()
+	paymentProviders[name] = provider
+}
+
+func paymentProvider(name string) (PaymentProvider, bool) {
+	paymentProvidersMu.RLock()
+	defer paymentProvidersMu.RUnlock()
+	provider, ok := paymentProviders[name]
+	return provider, ok
+}
+
+// CODProvider is cash on delivery: nothing is charged online and refunds are
+// handed back in cash by staff.
+type CODProvider struct{}
+
+func (CODProvider) CreatePaymentURL(ctx context.Context, payment Models.Payment, returnURL, clientIP string) (string, error) {
+	return "", nil
+}
+
+func (CODProvider) VerifyCallback(params url.Values) (PaymentResult, error) {
+	return PaymentResult{}, errCallbackNotUsable
+}
+
+func (CODProvider) Refund(ctx context.Context, payment Models.Payment, amount Models.Money, reason string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,496 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PaymentPending  = "pending"
+	PaymentPaid     = "paid"
+	PaymentFailed   = "failed"
+	PaymentRefunded = "refunded"
+
+	PaymentPartiallyRefunded = "partially_refunded"
+
+	PaymentTargetOrder   = "order"
+	PaymentTargetBooking = "booking"
+)
+
+var (
+	errPaymentNotFound         = errors.New("Payment not found")
+	errPaymentAmountMismatch   = errors.New("Payment amount does not match")
+	errPaymentAlreadyProcessed = errors.New("Payment has already been processed")
+)
+
+type paymentTarget struct {
+	Amount        Models.Money
+	Status        string
+	PaymentStatus string
+}
+
+func getPaymentCollection() *mongo.Collection {
+	return Database.Collection("payments")
+}
+
+func CreatePayment(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		TargetType string             `json:"target_type"`
+		TargetID   primitive.ObjectID `json:"target_id"`
+		Provider   string             `json:"provider"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	provider, ok := paymentProvider(request.Provider)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported payment method"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	target, err := loadPaymentTarget(ctx, request.TargetType, request.TargetID, claims.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if target.PaymentStatus == PaymentPaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This has already been paid"})
+		return
+	}
+	if target.Status == "cancelled" || target.Status == "completed" || target.Status == "refunded" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pay for a " + target.Status + " " + request.TargetType})
+		return
+	}
+
+	payment := Models.Payment{
+		ID:         primitive.NewObjectID(),
+		UserID:     claims.ID,
+		TargetType: request.TargetType,
+		TargetID:   request.TargetID,
+		Provider:   request.Provider,
+		Amount:     target.Amount,
+		Currency:   Models.DefaultCurrency,
+		Status:     PaymentPending,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	payment.TransactionRef = payment.ID.Hex()
+
+	payment.PaymentURL, err = provider.CreatePaymentURL(ctx, payment, serverURL("/api/payments/"+payment.Provider+"/return"), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+		return
+	}
+
+	attempt := newLedgerEntry(payment, LedgerAttempt, payment.Amount, "", "", claims.ID)
+	payment.LedgerOutbox = []Models.LedgerEntry{attempt}
+	if _, err := getPaymentCollection().InsertOne(ctx, payment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+		return
+	}
+	if err := deliverLedgerEntry(ctx, payment.ID, attempt); err != nil {
+		log.Println("Error recording payment attempt, left in the outbox:", err)
+	}
+
+	if err := syncPaymentTarget(ctx, payment, PaymentPending); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+func GetPayments(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	filter := bson.M{"user_id": claims.ID}
+	if target := c.Query("target_id"); target != "" {
+		targetID, err := primitive.ObjectIDFromHex(target)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+			return
+		}
+		filter["target_id"] = targetID
+	}
+
+	var payments []Models.Payment
+	cursor, err := getPaymentCollection().Find(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payments"})
+		return
+	}
+	if err := cursor.All(context.Background(), &payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+func PaymentReturn(c *gin.Context) {
+	status := "invalid"
+	paymentID := ""
+
+	if provider, ok := paymentProvider(c.Param("provider")); ok {
+		if result, err := provider.VerifyCallback(c.Request.URL.Query()); err == nil {
+			payment, err := applyPaymentResult(context.Background(), c.Param("provider"), result)
+			paymentID = payment.ID.Hex()
+			switch {
+			case err == nil || err == errPaymentAlreadyProcessed:
+				status = payment.Status
+			case err == errPaymentAmountMismatch:
+				status = PaymentFailed
+			}
+		}
+	}
+
+	c.Redirect(http.StatusFound, clientURL("/payment-result?status="+status+"&payment_id="+paymentID))
+}
+
+// PaymentIPN answers the gateway's server-to-server notification in the
+// response format VNPay expects.
+func PaymentIPN(c *gin.Context) {
+	provider, ok := paymentProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"RspCode": "99", "Message": "Unknown provider"})
+		return
+	}
+
+	result, err := provider.VerifyCallback(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"RspCode": "97", "Message": "Invalid signature"})
+		return
+	}
+
+	switch _, err := applyPaymentResult(context.Background(), c.Param("provider"), result); err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"RspCode": "00", "Message": "Confirm Success"})
+	case errPaymentNotFound:
+		c.JSON(http.StatusOK, gin.H{"RspCode": "01", "Message": "Order not found"})
+	case errPaymentAlreadyProcessed:
+		c.JSON(http.StatusOK, gin.H{"RspCode": "02", "Message": "Order already confirmed"})
+	case errPaymentAmountMismatch:
+		c.JSON(http.StatusOK, gin.H{"RspCode": "04", "Message": "Invalid amount"})
+	default:
+		log.Println("Error applying payment result:", err)
+		c.JSON(http.StatusOK, gin.H{"RspCode": "99", "Message": "Unknown error"})
+	}
+}
+
+func RefundPayment(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	paymentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var request struct {
+		Amount Models.Money `json:"amount"`
+		Reason string       `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Amount.Sign() < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var payment Models.Payment
+	if err := getPaymentCollection().FindOne(ctx, bson.M{"_id": paymentID}).Decode(&payment); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if payment.Status != PaymentPaid && payment.Status != PaymentPartiallyRefunded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only paid payments can be refunded"})
+		return
+	}
+
+	refundable := payment.Amount.Sub(payment.RefundedAmount)
+	if request.Amount.IsZero() {
+		request.Amount = refundable
+	}
+	if request.Amount.Currency() != refundable.Currency() || request.Amount.Sign() <= 0 || request.Amount.Cmp(refundable) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refund amount must be between 1 and the remaining paid amount"})
+		return
+	}
+
+	provider, ok := paymentProvider(payment.Provider)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment method is no longer available"})
+		return
+	}
+
+	// Reserve the amount before calling the gateway so two concurrent refunds
+	// cannot both pass the remaining-amount check.
+	status := PaymentPartiallyRefunded
+	if payment.RefundedAmount.Add(request.Amount).Cmp(payment.Amount) >= 0 {
+		status = PaymentRefunded
+	}
+	// Payments created before refunds existed have no refunded_amount yet.
+	refund := newLedgerEntry(payment, LedgerRefund, request.Amount, "", request.Reason, claims.ID)
+	filter := bson.M{
+		"_id":   paymentID,
+		"$expr": bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$refunded_amount", 0}}, payment.RefundedAmount}},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"refunded_amount": request.Amount},
+		"$set":  bson.M{"status": status, "updated_at": time.Now()},
+		"$push": bson.M{"ledger_outbox": refund},
+	}
+	result, err := getPaymentCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment was refunded concurrently, please reload"})
+		return
+	}
+
+	refundRef, err := provider.Refund(ctx, payment, request.Amount, request.Reason)
+	if err != nil {
+		rollback := bson.M{
+			"$inc":  bson.M{"refunded_amount": request.Amount.Neg()},
+			"$set":  bson.M{"status": payment.Status, "updated_at": time.Now()},
+			"$pull": bson.M{"ledger_outbox": bson.M{"_id": refund.ID}},
+		}
+		if _, rollbackErr := getPaymentCollection().UpdateOne(context.Background(), bson.M{"_id": paymentID}, rollback); rollbackErr != nil {
+			log.Println("Error releasing refund reservation:", rollbackErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Refund failed and the payment could not be restored, please check it"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund.ProviderRef = refundRef
+	if err := deliverLedgerEntry(ctx, paymentID, refund); err != nil {
+		log.Println("Error recording refund, left in the outbox:", err)
+	}
+	if status == PaymentRefunded {
+		if err := syncPaymentTarget(ctx, payment, PaymentRefunded); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+			return
+		}
+	} else {
+		collection := getOrderCollection()
+		if payment.TargetType == PaymentTargetBooking {
+			collection = getOrderBookingServiceCollection()
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": payment.TargetID}, bson.M{"$set": bson.M{"payment_status": PaymentPartiallyRefunded}}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Payment refunded",
+		"amount":          request.Amount,
+		"refunded_amount": payment.RefundedAmount.Add(request.Amount),
+		"status":          status,
+	})
+}
+
+// applyPaymentResult records the outcome of a gateway callback. Return and
+// IPN callbacks can both arrive for the same payment, so only the first one
+// moves it out of pending.
+func applyPaymentResult(ctx context.Context, providerName string, result PaymentResult) (Models.Payment, error) {
+	var payment Models.Payment
+	filter := bson.M{"transaction_ref": result.TransactionRef, "provider": providerName}
+	if err := getPaymentCollection().FindOne(ctx, filter).Decode(&payment); err != nil {
+		return payment, errPaymentNotFound
+	}
+	if payment.Status != PaymentPending {
+		return payment, errPaymentAlreadyProcessed
+	}
+
+	set := bson.M{"provider_ref": result.ProviderRef, "updated_at": time.Now()}
+	var err error
+	switch {
+	case result.Amount != payment.Amount:
+		set["status"] = PaymentFailed
+		set["failure_reason"] = errPaymentAmountMismatch.Error()
+		err = errPaymentAmountMismatch
+	case result.Success:
+		set["status"] = PaymentPaid
+		set["paid_at"] = time.Now()
+	default:
+		set["status"] = PaymentFailed
+		set["failure_reason"] = result.Message
+	}
+
+	entryType, note := LedgerCapture, ""
+	if set["status"] != PaymentPaid {
+		entryType, note = LedgerFailure, set["failure_reason"].(string)
+	}
+	entry := newLedgerEntry(payment, entryType, result.Amount, result.ProviderRef, note, primitive.NilObjectID)
+
+	update := bson.M{"$set": set, "$push": bson.M{"ledger_outbox": entry}}
+	updated, updateErr := getPaymentCollection().UpdateOne(ctx, bson.M{"_id": payment.ID, "status": PaymentPending}, update)
+	if updateErr != nil {
+		return payment, updateErr
+	}
+	if updated.MatchedCount == 0 {
+		return payment, errPaymentAlreadyProcessed
+	}
+
+	payment.Status = set["status"].(string)
+	payment.ProviderRef = result.ProviderRef
+
+	if ledgerErr := deliverLedgerEntry(ctx, payment.ID, entry); ledgerErr != nil {
+		log.Println("Error recording payment result, left in the outbox:", ledgerErr)
+	}
+	if syncErr := syncPaymentTarget(ctx, payment, payment.Status); syncErr != nil {
+		return payment, syncErr
+	}
+	return payment, err
+}
+
+func loadPaymentTarget(ctx context.Context, targetType string, targetID, userID primitive.ObjectID) (paymentTarget, error) {
+	switch targetType {
+	case PaymentTargetOrder:
+		var order Models.Order
+		if err := getOrderCollection().FindOne(ctx, bson.M{"_id": targetID, "user_id": userID}).Decode(&order); err != nil {
+			return paymentTarget{}, errors.New("Order not found")
+		}
+		return paymentTarget{Amount: order.TotalPrice, Status: order.Status, PaymentStatus: order.PaymentStatus}, nil
+	case PaymentTargetBooking:
+		var booking Models.OrderBookingService
+		if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": targetID, "user_id": userID}).Decode(&booking); err != nil {
+			return paymentTarget{}, errors.New("Booking not found")
+		}
+		return paymentTarget{Amount: booking.TotalPrice, Status: booking.Status, PaymentStatus: booking.PaymentStatus}, nil
+	}
+	return paymentTarget{}, errors.New("Unknown payment target")
+}
+
+// syncPaymentTarget mirrors a payment's status onto the order or booking it
+// pays for and moves that order or booking along with it: a successful
+// payment confirms it and a full refund refunds or cancels it. Choosing cash
+// on delivery leaves it pending until staff confirm it. How it was paid is
+// kept in payment_status, never in the status itself.
+func syncPaymentTarget(ctx context.Context, payment Models.Payment, status string) error {
+	switch payment.TargetType {
+	case PaymentTargetOrder:
+		set := bson.M{"payment_method": payment.Provider, "payment_status": status, "updated_at": time.Now()}
+		var order Models.Order
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := getOrderCollection().FindOneAndUpdate(ctx, bson.M{"_id": payment.TargetID}, bson.M{"$set": set}, opts).Decode(&order); err != nil {
+			return err
+		}
+
+		var err error
+		switch {
+		case status == PaymentPaid:
+			err = transitionOrder(ctx, payment.TargetID, OrderPending, OrderConfirmed, nil)
+		case status == PaymentRefunded && canTransitionOrder(order.Status, OrderRefunded):
+			err = transitionOrder(ctx, payment.TargetID, order.Status, OrderRefunded, nil)
+		}
+		if err != nil && err != errOrderStatusChanged {
+			return err
+		}
+		if status == PaymentRefunded {
+			return reverseLoyalty(ctx, PaymentTargetOrder, payment.TargetID)
+		}
+
+	case PaymentTargetBooking:
+		set := bson.M{"payment_method": payment.Provider, "payment_status": status, "updated_at": primitive.NewDateTimeFromTime(time.Now())}
+		if _, err := getOrderBookingServiceCollection().UpdateOne(ctx, bson.M{"_id": payment.TargetID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+
+		var err error
+		switch {
+		case status == PaymentPaid:
+			err = transitionBooking(ctx, payment.TargetID, BookingPending, BookingConfirmed, nil)
+		case status == PaymentRefunded:
+			err = transitionBooking(ctx, payment.TargetID, BookingPending, BookingCancelled, nil)
+			if err == errBookingStatusChanged {
+				err = transitionBooking(ctx, payment.TargetID, BookingConfirmed, BookingCancelled, nil)
+			}
+		}
+		if err != nil && err != errBookingStatusChanged {
+			return err
+		}
+		if status == PaymentRefunded {
+			return reverseLoyalty(ctx, PaymentTargetBooking, payment.TargetID)
+		}
+	}
+	return nil
+}
+
+// captureCODPayment marks cash on delivery as collected once the order or
+// booking it pays for is completed.
+func captureCODPayment(ctx context.Context, targetType string, targetID primitive.ObjectID) error {
+	var payments []Models.Payment
+	filter := bson.M{"target_type": targetType, "target_id": targetID, "provider": "cod", "status": PaymentPending}
+	cursor, err := getPaymentCollection().Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return err
+	}
+
+	captured := false
+	for _, payment := range payments {
+		entry := newLedgerEntry(payment, LedgerCapture, payment.Amount, "", "Collected on delivery", primitive.NilObjectID)
+		update := bson.M{
+			"$set":  bson.M{"status": PaymentPaid, "paid_at": time.Now(), "updated_at": time.Now()},
+			"$push": bson.M{"ledger_outbox": entry},
+		}
+		result, err := getPaymentCollection().UpdateOne(ctx, bson.M{"_id": payment.ID, "status": PaymentPending}, update)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		captured = true
+		if err := deliverLedgerEntry(ctx, payment.ID, entry); err != nil {
+			log.Println("Error recording cash on delivery capture, left in the outbox:", err)
+		}
+	}
+	if !captured {
+		return nil
+	}
+
+	collection := getOrderCollection()
+	if targetType == PaymentTargetBooking {
+		collection = getOrderBookingServiceCollection()
+	}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": targetID}, bson.M{"$set": bson.M{"payment_status": PaymentPaid}})
+	return err
+}
+
+func serverURL(path string) string {
+	base := os.Getenv("SERVER_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/") + path
+}
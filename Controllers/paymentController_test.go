@@ -0,0 +1,148 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSyncPaymentTargetLeavesCODBookingPending(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("cod", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+
+		payment := Models.Payment{TargetType: PaymentTargetBooking, TargetID: primitive.NewObjectID(), Provider: "cod"}
+		if err := syncPaymentTarget(context.Background(), payment, PaymentPending); err != nil {
+			mt.Fatalf("syncPaymentTarget: %v", err)
+		}
+
+		events := mt.GetAllStartedEvents()
+		if len(events) != 1 {
+			mt.Fatalf("got %d commands, want only the payment status update", len(events))
+		}
+		if _, err := events[0].Command.LookupErr("updates", "0", "u", "$set", "status"); err == nil {
+			mt.Fatal("choosing cash on delivery must not change the booking status")
+		}
+	})
+}
+
+func TestSyncPaymentTargetConfirmsPaidOrder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("paid", func(mt *mtest.T) {
+		Database = mt.DB
+		orderID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: orderID}, {Key: "status", Value: OrderPending}}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		payment := Models.Payment{TargetType: PaymentTargetOrder, TargetID: orderID, Provider: "vnpay"}
+		if err := syncPaymentTarget(context.Background(), payment, PaymentPaid); err != nil {
+			mt.Fatalf("syncPaymentTarget: %v", err)
+		}
+
+		var transition bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				transition = started.Command
+			}
+		}
+		if transition == nil {
+			mt.Fatal("the order status was not updated")
+		}
+		if from := transition.Lookup("updates", "0", "q", "status").StringValue(); from != OrderPending {
+			mt.Fatalf("transition from %q, want %q", from, OrderPending)
+		}
+		if to := transition.Lookup("updates", "0", "u", "$set", "status").StringValue(); to != OrderConfirmed {
+			mt.Fatalf("paid order moved to %q, want %q", to, OrderConfirmed)
+		}
+	})
+}
+
+func TestOrderTransitions(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderPending, OrderConfirmed, true},
+		{OrderConfirmed, OrderShipped, true},
+		{OrderCompleted, OrderRefunded, true},
+		{OrderPending, OrderRefunded, false},
+		{OrderCompleted, OrderCancelled, false},
+		{OrderCancelled, OrderConfirmed, false},
+		{OrderRefunded, OrderCompleted, false},
+	}
+	for _, tc := range cases {
+		if got := canTransitionOrder(tc.from, tc.to); got != tc.want {
+			t.Errorf("canTransitionOrder(%q, %q) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+type stubPaymentProvider struct {
+	CODProvider
+	refundErr error
+}
+
+func (p stubPaymentProvider) Refund(ctx context.Context, payment Models.Payment, amount Models.Money, reason string) (string, error) {
+	return "refund-1", p.refundErr
+}
+
+func TestRefundPaymentHandlesLegacyPaymentsAndFailedRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	RegisterPaymentProvider("stub-refund", stubPaymentProvider{refundErr: errors.New("gateway down")})
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("rollback fails", func(mt *mtest.T) {
+		Database = mt.DB
+		paymentID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.payments", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: paymentID},
+				{Key: "provider", Value: "stub-refund"},
+				{Key: "amount", Value: int64(100000)},
+				{Key: "status", Value: PaymentPaid},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}),
+		)
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/payments/"+paymentID.Hex()+"/refund", strings.NewReader(`{"amount": 40000}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: paymentID.Hex()}}
+		c.Set("user", &Middleware.UserClaims{ID: primitive.NewObjectID(), Role: Middleware.Admin})
+
+		RefundPayment(c)
+
+		if recorder.Code != http.StatusInternalServerError {
+			mt.Fatalf("status = %d, body %s; want a 500 when the reservation cannot be released", recorder.Code, recorder.Body)
+		}
+		var reservation bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				reservation = started.Command
+				break
+			}
+		}
+		condition := reservation.Lookup("updates", "0", "q", "$expr", "$eq").Array()
+		if _, err := condition.Index(0).Value().Document().LookupErr("$ifNull"); err != nil {
+			mt.Fatalf("the refund filter must treat a missing refunded_amount as 0, got %s", condition)
+		}
+	})
+}
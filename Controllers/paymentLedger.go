@@ -0,0 +1,114 @@
+package Controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	LedgerAttempt = "attempt"
+	LedgerCapture = "capture"
+	LedgerFailure = "failure"
+	LedgerRefund  = "refund"
+)
+
+func getLedgerCollection() *mongo.Collection {
+	return Database.Collection("payment_ledger")
+}
+
+// newLedgerEntry builds a ledger entry for a payment. Entries are never
+// updated or deleted so the ledger stays an audit trail of every attempt,
+// capture and refund.
+func newLedgerEntry(payment Models.Payment, entryType string, amount Models.Money, providerRef, note string, createdBy primitive.ObjectID) Models.LedgerEntry {
+	return Models.LedgerEntry{
+		ID:             primitive.NewObjectID(),
+		PaymentID:      payment.ID,
+		TargetType:     payment.TargetType,
+		TargetID:       payment.TargetID,
+		Provider:       payment.Provider,
+		Type:           entryType,
+		Amount:         amount,
+		TransactionRef: payment.TransactionRef,
+		ProviderRef:    providerRef,
+		Note:           note,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// deliverLedgerEntry appends an entry queued in a payment's outbox to the
+// ledger and then removes it from the outbox. The entry keeps its ID, so
+// delivering it twice hits the duplicate key instead of counting it twice.
+// Whatever is left in an outbox is retried by flushLedgerOutbox.
+func deliverLedgerEntry(ctx context.Context, paymentID primitive.ObjectID, entry Models.LedgerEntry) error {
+	if _, err := getLedgerCollection().InsertOne(ctx, entry); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	_, err := getPaymentCollection().UpdateOne(ctx, bson.M{"_id": paymentID}, bson.M{"$pull": bson.M{"ledger_outbox": bson.M{"_id": entry.ID}}})
+	return err
+}
+
+// flushLedgerOutbox delivers the ledger entries that were queued on payments
+// but did not reach the ledger when they were written.
+func flushLedgerOutbox(ctx context.Context) error {
+	var payments []Models.Payment
+	cursor, err := getPaymentCollection().Find(ctx, bson.M{"ledger_outbox.0": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		for _, entry := range payment.LedgerOutbox {
+			if err := deliverLedgerEntry(ctx, payment.ID, entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func GetPaymentLedger(c *gin.Context) {
+	filter := bson.M{}
+	for _, field := range []string{"payment_id", "target_id"} {
+		if value := c.Query(field); value != "" {
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + field})
+				return
+			}
+			filter[field] = id
+		}
+	}
+	if provider := c.Query("provider"); provider != "" {
+		filter["provider"] = provider
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entries []Models.LedgerEntry
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(500)
+	cursor, err := getLedgerCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ledger"})
+		return
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,151 @@
+package Controllers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+type pdfLine struct {
+	text string
+	font string
+	size float64
+}
+
+// pdfDocument is a minimal single-column PDF writer built on the standard
+// Type1 fonts, which is enough for transcripts and invoices without pulling
+// in a PDF dependency. Vietnamese text is folded to ASCII before rendering.
+type pdfDocument struct {
+	lines []pdfLine
+}
+
+func (d *pdfDocument) Heading(text string) { d.add(text, "F2", 14) }
+func (d *pdfDocument) Bold(text string)    { d.add(text, "F2", 10) }
+func (d *pdfDocument) Text(text string)    { d.add(text, "F1", 10) }
+func (d *pdfDocument) Mono(text string)    { d.add(text, "F3", 9) }
+func (d *pdfDocument) Blank()              { d.lines = append(d.lines, pdfLine{font: "F1", size: 10}) }
+
+func (d *pdfDocument) add(text, font string, size float64) {
+	charWidth := size * 0.5
+	if font == "F3" {
+		charWidth = size * 0.6
+	}
+	maxChars := int((pdfPageWidth - 2*pdfMargin) / charWidth)
+
+	for _, paragraph := range strings.Split(foldVietnamese(text), "\n") {
+		for _, line := range wrapText(paragraph, maxChars) {
+			d.lines = append(d.lines, pdfLine{text: line, font: font, size: size})
+		}
+	}
+}
+
+func (d *pdfDocument) Bytes() []byte {
+	var pages []string
+	var content strings.Builder
+	y := pdfPageHeight - pdfMargin
+
+	for _, line := range d.lines {
+		leading := line.size * 1.4
+		if y-leading < pdfMargin {
+			pages = append(pages, content.String())
+			content.Reset()
+			y = pdfPageHeight - pdfMargin
+		}
+		y -= leading
+		if line.text != "" {
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", line.font, line.size, pdfMargin, y, pdfEscape(line.text))
+		}
+	}
+	pages = append(pages, content.String())
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+
+	var kids []string
+	for _, page := range pages {
+		pageNumber := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageNumber))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, pageNumber+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(page), page),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range words {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
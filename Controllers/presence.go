@@ -0,0 +1,239 @@
+package Controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	presenceTimeout       = 90 * time.Second
+	presenceFlushInterval = 10 * time.Second
+	presenceSaveInterval  = time.Minute
+)
+
+type presenceState struct {
+	role        Models.Role
+	connections int
+	online      bool
+	lastSeen    time.Time
+	lastSaved   time.Time
+	dirty       bool
+}
+
+type PresenceTracker struct {
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*presenceState
+}
+
+var Presence = NewPresenceTracker()
+
+func NewPresenceTracker() *PresenceTracker {
+	return &PresenceTracker{users: make(map[primitive.ObjectID]*presenceState)}
+}
+
+func (p *PresenceTracker) Connect(userID primitive.ObjectID, role Models.Role) {
+	p.mu.Lock()
+	state, ok := p.users[userID]
+	if !ok {
+		state = &presenceState{role: role}
+		p.users[userID] = state
+	}
+	state.connections++
+	state.lastSeen = time.Now()
+	changed := !state.online
+	state.online = true
+	state.dirty = true
+	event := state.event(userID)
+	p.mu.Unlock()
+
+	if changed {
+		p.announce(event)
+	}
+}
+
+// Heartbeat records activity on an open connection. A connection that was
+// expired or flushed while still open is brought back online.
+func (p *PresenceTracker) Heartbeat(userID primitive.ObjectID, role Models.Role) {
+	p.mu.Lock()
+	state, ok := p.users[userID]
+	if !ok {
+		state = &presenceState{role: role}
+		p.users[userID] = state
+	}
+	state.lastSeen = time.Now()
+	changed := !state.online
+	if changed {
+		state.online = true
+		if state.connections <= 0 {
+			state.connections = 1
+		}
+		state.dirty = true
+	} else if time.Since(state.lastSaved) > presenceSaveInterval {
+		state.dirty = true
+	}
+	event := state.event(userID)
+	p.mu.Unlock()
+
+	if changed {
+		p.announce(event)
+	}
+}
+
+func (p *PresenceTracker) Disconnect(userID primitive.ObjectID) {
+	p.mu.Lock()
+	state, ok := p.users[userID]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	state.connections--
+	state.lastSeen = time.Now()
+	changed := state.connections <= 0 && state.online
+	if changed {
+		state.connections = 0
+		state.online = false
+		state.dirty = true
+	}
+	event := state.event(userID)
+	p.mu.Unlock()
+
+	if changed {
+		p.announce(event)
+	}
+}
+
+func (p *PresenceTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(presenceFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.expire()
+			p.flush()
+		}
+	}
+}
+
+func (p *PresenceTracker) expire() {
+	var events []Models.PresenceEvent
+
+	p.mu.Lock()
+	for userID, state := range p.users {
+		if state.online && time.Since(state.lastSeen) > presenceTimeout {
+			state.online = false
+			state.connections = 0
+			state.dirty = true
+			events = append(events, state.event(userID))
+		}
+	}
+	p.mu.Unlock()
+
+	for _, event := range events {
+		p.announce(event)
+	}
+}
+
+func (p *PresenceTracker) flush() {
+	type pending struct {
+		userID   primitive.ObjectID
+		online   bool
+		lastSeen time.Time
+	}
+
+	var writes []pending
+	p.mu.Lock()
+	for userID, state := range p.users {
+		if !state.dirty {
+			continue
+		}
+		writes = append(writes, pending{userID, state.online, state.lastSeen})
+		state.dirty = false
+		state.lastSaved = time.Now()
+		if !state.online {
+			delete(p.users, userID)
+		}
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := getUserCollection()
+	for _, write := range writes {
+		update := bson.M{"$set": bson.M{"is_online": write.online, "last_seen": write.lastSeen}}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": write.userID}, update); err != nil {
+			log.Println("Error saving presence:", err)
+		}
+	}
+
+	stale := bson.M{"is_online": true, "last_seen": bson.M{"$lt": time.Now().Add(-3 * presenceSaveInterval)}}
+	if _, err := collection.UpdateMany(ctx, stale, bson.M{"$set": bson.M{"is_online": false}}); err != nil {
+		log.Println("Error expiring presence:", err)
+	}
+}
+
+func (p *PresenceTracker) announce(event Models.PresenceEvent) {
+	if event.Role > Models.Staff {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ChatHub.PublishPresence(ctx, event); err != nil {
+		log.Println("Error publishing presence:", err)
+	}
+}
+
+func (s *presenceState) event(userID primitive.ObjectID) Models.PresenceEvent {
+	return Models.PresenceEvent{
+		UserID:   userID,
+		Role:     s.role,
+		IsOnline: s.online,
+		LastSeen: s.lastSeen,
+	}
+}
+
+func GetOnlineStaff(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"is_online": true, "role": bson.M{"$in": []Models.Role{Models.Admin, Models.Staff}}}
+	projection := options.Find().SetProjection(bson.M{
+		"firstname": 1,
+		"lastname":  1,
+		"avatar":    1,
+		"role":      1,
+		"is_online": 1,
+		"last_seen": 1,
+	})
+
+	var staff []Models.User
+	cursor, err := getUserCollection().Find(ctx, filter, projection)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching presence"})
+		return
+	}
+	if err := cursor.All(ctx, &staff); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error parsing presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(staff),
+		"staff": staff,
+	})
+}
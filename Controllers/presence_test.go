@@ -0,0 +1,44 @@
+package Controllers
+
+import (
+	"testing"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHeartbeatRevivesExpiredConnection(t *testing.T) {
+	tracker := NewPresenceTracker()
+	userID := primitive.NewObjectID()
+
+	tracker.Connect(userID, Models.Customer)
+	tracker.users[userID].lastSeen = time.Now().Add(-2 * presenceTimeout)
+	tracker.expire()
+	if tracker.users[userID].online {
+		t.Fatal("expected the idle connection to expire")
+	}
+
+	tracker.Heartbeat(userID, Models.Customer)
+	state := tracker.users[userID]
+	if !state.online || state.connections != 1 || !state.dirty {
+		t.Fatalf("heartbeat did not bring the user back online: %+v", state)
+	}
+}
+
+func TestHeartbeatReinsertsFlushedUser(t *testing.T) {
+	tracker := NewPresenceTracker()
+	userID := primitive.NewObjectID()
+
+	tracker.Heartbeat(userID, Models.Staff)
+	state, ok := tracker.users[userID]
+	if !ok || !state.online || state.role != Models.Staff {
+		t.Fatalf("heartbeat did not re-insert the user: %+v", state)
+	}
+
+	tracker.Disconnect(userID)
+	if tracker.users[userID].online {
+		t.Fatal("closing the revived connection should take the user offline")
+	}
+}
@@ -0,0 +1,144 @@
+package Controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type quoteRequest struct {
+	Quantity    int                `json:"quantity"`
+	Area        float64            `json:"area"`
+	Hours       float64            `json:"hours"`
+	AddOns      []string           `json:"add_ons"`
+	BookingDate time.Time          `json:"booking_date"`
+	Address     string             `json:"address"`
+	Location    *Models.Address    `json:"location"`
+	AddressID   primitive.ObjectID `json:"address_id"`
+}
+
+type quote struct {
+	Lines []Models.PriceLine `json:"lines"`
+	Total Models.Money       `json:"total"`
+}
+
+// quoteService prices a booking of a service. Services without pricing rules
+// keep the original Quantity x Price behaviour.
+func quoteService(service Models.Service, request quoteRequest) (quote, error) {
+	var result quote
+	if request.Quantity <= 0 {
+		request.Quantity = 1
+	}
+
+	pricing := service.Pricing
+	if pricing == nil {
+		result.add("base", service.Name, service.Price.Mul(request.Quantity))
+		return result, nil
+	}
+
+	base := service.Price
+	label := service.Name
+	if len(pricing.AreaTiers) > 0 {
+		if request.Area <= 0 {
+			return result, errors.New("Area is required for this service")
+		}
+		tier, ok := areaTier(pricing.AreaTiers, request.Area)
+		if !ok {
+			return result, fmt.Errorf("Area of %.0f m2 is larger than we can quote online", request.Area)
+		}
+		base = tier.Price
+		label = fmt.Sprintf("%s (up to %.0f m2)", service.Name, tier.MaxArea)
+	}
+	result.add("base", label, base.Mul(request.Quantity))
+
+	if pricing.HourlyRate.Sign() > 0 {
+		hours := math.Max(request.Hours, pricing.MinHours)
+		if hours <= 0 {
+			return result, errors.New("Hours are required for this service")
+		}
+		result.add("hours", fmt.Sprintf("%g hours", hours), pricing.HourlyRate.Scale(hours))
+	}
+
+	for _, code := range request.AddOns {
+		addOn, ok := findAddOn(pricing.AddOns, code)
+		if !ok {
+			return result, fmt.Errorf("Unknown add-on %q", code)
+		}
+		result.add("addon:"+addOn.Code, addOn.Name, addOn.Price)
+	}
+
+	if !request.BookingDate.IsZero() {
+		subtotal := result.Total
+		local := request.BookingDate.In(bookingLocation)
+		if containsString(pricing.Holidays, local.Format("2006-01-02")) && pricing.HolidaySurchargePercent > 0 {
+			result.add("holiday", fmt.Sprintf("Holiday surcharge %g%%", pricing.HolidaySurchargePercent), subtotal.Percent(pricing.HolidaySurchargePercent))
+		} else if (local.Weekday() == time.Saturday || local.Weekday() == time.Sunday) && pricing.WeekendSurchargePercent > 0 {
+			result.add("weekend", fmt.Sprintf("Weekend surcharge %g%%", pricing.WeekendSurchargePercent), subtotal.Percent(pricing.WeekendSurchargePercent))
+		}
+	}
+
+	return result, nil
+}
+
+func (q *quote) add(code, label string, amount Models.Money) {
+	q.Lines = append(q.Lines, Models.PriceLine{Code: code, Label: label, Amount: amount})
+	q.Total = q.Total.Add(amount)
+}
+
+func areaTier(tiers []Models.AreaTier, area float64) (Models.AreaTier, bool) {
+	sorted := append([]Models.AreaTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MaxArea < sorted[j].MaxArea })
+	for _, tier := range sorted {
+		if area <= tier.MaxArea {
+			return tier, true
+		}
+	}
+	return Models.AreaTier{}, false
+}
+
+func findAddOn(addOns []Models.ServiceAddOn, code string) (Models.ServiceAddOn, bool) {
+	for _, addOn := range addOns {
+		if strings.EqualFold(addOn.Code, code) {
+			return addOn, true
+		}
+	}
+	return Models.ServiceAddOn{}, false
+}
+
+func validateServicePricing(pricing Models.ServicePricing) error {
+	for _, tier := range pricing.AreaTiers {
+		if tier.MaxArea <= 0 || tier.Price.Sign() < 0 {
+			return errors.New("Area tiers need a positive size and a non-negative price")
+		}
+	}
+	if pricing.HourlyRate.Sign() < 0 || pricing.MinHours < 0 {
+		return errors.New("Hourly rate and minimum hours cannot be negative")
+	}
+	codes := map[string]bool{}
+	for _, addOn := range pricing.AddOns {
+		code := strings.ToLower(addOn.Code)
+		if code == "" || addOn.Name == "" || addOn.Price.Sign() < 0 {
+			return errors.New("Add-ons need a code, a name and a non-negative price")
+		}
+		if codes[code] {
+			return fmt.Errorf("Duplicate add-on code %q", addOn.Code)
+		}
+		codes[code] = true
+	}
+	if pricing.WeekendSurchargePercent < 0 || pricing.HolidaySurchargePercent < 0 {
+		return errors.New("Surcharges cannot be negative")
+	}
+	for _, date := range pricing.Holidays {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("Invalid holiday %q, expected YYYY-MM-DD", date)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package Controllers
+
+import (
+	"testing"
+	"time"
+
+	"Server/Models"
+)
+
+func TestServiceDurationFollowsBookedHours(t *testing.T) {
+	hourly := Models.Service{DurationMinutes: 90, Pricing: &Models.ServicePricing{HourlyRate: Models.Minor(100000), MinHours: 2}}
+	fixed := Models.Service{DurationMinutes: 90}
+
+	cases := []struct {
+		name    string
+		service Models.Service
+		hours   float64
+		want    time.Duration
+	}{
+		{"hours booked", hourly, 3.5, 210 * time.Minute},
+		{"below the minimum", hourly, 1, 2 * time.Hour},
+		{"no hours on an hourly service", hourly, 0, 2 * time.Hour},
+		{"fixed service", fixed, 0, 90 * time.Minute},
+		{"no duration", Models.Service{}, 0, defaultServiceDuration},
+	}
+	for _, tc := range cases {
+		if got := serviceDuration(tc.service, tc.hours); got != tc.want {
+			t.Errorf("%s: serviceDuration = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
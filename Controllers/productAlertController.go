@@ -0,0 +1,637 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AlertRestock   = "restock"
+	AlertPriceDrop = "price_drop"
+
+	ProductEventRestocked    = "restocked"
+	ProductEventPriceDropped = "price_dropped"
+
+	productEventPending    = "pending"
+	productEventProcessing = "processing"
+	productEventDone       = "done"
+
+	productAlertInterval = time.Minute
+	// productAlertCooldown is the least time between two alerts for the same
+	// subscription, so a price that keeps dropping does not flood customers.
+	productAlertCooldown = 24 * time.Hour
+	// productAlertDailyLimit caps the alerts one customer receives a day
+	// across all their subscriptions.
+	productAlertDailyLimit = 10
+	productEventClaimTTL   = 10 * time.Minute
+
+	alertDeliveryPending    = "pending"
+	alertDeliveryProcessing = "processing"
+	alertDeliverySent       = "sent"
+	alertDeliverySkipped    = "skipped"
+	alertDeliveryFailed     = "failed"
+
+	// A delivery that fails is retried after productAlertRetryDelay times the
+	// number of attempts so far, up to productAlertMaxAttempts.
+	productAlertRetryDelay  = 5 * time.Minute
+	productAlertMaxAttempts = 5
+)
+
+func getProductAlertCollection() *mongo.Collection {
+	return Database.Collection("product_alerts")
+}
+
+func getProductEventCollection() *mongo.Collection {
+	return Database.Collection("product_events")
+}
+
+func getAlertDeliveryCollection() *mongo.Collection {
+	return Database.Collection("product_alert_deliveries")
+}
+
+func getAlertQuotaCollection() *mongo.Collection {
+	return Database.Collection("product_alert_quotas")
+}
+
+func getNotificationCollection() *mongo.Collection {
+	return Database.Collection("notifications")
+}
+
+// SubscribeProductAlert subscribes the customer to a restock or price drop
+// alert. Subscribing again reactivates the alert and updates the target.
+func SubscribeProductAlert(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	productID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request struct {
+		Type        string       `json:"type"`
+		TargetPrice Models.Money `json:"target_price"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if request.Type != AlertRestock && request.Type != AlertPriceDrop {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be restock or price_drop"})
+		return
+	}
+	if request.TargetPrice.Sign() < 0 || (request.Type == AlertRestock && request.TargetPrice.Sign() != 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target price only applies to price drop alerts and cannot be negative"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var product Models.Product
+	if err := getProductCollection().FindOne(ctx, bson.M{"_id": productID}).Decode(&product); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if request.Type == AlertRestock && product.Stock > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product is in stock"})
+		return
+	}
+
+	filter := bson.M{"user_id": claims.ID, "product_id": productID, "type": request.Type}
+	update := bson.M{
+		"$set": bson.M{
+			"target_price": request.TargetPrice,
+			"active":       true,
+			"updated_at":   time.Now(),
+		},
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var alert Models.ProductAlert
+	if err := getProductAlertCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&alert); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func GetMyProductAlerts(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alerts := []Models.ProductAlert{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := getProductAlertCollection().Find(ctx, bson.M{"user_id": claims.ID, "active": true}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alerts"})
+		return
+	}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+func DeleteProductAlert(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getProductAlertCollection().DeleteOne(context.Background(), bson.M{"_id": id, "user_id": claims.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func GetMyNotifications(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	notifications := []Models.Notification{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(50)
+	cursor, err := getNotificationCollection().Find(ctx, bson.M{"user_id": claims.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode notifications"})
+		return
+	}
+	unread, err := getNotificationCollection().CountDocuments(ctx, bson.M{"user_id": claims.ID, "read": false})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread": unread})
+}
+
+// MarkNotificationsRead marks the given notifications, or all of them when
+// no IDs are sent, as read.
+func MarkNotificationsRead(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		IDs []primitive.ObjectID `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	filter := bson.M{"user_id": claims.ID, "read": false}
+	if len(request.IDs) > 0 {
+		filter["_id"] = bson.M{"$in": request.IDs}
+	}
+	if _, err := getNotificationCollection().UpdateMany(context.Background(), filter, bson.M{"$set": bson.M{"read": true}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read"})
+}
+
+// emitProductEvents records the changes between two versions of a product
+// that alerts are waiting for: coming back in stock and a lower price.
+func emitProductEvents(ctx context.Context, before, after Models.Product) error {
+	var events []interface{}
+	newEvent := func(eventType string) Models.ProductEvent {
+		return Models.ProductEvent{
+			ID:        primitive.NewObjectID(),
+			Type:      eventType,
+			ProductID: after.ID,
+			OldPrice:  before.Price,
+			NewPrice:  after.Price,
+			OldStock:  before.Stock,
+			NewStock:  after.Stock,
+			Status:    productEventPending,
+			CreatedAt: time.Now(),
+		}
+	}
+	if before.Stock <= 0 && after.Stock > 0 {
+		events = append(events, newEvent(ProductEventRestocked))
+	}
+	if after.Price.Cmp(before.Price) < 0 {
+		events = append(events, newEvent(ProductEventPriceDropped))
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := getProductEventCollection().InsertMany(ctx, events)
+	return err
+}
+
+// RunProductAlertNotifier turns pending product events into alert deliveries
+// and sends the deliveries that are due, until the context is cancelled.
+func RunProductAlertNotifier(ctx context.Context) {
+	ticker := time.NewTicker(productAlertInterval)
+	defer ticker.Stop()
+
+	for {
+		processProductEvents(ctx)
+		processAlertDeliveries(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func processProductEvents(ctx context.Context) {
+	for {
+		event, err := claimProductEvent(ctx)
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Println("Error claiming product event:", err)
+			return
+		}
+
+		if err := queueAlertDeliveries(ctx, event); err != nil {
+			log.Println("Error queueing product alerts:", err)
+			continue
+		}
+		if _, err := getProductEventCollection().UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{"$set": bson.M{"status": productEventDone}}); err != nil {
+			log.Println("Error completing product event:", err)
+		}
+	}
+}
+
+// claimProductEvent takes the oldest pending event, or one whose previous
+// claim was abandoned, so that several server instances never deliver the
+// same event at the same time.
+func claimProductEvent(ctx context.Context) (Models.ProductEvent, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": productEventPending},
+		bson.M{"status": productEventProcessing, "claimed_at": bson.M{"$lt": time.Now().Add(-productEventClaimTTL)}},
+	}}
+	update := bson.M{"$set": bson.M{"status": productEventProcessing, "claimed_at": time.Now()}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetReturnDocument(options.After)
+	var event Models.ProductEvent
+	err := getProductEventCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+	return event, err
+}
+
+// queueAlertDeliveries records one delivery for every alert the event
+// matches. Nothing is sent here: the event is done once its deliveries are
+// stored, and each delivery is then sent and retried on its own. The unique
+// index on alert and event makes queueing an event twice harmless.
+func queueAlertDeliveries(ctx context.Context, event Models.ProductEvent) error {
+	var product Models.Product
+	if err := getProductCollection().FindOne(ctx, bson.M{"_id": event.ProductID}).Decode(&product); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	filter := bson.M{"product_id": event.ProductID, "active": true}
+	switch event.Type {
+	case ProductEventRestocked:
+		if product.Stock <= 0 {
+			return nil
+		}
+		filter["type"] = AlertRestock
+	case ProductEventPriceDropped:
+		filter["type"] = AlertPriceDrop
+		filter["$or"] = bson.A{
+			bson.M{"target_price": bson.M{"$exists": false}},
+			bson.M{"target_price": 0},
+			bson.M{"target_price": bson.M{"$gte": event.NewPrice}},
+		}
+	default:
+		return nil
+	}
+
+	var alerts []Models.ProductAlert
+	cursor, err := getProductAlertCollection().Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	deliveries := make([]interface{}, 0, len(alerts))
+	for _, alert := range alerts {
+		deliveries = append(deliveries, Models.ProductAlertDelivery{
+			ID:            primitive.NewObjectID(),
+			AlertID:       alert.ID,
+			EventID:       event.ID,
+			UserID:        alert.UserID,
+			Status:        alertDeliveryPending,
+			NextAttemptAt: time.Now(),
+			CreatedAt:     time.Now(),
+		})
+	}
+	_, err = getAlertDeliveryCollection().InsertMany(ctx, deliveries, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
+		return err
+	}
+	return nil
+}
+
+// onlyDuplicateKeys reports whether every write in a failed bulk insert was
+// rejected as a duplicate, meaning the documents were already there.
+func onlyDuplicateKeys(err error) bool {
+	var bulk mongo.BulkWriteException
+	if !errors.As(err, &bulk) || bulk.WriteConcernError != nil || len(bulk.WriteErrors) == 0 {
+		return false
+	}
+	for _, writeErr := range bulk.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+func processAlertDeliveries(ctx context.Context) {
+	for {
+		delivery, err := claimAlertDelivery(ctx)
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Println("Error claiming product alert:", err)
+			return
+		}
+
+		if err := sendAlertDelivery(ctx, delivery); err != nil {
+			log.Println("Error sending product alert:", err)
+			if err := retryAlertDelivery(ctx, delivery, err); err != nil {
+				log.Println("Error rescheduling product alert:", err)
+			}
+		}
+	}
+}
+
+// claimAlertDelivery takes the delivery that has been due the longest, or
+// one whose previous claim was abandoned.
+func claimAlertDelivery(ctx context.Context) (Models.ProductAlertDelivery, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": alertDeliveryPending, "next_attempt_at": bson.M{"$lte": time.Now()}},
+		bson.M{"status": alertDeliveryProcessing, "claimed_at": bson.M{"$lt": time.Now().Add(-productEventClaimTTL)}},
+	}}
+	update := bson.M{"$set": bson.M{"status": alertDeliveryProcessing, "claimed_at": time.Now()}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).SetReturnDocument(options.After)
+	var delivery Models.ProductAlertDelivery
+	err := getAlertDeliveryCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	return delivery, err
+}
+
+// sendAlertDelivery sends one claimed delivery by email and in-app. An alert
+// that was switched off, already sent for this event or is within its
+// cooldown is skipped. Customers over their daily limit have the delivery
+// put off until the next day. The alert only records the event, and one-off
+// alerts only switch themselves off, once sending succeeded; a returned error
+// leaves both untouched for the retry.
+func sendAlertDelivery(ctx context.Context, delivery Models.ProductAlertDelivery) error {
+	var alert Models.ProductAlert
+	if err := getProductAlertCollection().FindOne(ctx, bson.M{"_id": delivery.AlertID}).Decode(&alert); err != nil {
+		return skipMissingAlert(ctx, delivery, err)
+	}
+	var event Models.ProductEvent
+	if err := getProductEventCollection().FindOne(ctx, bson.M{"_id": delivery.EventID}).Decode(&event); err != nil {
+		return skipMissingAlert(ctx, delivery, err)
+	}
+	var product Models.Product
+	if err := getProductCollection().FindOne(ctx, bson.M{"_id": event.ProductID}).Decode(&product); err != nil {
+		return skipMissingAlert(ctx, delivery, err)
+	}
+	if !alertStillWanted(alert, event, product, time.Now()) {
+		return finishAlertDelivery(ctx, delivery, alertDeliverySkipped)
+	}
+
+	// The alert is locked while it is being sent so a delivery for another
+	// event of the same alert waits instead of notifying the customer twice.
+	lock := bson.M{
+		"_id":    alert.ID,
+		"active": true,
+		"$or": bson.A{
+			bson.M{"sending_until": bson.M{"$exists": false}},
+			bson.M{"sending_until": bson.M{"$lt": time.Now()}},
+		},
+	}
+	locked, err := getProductAlertCollection().UpdateOne(ctx, lock, bson.M{"$set": bson.M{"sending_until": time.Now().Add(productEventClaimTTL)}})
+	if err != nil {
+		return err
+	}
+	if locked.ModifiedCount == 0 {
+		return deferAlertDelivery(ctx, delivery, time.Now().Add(productAlertInterval))
+	}
+	unlock := func() {
+		if _, err := getProductAlertCollection().UpdateOne(ctx, bson.M{"_id": alert.ID}, bson.M{"$unset": bson.M{"sending_until": ""}}); err != nil {
+			log.Println("Error unlocking product alert:", err)
+		}
+	}
+
+	now := time.Now()
+	reserved, err := reserveAlertQuota(ctx, alert.UserID, now)
+	if err != nil {
+		unlock()
+		return err
+	}
+	if !reserved {
+		unlock()
+		return deferAlertDelivery(ctx, delivery, nextAlertDay(now))
+	}
+
+	if err := sendProductAlert(ctx, delivery, alert, event, product); err != nil {
+		if releaseErr := releaseAlertQuota(ctx, alert.UserID, now); releaseErr != nil {
+			log.Println("Error releasing product alert quota:", releaseErr)
+		}
+		unlock()
+		return err
+	}
+
+	set := bson.M{"last_event_id": event.ID, "last_notified_at": time.Now(), "updated_at": time.Now()}
+	if alert.Type == AlertRestock || alert.TargetPrice.Sign() > 0 {
+		set["active"] = false
+	}
+	update := bson.M{"$set": set, "$unset": bson.M{"sending_until": ""}}
+	if _, err := getProductAlertCollection().UpdateOne(ctx, bson.M{"_id": alert.ID}, update); err != nil {
+		log.Println("Error recording product alert:", err)
+	}
+	return finishAlertDelivery(ctx, delivery, alertDeliverySent)
+}
+
+// skipMissingAlert skips a delivery whose alert, event or product has been
+// deleted, and returns any other lookup error to be retried.
+func skipMissingAlert(ctx context.Context, delivery Models.ProductAlertDelivery, err error) error {
+	if err == mongo.ErrNoDocuments {
+		return finishAlertDelivery(ctx, delivery, alertDeliverySkipped)
+	}
+	return err
+}
+
+// alertStillWanted checks the alert against the event again at send time,
+// since the customer may have changed or switched it off since it was queued.
+func alertStillWanted(alert Models.ProductAlert, event Models.ProductEvent, product Models.Product, now time.Time) bool {
+	if !alert.Active || alert.LastEventID == event.ID {
+		return false
+	}
+	if !alert.LastNotifiedAt.IsZero() && now.Sub(alert.LastNotifiedAt) < productAlertCooldown {
+		return false
+	}
+	switch alert.Type {
+	case AlertRestock:
+		return product.Stock > 0
+	case AlertPriceDrop:
+		return alert.TargetPrice.Sign() == 0 || event.NewPrice.Cmp(alert.TargetPrice) <= 0
+	}
+	return false
+}
+
+// alertQuotaID names the counter of alerts sent to a customer on one
+// calendar day in the shop's time zone.
+func alertQuotaID(userID primitive.ObjectID, now time.Time) string {
+	return userID.Hex() + ":" + now.In(bookingLocation).Format("2006-01-02")
+}
+
+// reserveAlertQuota counts one alert against the customer's daily limit and
+// reports whether there was room. The increment only matches a counter below
+// the limit, and at the limit the upsert collides with the existing counter,
+// so concurrent senders cannot go over it.
+func reserveAlertQuota(ctx context.Context, userID primitive.ObjectID, now time.Time) (bool, error) {
+	filter := bson.M{"_id": alertQuotaID(userID, now), "count": bson.M{"$lt": productAlertDailyLimit}}
+	update := bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"user_id": userID, "created_at": now}}
+	_, err := getAlertQuotaCollection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// releaseAlertQuota gives back an alert that was counted but not sent.
+func releaseAlertQuota(ctx context.Context, userID primitive.ObjectID, now time.Time) error {
+	filter := bson.M{"_id": alertQuotaID(userID, now), "count": bson.M{"$gt": 0}}
+	_, err := getAlertQuotaCollection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"count": -1}})
+	return err
+}
+
+// nextAlertDay is midnight after now, when the daily limit starts over.
+func nextAlertDay(now time.Time) time.Time {
+	local := now.In(bookingLocation)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, bookingLocation)
+}
+
+// retryAlertDelivery puts a delivery that failed back in the queue, waiting
+// longer after every attempt, and gives up after productAlertMaxAttempts.
+func retryAlertDelivery(ctx context.Context, delivery Models.ProductAlertDelivery, cause error) error {
+	attempts := delivery.Attempts + 1
+	set := bson.M{
+		"status":          alertDeliveryPending,
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": time.Now().Add(time.Duration(attempts) * productAlertRetryDelay),
+	}
+	if attempts >= productAlertMaxAttempts {
+		set["status"] = alertDeliveryFailed
+	}
+	_, err := getAlertDeliveryCollection().UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": set})
+	return err
+}
+
+// deferAlertDelivery puts a delivery back in the queue until a later time
+// without counting it as a failed attempt.
+func deferAlertDelivery(ctx context.Context, delivery Models.ProductAlertDelivery, until time.Time) error {
+	update := bson.M{"$set": bson.M{"status": alertDeliveryPending, "next_attempt_at": until}}
+	_, err := getAlertDeliveryCollection().UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	return err
+}
+
+func finishAlertDelivery(ctx context.Context, delivery Models.ProductAlertDelivery, status string) error {
+	_, err := getAlertDeliveryCollection().UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": bson.M{"status": status}})
+	return err
+}
+
+// sendProductAlert writes the in-app notification and emails the customer.
+// The notification takes the delivery's ID, so a retry after the email failed
+// does not show it twice.
+func sendProductAlert(ctx context.Context, delivery Models.ProductAlertDelivery, alert Models.ProductAlert, event Models.ProductEvent, product Models.Product) error {
+	notification := Models.Notification{
+		ID:        delivery.ID,
+		UserID:    alert.UserID,
+		Type:      alert.Type,
+		Link:      clientURL("/product/" + product.ID.Hex()),
+		ProductID: product.ID,
+		CreatedAt: time.Now(),
+	}
+	if alert.Type == AlertRestock {
+		notification.Title = product.Name + " is back in stock"
+		notification.Body = fmt.Sprintf("%s is available again, %d left.", product.Name, product.Stock)
+	} else {
+		notification.Title = product.Name + " is now cheaper"
+		notification.Body = fmt.Sprintf("%s dropped from %s to %s.", product.Name, event.OldPrice, event.NewPrice)
+	}
+	if _, err := getNotificationCollection().InsertOne(ctx, notification); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	var user Models.User
+	if err := getUserCollection().FindOne(ctx, bson.M{"_id": alert.UserID}).Decode(&user); err != nil || user.Email == "" {
+		return err
+	}
+	var body bytes.Buffer
+	if err := productAlertTemplate.Execute(&body, gin.H{"Notification": notification, "Product": product}); err != nil {
+		return err
+	}
+	if err := sendMail(user.Email, notification.Title, body.String()); err != nil && err != errMailerNotConfigured {
+		return err
+	}
+	return nil
+}
+
+var productAlertTemplate = template.Must(template.New("product-alert").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222;">
+<h2>{{.Notification.Title}}</h2>
+{{if .Product.ImageURL}}<p><img src="{{.Product.ImageURL}}" alt="{{.Product.Name}}" style="max-width: 240px;"></p>{{end}}
+<p>{{.Notification.Body}}</p>
+<p><a href="{{.Notification.Link}}">View {{.Product.Name}}</a></p>
+<p style="color: #888; font-size: 12px;">You are receiving this because you asked to be notified about this product.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,233 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"Server/Middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// updatesTo returns the update documents sent to one collection.
+func updatesTo(mt *mtest.T, collection string) []bson.Raw {
+	var updates []bson.Raw
+	for _, command := range updatesSent(mt) {
+		if command.Lookup("update").StringValue() == collection {
+			updates = append(updates, command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document())
+		}
+	}
+	return updates
+}
+
+func okUpdate() bson.D {
+	return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+}
+
+func foundAndModified(doc interface{}) bson.D {
+	return bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: doc}}
+}
+
+// dueDelivery queues the responses that take a restock delivery up to the
+// point where the daily limit is checked.
+func dueDelivery(mt *mtest.T, deliveryID, alertID, eventID, productID, userID primitive.ObjectID) {
+	mt.AddMockResponses(
+		foundAndModified(bson.D{
+			{Key: "_id", Value: deliveryID},
+			{Key: "alert_id", Value: alertID},
+			{Key: "event_id", Value: eventID},
+			{Key: "user_id", Value: userID},
+			{Key: "status", Value: alertDeliveryProcessing},
+		}),
+		mtest.CreateCursorResponse(0, "test.product_alerts", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: alertID},
+			{Key: "user_id", Value: userID},
+			{Key: "product_id", Value: productID},
+			{Key: "type", Value: AlertRestock},
+			{Key: "active", Value: true},
+		}),
+		mtest.CreateCursorResponse(0, "test.product_events", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: eventID},
+			{Key: "type", Value: ProductEventRestocked},
+			{Key: "product_id", Value: productID},
+		}),
+		mtest.CreateCursorResponse(0, "test.products", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: productID},
+			{Key: "name", Value: "Mop"},
+			{Key: "stock", Value: 2},
+		}),
+		okUpdate(),
+	)
+}
+
+func TestProcessAlertDeliveriesRetriesFailedSends(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("notification insert fails", func(mt *mtest.T) {
+		Database = mt.DB
+		deliveryID, alertID, eventID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		dueDelivery(mt, deliveryID, alertID, eventID, primitive.NewObjectID(), primitive.NewObjectID())
+		mt.AddMockResponses(
+			okUpdate(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}),
+			okUpdate(),
+			okUpdate(),
+			okUpdate(),
+			foundAndModified(nil),
+		)
+
+		processAlertDeliveries(context.Background())
+
+		alertUpdates := updatesTo(mt, "product_alerts")
+		for _, update := range alertUpdates {
+			if _, err := update.LookupErr("$set", "active"); err == nil {
+				mt.Fatal("a failed send must not switch the alert off")
+			}
+			if _, err := update.LookupErr("$set", "last_event_id"); err == nil {
+				mt.Fatal("a failed send must not mark the event as sent")
+			}
+		}
+		if len(alertUpdates) != 2 {
+			mt.Fatalf("alert updates = %d, want the lock and the unlock", len(alertUpdates))
+		}
+
+		quotaUpdates := updatesTo(mt, "product_alert_quotas")
+		if len(quotaUpdates) != 2 || quotaUpdates[1].Lookup("$inc", "count").AsInt64() != -1 {
+			mt.Fatalf("quota updates = %v, want the reservation given back", quotaUpdates)
+		}
+
+		deliveryUpdates := updatesTo(mt, "product_alert_deliveries")
+		if len(deliveryUpdates) != 1 {
+			mt.Fatalf("delivery updates = %d, want 1", len(deliveryUpdates))
+		}
+		retry := deliveryUpdates[0]
+		if status := retry.Lookup("$set", "status").StringValue(); status != alertDeliveryPending {
+			mt.Errorf("status = %q, want %q", status, alertDeliveryPending)
+		}
+		if attempts := retry.Lookup("$set", "attempts").AsInt64(); attempts != 1 {
+			mt.Errorf("attempts = %d, want 1", attempts)
+		}
+		if next := retry.Lookup("$set", "next_attempt_at").Time(); !next.After(time.Now()) {
+			mt.Errorf("next attempt = %v, want a later time", next)
+		}
+	})
+}
+
+func TestProcessAlertDeliveriesDefersOverTheDailyLimit(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("limit reached", func(mt *mtest.T) {
+		Database = mt.DB
+		deliveryID, alertID, eventID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		dueDelivery(mt, deliveryID, alertID, eventID, primitive.NewObjectID(), primitive.NewObjectID())
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+			okUpdate(),
+			okUpdate(),
+			foundAndModified(nil),
+		)
+
+		processAlertDeliveries(context.Background())
+
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "insert" {
+				mt.Fatal("nothing should be sent over the daily limit")
+			}
+		}
+		deliveryUpdates := updatesTo(mt, "product_alert_deliveries")
+		if len(deliveryUpdates) != 1 {
+			mt.Fatalf("delivery updates = %d, want 1", len(deliveryUpdates))
+		}
+		deferred := deliveryUpdates[0]
+		if status := deferred.Lookup("$set", "status").StringValue(); status != alertDeliveryPending {
+			mt.Errorf("status = %q, want %q", status, alertDeliveryPending)
+		}
+		if _, err := deferred.LookupErr("$set", "attempts"); err == nil {
+			mt.Error("deferring must not count as a failed attempt")
+		}
+		want := nextAlertDay(time.Now())
+		if next := deferred.Lookup("$set", "next_attempt_at").Time(); !next.Equal(want) {
+			mt.Errorf("next attempt = %v, want %v", next, want)
+		}
+	})
+}
+
+func TestNextAlertDay(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, bookingLocation)
+	want := time.Date(2026, 4, 1, 0, 0, 0, 0, bookingLocation)
+	if got := nextAlertDay(now); !got.Equal(want) {
+		t.Fatalf("nextAlertDay = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateProductEmitsProductEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name  string
+		stock int
+		form  map[string]string
+		want  []string
+	}{
+		{name: "back in stock", stock: 0, form: map[string]string{"stock": "5"}, want: []string{ProductEventRestocked}},
+		{name: "cheaper", stock: 3, form: map[string]string{"price": "90000"}, want: []string{ProductEventPriceDropped}},
+		{name: "both", stock: 0, form: map[string]string{"stock": "2", "price": "90000"}, want: []string{ProductEventRestocked, ProductEventPriceDropped}},
+		{name: "more stock", stock: 3, form: map[string]string{"stock": "8"}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			Database = mt.DB
+			productID := primitive.NewObjectID()
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "test.products", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: productID},
+					{Key: "name", Value: "Mop"},
+					{Key: "price", Value: 100000},
+					{Key: "stock", Value: tt.stock},
+				}),
+				okUpdate(),
+				mtest.CreateSuccessResponse(),
+			)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			for key, value := range tt.form {
+				writer.WriteField(key, value)
+			}
+			writer.Close()
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPut, "/products/"+productID.Hex(), &body)
+			c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+			c.Params = gin.Params{{Key: "id", Value: productID.Hex()}}
+			c.Set("user", &Middleware.UserClaims{ID: primitive.NewObjectID(), Role: Middleware.Admin})
+			UpdateProduct(c)
+
+			if recorder.Code != http.StatusOK {
+				mt.Fatalf("status = %d, body %s", recorder.Code, recorder.Body.String())
+			}
+			var got []string
+			for _, started := range mt.GetAllStartedEvents() {
+				if started.CommandName == "insert" && started.Command.Lookup("insert").StringValue() == "product_events" {
+					values, _ := started.Command.Lookup("documents").Array().Values()
+					for _, value := range values {
+						got = append(got, value.Document().Lookup("type").StringValue())
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				mt.Fatalf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package Controllers
 
 import (
 	"context"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"strconv"
@@ -66,11 +67,14 @@ func CreateProduct(c *gin.Context) {
 
 	product.ImageURL = url
 	product.Name = c.PostForm("name")
-	product.Price, _ = strconv.ParseFloat(c.PostForm("price"), 64)
+	price, _ := strconv.ParseFloat(c.PostForm("price"), 64)
+	product.Price = Models.NewMoney(price)
 	product.Stock, _ = strconv.Atoi(c.PostForm("stock"))
+	product.Weight, _ = strconv.Atoi(c.PostForm("weight"))
 	product.ProductCategory, _ = primitive.ObjectIDFromHex(c.PostForm("productcategory"))
+	product.TaxClass = c.PostForm("tax_class")
 
-	if product.Name == "" || product.Price <= 0 || product.Stock <= 0 {
+	if product.Name == "" || product.Price.Sign() <= 0 || product.Stock <= 0 || product.Weight < 0 {
 		c.JSON(400, gin.H{"error": "Invalid input"})
 		return
 	}
@@ -145,6 +149,7 @@ func UpdateProduct(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
+	before := existingProduct
 
 	err = c.Request.ParseMultipartForm(10 << 20)
 	if err != nil {
@@ -174,7 +179,7 @@ func UpdateProduct(c *gin.Context) {
 		existingProduct.Name = name
 	}
 	if price, err := strconv.ParseFloat(c.PostForm("price"), 64); err == nil && price > 0 {
-		existingProduct.Price = price
+		existingProduct.Price = Models.NewMoney(price)
 	}
 	if stock, err := strconv.Atoi(c.PostForm("stock")); err == nil && stock >= 0 {
 		existingProduct.Stock = stock
@@ -184,8 +189,14 @@ func UpdateProduct(c *gin.Context) {
 			existingProduct.ProductCategory = productCategory
 		}
 	}
+	if weight, err := strconv.Atoi(c.PostForm("weight")); err == nil && weight >= 0 {
+		existingProduct.Weight = weight
+	}
+	if class := c.PostForm("tax_class"); class != "" {
+		existingProduct.TaxClass = class
+	}
 
-	if existingProduct.Name == "" || existingProduct.Price <= 0 || existingProduct.Stock < 0 {
+	if existingProduct.Name == "" || existingProduct.Price.Sign() <= 0 || existingProduct.Stock < 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
@@ -197,6 +208,8 @@ func UpdateProduct(c *gin.Context) {
 			"stock":           existingProduct.Stock,
 			"productcategory": existingProduct.ProductCategory,
 			"imageurl":        existingProduct.ImageURL,
+			"weight":          existingProduct.Weight,
+			"tax_class":       existingProduct.TaxClass,
 		},
 	}
 
@@ -211,6 +224,10 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if err := emitProductEvents(context.Background(), before, existingProduct); err != nil {
+		log.Println("Error recording product events:", err)
+	}
+
 	c.JSON(http.StatusOK, existingProduct)
 }
 
@@ -0,0 +1,359 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	PromotionPercentage = "percentage"
+	PromotionFixed      = "fixed"
+	PromotionBuyXGetY   = "buy_x_get_y"
+)
+
+func getPromotionCollection() *mongo.Collection {
+	return Database.Collection("promotions")
+}
+
+func GetPromotions(c *gin.Context) {
+	var promotions []Models.Promotion
+	cursor, err := getPromotionCollection().Find(context.Background(), bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get promotions"})
+		return
+	}
+	if err := cursor.All(context.Background(), &promotions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetActivePromotions lists the promotions customers can currently benefit
+// from, for banners and flash sale countdowns.
+func GetActivePromotions(c *gin.Context) {
+	promotions, err := loadActivePromotions(context.Background(), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+func CreatePromotion(c *gin.Context) {
+	var promotion Models.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if promotion.Type == PromotionFixed {
+		promotion.Amount, promotion.Value = fixedDiscount(promotion.Amount, promotion.Value)
+	}
+	if err := validatePromotion(promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion.ID = primitive.NewObjectID()
+	promotion.CreatedAt = time.Now()
+	promotion.UpdatedAt = time.Now()
+
+	if _, err := getPromotionCollection().InsertOne(context.Background(), promotion); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+func UpdatePromotion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var promotion Models.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if promotion.Type == PromotionFixed {
+		promotion.Amount, promotion.Value = fixedDiscount(promotion.Amount, promotion.Value)
+	}
+	if err := validatePromotion(promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":               promotion.Name,
+		"description":        promotion.Description,
+		"type":               promotion.Type,
+		"target_type":        promotion.TargetType,
+		"product_categories": promotion.ProductCategories,
+		"service_categories": promotion.ServiceCategories,
+		"buy_quantity":       promotion.BuyQuantity,
+		"free_quantity":      promotion.FreeQuantity,
+		"value":              promotion.Value,
+		"amount":             promotion.Amount,
+		"max_discount":       promotion.MaxDiscount,
+		"min_spend":          promotion.MinSpend,
+		"with_order_hours":   promotion.WithOrderHours,
+		"starts_at":          promotion.StartsAt,
+		"ends_at":            promotion.EndsAt,
+		"priority":           promotion.Priority,
+		"active":             promotion.Active,
+		"updated_at":         time.Now(),
+	}}
+	result, err := getPromotionCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	promotion.ID = id
+	c.JSON(http.StatusOK, promotion)
+}
+
+func DeletePromotion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getPromotionCollection().DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func validatePromotion(promotion Models.Promotion) error {
+	if promotion.Name == "" {
+		return errors.New("Name is required")
+	}
+	switch promotion.TargetType {
+	case PaymentTargetOrder, PaymentTargetBooking:
+	default:
+		return errors.New("Target type must be order or booking")
+	}
+	switch promotion.Type {
+	case PromotionPercentage:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return errors.New("Percentage must be between 0 and 100")
+		}
+	case PromotionFixed:
+		if promotion.Amount.Sign() <= 0 {
+			return errors.New("Amount must be positive")
+		}
+	case PromotionBuyXGetY:
+		if promotion.TargetType != PaymentTargetOrder {
+			return errors.New("Buy X get Y promotions only apply to product orders")
+		}
+		if promotion.BuyQuantity <= 0 || promotion.FreeQuantity <= 0 {
+			return errors.New("Buy and free quantities must be positive")
+		}
+	default:
+		return errors.New("Type must be percentage, fixed or buy_x_get_y")
+	}
+	if promotion.WithOrderHours < 0 || (promotion.WithOrderHours > 0 && promotion.TargetType != PaymentTargetBooking) {
+		return errors.New("Order bundles only apply to bookings")
+	}
+	if promotion.MaxDiscount.Sign() < 0 || promotion.MinSpend.Sign() < 0 {
+		return errors.New("Limits cannot be negative")
+	}
+	if !promotion.EndsAt.IsZero() && promotion.EndsAt.Before(promotion.StartsAt) {
+		return errors.New("End date must be after start date")
+	}
+	return nil
+}
+
+// loadActivePromotions returns the enabled promotions whose window includes
+// now, highest priority first. An empty target type returns all of them.
+func loadActivePromotions(ctx context.Context, targetType string) ([]Models.Promotion, error) {
+	now := time.Now()
+	filter := bson.M{
+		"active": true,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"starts_at": bson.M{"$lte": now}}, bson.M{"starts_at": time.Time{}}}},
+			bson.M{"$or": bson.A{bson.M{"ends_at": bson.M{"$gte": now}}, bson.M{"ends_at": time.Time{}}}},
+		},
+	}
+	if targetType != "" {
+		filter["target_type"] = targetType
+	}
+
+	var promotions []Models.Promotion
+	cursor, err := getPromotionCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(promotions, func(i, j int) bool {
+		return promotions[i].Priority > promotions[j].Priority
+	})
+	return promotions, nil
+}
+
+// evaluatePromotions runs every active promotion against the cart in
+// priority order. Each promotion sees the amounts left by the ones before
+// it, and the returned cart carries the discounted amounts so a coupon can
+// be applied on top. Promotions that did not apply are reported with the
+// reason so the customer can see what they are missing.
+func evaluatePromotions(ctx context.Context, userID primitive.ObjectID, cart discountCart) (discountCart, []Models.DiscountLine, []Models.AppliedPromotion, error) {
+	promotions, err := loadActivePromotions(ctx, cart.TargetType)
+	if err != nil {
+		return cart, nil, nil, err
+	}
+
+	var lines []Models.DiscountLine
+	var applied []Models.AppliedPromotion
+	for _, promotion := range promotions {
+		promotionLines, reason, err := applyPromotion(ctx, userID, promotion, cart)
+		if err != nil {
+			return cart, nil, nil, err
+		}
+		result := Models.AppliedPromotion{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Reason:      reason,
+			Discount:    discountTotal(promotionLines),
+		}
+		result.Applied = result.Discount.Sign() > 0
+		applied = append(applied, result)
+		if !result.Applied {
+			continue
+		}
+
+		lines = append(lines, promotionLines...)
+		cart = applyDiscountLines(cart, promotionLines)
+	}
+	return cart, lines, applied, nil
+}
+
+// applyPromotion computes the discount lines of a single promotion and a
+// short explanation of the outcome.
+func applyPromotion(ctx context.Context, userID primitive.ObjectID, promotion Models.Promotion, cart discountCart) ([]Models.DiscountLine, string, error) {
+	var eligible []discountItem
+	for _, item := range cart.Items {
+		if item.Amount.Sign() > 0 && categoryScoped(promotion.ProductCategories, promotion.ServiceCategories, cart.TargetType, item.Category) {
+			eligible = append(eligible, item)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, "No eligible items", nil
+	}
+
+	eligibleTotal := discountCart{Items: eligible}.subtotal()
+	if eligibleTotal.Cmp(promotion.MinSpend) < 0 {
+		return nil, fmt.Sprintf("Spend %s more on eligible items", promotion.MinSpend.Sub(eligibleTotal)), nil
+	}
+
+	// Only a paid or completed order counts, so placing an order to unlock the
+	// bundle and cancelling it afterwards does not keep the discount.
+	if promotion.WithOrderHours > 0 {
+		since := time.Now().Add(-time.Duration(promotion.WithOrderHours) * time.Hour)
+		count, err := getOrderCollection().CountDocuments(ctx, bson.M{
+			"user_id":    userID,
+			"status":     bson.M{"$nin": bson.A{OrderCancelled, OrderRefunded}},
+			"$or":        bson.A{bson.M{"payment_status": PaymentPaid}, bson.M{"status": OrderCompleted}},
+			"created_at": bson.M{"$gte": since},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if count == 0 {
+			return nil, fmt.Sprintf("Requires a paid product order placed within %d hours", promotion.WithOrderHours), nil
+		}
+	}
+
+	switch promotion.Type {
+	case PromotionBuyXGetY:
+		lines, reason := buyXGetYDiscount(promotion, eligible)
+		return lines, reason, nil
+	case PromotionPercentage:
+		discount := eligibleTotal.Percent(promotion.Value)
+		if promotion.MaxDiscount.Sign() > 0 {
+			discount = Models.MinMoney(discount, promotion.MaxDiscount)
+		}
+		return allocateDiscount("promotion", promotion.ID.Hex(), promotion.Name, discount, eligible), fmt.Sprintf("%.0f%% off %d eligible items", promotion.Value, len(eligible)), nil
+	default:
+		discount := Models.MinMoney(promotion.Amount, eligibleTotal)
+		return allocateDiscount("promotion", promotion.ID.Hex(), promotion.Name, discount, eligible), fmt.Sprintf("%s off eligible items", discount), nil
+	}
+}
+
+// buyXGetYDiscount pools the units of all eligible items and makes the
+// cheapest units of every complete group free, so mixing products within a
+// category still counts toward the deal.
+func buyXGetYDiscount(promotion Models.Promotion, eligible []discountItem) ([]Models.DiscountLine, string) {
+	var units []int
+	for i, item := range eligible {
+		for n := 0; n < item.Quantity; n++ {
+			units = append(units, i)
+		}
+	}
+
+	group := promotion.BuyQuantity + promotion.FreeQuantity
+	free := len(units) / group * promotion.FreeQuantity
+	if free == 0 {
+		return nil, fmt.Sprintf("Add %d more eligible items", group-len(units)%group)
+	}
+
+	// Unit prices are compared by cross-multiplying so nothing is rounded
+	// before the free units are picked.
+	sort.SliceStable(units, func(i, j int) bool {
+		a, b := eligible[units[i]], eligible[units[j]]
+		return a.Amount.Mul(b.Quantity).Cmp(b.Amount.Mul(a.Quantity)) < 0
+	})
+	freeUnits := make([]int, len(eligible))
+	for _, item := range units[:free] {
+		freeUnits[item]++
+	}
+
+	var lines []Models.DiscountLine
+	for i, count := range freeUnits {
+		if count == 0 {
+			continue
+		}
+		share := eligible[i].Amount.Share(Models.Minor(int64(count)), Models.Minor(int64(eligible[i].Quantity)))
+		if amount := Models.MinMoney(share, eligible[i].Amount); amount.Sign() > 0 {
+			lines = append(lines, Models.DiscountLine{
+				Source: "promotion",
+				Code:   promotion.ID.Hex(),
+				Label:  promotion.Name + " - " + eligible[i].Name,
+				ItemID: eligible[i].ID,
+				Amount: amount,
+			})
+		}
+	}
+	return lines, fmt.Sprintf("Buy %d get %d free: %d free items", promotion.BuyQuantity, promotion.FreeQuantity, free)
+}
@@ -0,0 +1,105 @@
+package Controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestOrderBundleRequiresPaidOrder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	promotion := Models.Promotion{
+		ID:             primitive.NewObjectID(),
+		Name:           "Clean after shopping",
+		Type:           PromotionPercentage,
+		TargetType:     PaymentTargetBooking,
+		Value:          10,
+		WithOrderHours: 48,
+	}
+	cart := discountCart{TargetType: PaymentTargetBooking, Items: []discountItem{{ID: primitive.NewObjectID(), Quantity: 1, Amount: Models.Minor(300000)}}}
+
+	mt.Run("no paid order", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.product_order", mtest.FirstBatch))
+
+		lines, reason, err := applyPromotion(context.Background(), primitive.NewObjectID(), promotion, cart)
+		if err != nil {
+			mt.Fatalf("applyPromotion: %v", err)
+		}
+		if len(lines) != 0 || !strings.Contains(reason, "paid product order") {
+			mt.Fatalf("lines = %+v, reason %q; want no discount without a paid order", lines, reason)
+		}
+
+		match := mt.GetStartedEvent().Command.Lookup("pipeline", "0", "$match").Document()
+		if _, err := match.LookupErr("$or"); err != nil {
+			mt.Fatalf("the order filter must require payment or completion, got %s", match)
+		}
+	})
+
+	mt.Run("count fails", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}))
+
+		if _, _, err := applyPromotion(context.Background(), primitive.NewObjectID(), promotion, cart); err == nil {
+			mt.Fatal("a failed order lookup must be reported, not treated as a missing order")
+		}
+	})
+}
+
+func TestBuyXGetYDiscount(t *testing.T) {
+	promotion := Models.Promotion{ID: primitive.NewObjectID(), Name: "3 for 2", BuyQuantity: 2, FreeQuantity: 1}
+	tests := []struct {
+		name  string
+		items []discountItem
+		want  []int64
+	}{
+		{
+			name:  "uneven unit price is rounded once",
+			items: []discountItem{{Name: "Cloth", Quantity: 3, Amount: Models.Minor(100001)}},
+			want:  []int64{33334},
+		},
+		{
+			name: "cheapest units are free",
+			items: []discountItem{
+				{Name: "Mop", Quantity: 2, Amount: Models.Minor(200000)},
+				{Name: "Sponge", Quantity: 4, Amount: Models.Minor(40000)},
+			},
+			want: []int64{20000},
+		},
+		{
+			name: "free units all come from the cheaper item",
+			items: []discountItem{
+				{Name: "Brush", Quantity: 1, Amount: Models.Minor(15000)},
+				{Name: "Sponge", Quantity: 5, Amount: Models.Minor(50000)},
+			},
+			want: []int64{20000},
+		},
+		{
+			name:  "incomplete group",
+			items: []discountItem{{Name: "Mop", Quantity: 2, Amount: Models.Minor(200000)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, _ := buyXGetYDiscount(promotion, tt.items)
+			var got []int64
+			for _, line := range lines {
+				got = append(got, line.Amount.MinorUnits())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("discounts = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("discounts = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
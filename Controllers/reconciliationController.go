@@ -0,0 +1,430 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	MismatchMissingInSettlement = "missing_in_settlement"
+	MismatchMissingInLedger     = "missing_in_ledger"
+	MismatchAmount              = "amount_mismatch"
+
+	reconciliationInterval = time.Hour
+)
+
+var settlementTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "20060102150405", "2006-01-02"}
+
+func getSettlementCollection() *mongo.Collection {
+	return Database.Collection("settlements")
+}
+
+func getReconciliationCollection() *mongo.Collection {
+	return Database.Collection("reconciliation_reports")
+}
+
+// ImportSettlement loads a provider settlement CSV with the columns
+// transaction_ref, provider_ref, type (capture or refund), amount and
+// settled_at, then reconciles every day the file covers.
+func ImportSettlement(c *gin.Context) {
+	provider := c.PostForm("provider")
+	if _, ok := paymentProvider(provider); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Settlement file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open file"})
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseSettlementCSV(file, provider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	dates := map[string]bool{}
+	var documents []interface{}
+	var refs []string
+	for _, row := range rows {
+		dates[row.Date] = true
+		documents = append(documents, row)
+		refs = append(refs, row.TransactionRef)
+	}
+	var imported []string
+	for date := range dates {
+		imported = append(imported, date)
+	}
+
+	// Re-importing a day replaces its rows instead of double counting them.
+	if _, err := getSettlementCollection().DeleteMany(ctx, bson.M{"provider": provider, "date": bson.M{"$in": imported}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import settlement"})
+		return
+	}
+	if len(documents) > 0 {
+		if _, err := getSettlementCollection().InsertMany(ctx, documents); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import settlement"})
+			return
+		}
+	}
+
+	// The days the settled transactions were recorded on are reconciled again
+	// too, so they stop reporting them as missing from the settlement.
+	var recorded []Models.LedgerEntry
+	cursor, err := getLedgerCollection().Find(ctx, bson.M{"provider": provider, "transaction_ref": bson.M{"$in": refs}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import settlement"})
+		return
+	}
+	if err := cursor.All(ctx, &recorded); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import settlement"})
+		return
+	}
+	for _, entry := range recorded {
+		dates[entry.CreatedAt.In(bookingLocation).Format("2006-01-02")] = true
+	}
+	var dateList []string
+	for date := range dates {
+		dateList = append(dateList, date)
+	}
+	sort.Strings(dateList)
+
+	reports := []Models.ReconciliationReport{}
+	for _, date := range dateList {
+		report, err := reconcileSettlement(ctx, provider, date)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile " + date})
+			return
+		}
+		reports = append(reports, report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rows": len(rows), "reports": reports})
+}
+
+func GetReconciliationReports(c *gin.Context) {
+	filter := bson.M{}
+	if provider := c.Query("provider"); provider != "" {
+		filter["provider"] = provider
+	}
+	if c.Query("mismatched") == "true" {
+		filter["mismatches.0"] = bson.M{"$exists": true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reports []Models.ReconciliationReport
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(100)
+	cursor, err := getReconciliationCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reconciliation reports"})
+		return
+	}
+	if err := cursor.All(ctx, &reports); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode reconciliation reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+func GetReconciliationReport(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var report Models.ReconciliationReport
+	if err := getReconciliationCollection().FindOne(context.Background(), bson.M{"_id": id}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation report not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RunReconciliationScheduler reconciles the previous day for every online
+// provider once per day, after the settlement files have had time to arrive.
+func RunReconciliationScheduler(ctx context.Context) {
+	ticker := time.NewTicker(reconciliationInterval)
+	defer ticker.Stop()
+
+	for {
+		reconcileYesterday(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reconcileYesterday(ctx context.Context) {
+	if err := flushLedgerOutbox(ctx); err != nil {
+		log.Println("Error flushing payment ledger:", err)
+	}
+
+	date := time.Now().In(bookingLocation).AddDate(0, 0, -1).Format("2006-01-02")
+	start, end := settlementDay(date)
+
+	providers := map[string]bool{}
+	ledgerProviders, err := getLedgerCollection().Distinct(ctx, "provider", bson.M{"created_at": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		log.Println("Error listing ledger providers:", err)
+		return
+	}
+	settlementProviders, err := getSettlementCollection().Distinct(ctx, "provider", bson.M{"date": date})
+	if err != nil {
+		log.Println("Error listing settlement providers:", err)
+		return
+	}
+	for _, provider := range append(ledgerProviders, settlementProviders...) {
+		if name, ok := provider.(string); ok && name != "cod" {
+			providers[name] = true
+		}
+	}
+
+	for provider := range providers {
+		count, err := getReconciliationCollection().CountDocuments(ctx, bson.M{"provider": provider, "date": date})
+		if err != nil || count > 0 {
+			continue
+		}
+		if _, err := reconcileSettlement(ctx, provider, date); err != nil {
+			log.Println("Error reconciling", provider, date, ":", err)
+		}
+	}
+}
+
+// reconcileSettlement reports on the transactions the provider settled on a
+// day, plus the ledger entries of that day that have not been settled on any
+// day. Amounts are summed per transaction and type across all days, so a
+// capture recorded late in the evening matches the settlement line of the
+// next day, and several partial refunds of one payment match a single
+// settlement line or several.
+func reconcileSettlement(ctx context.Context, provider, date string) (Models.ReconciliationReport, error) {
+	report := Models.ReconciliationReport{
+		Provider:   provider,
+		Date:       date,
+		Mismatches: []Models.ReconciliationMismatch{},
+		CreatedAt:  time.Now(),
+	}
+	start, end := settlementDay(date)
+	types := bson.M{"$in": []string{LedgerCapture, LedgerRefund}}
+
+	var settledToday []Models.SettlementRow
+	cursor, err := getSettlementCollection().Find(ctx, bson.M{"provider": provider, "date": date})
+	if err != nil {
+		return report, err
+	}
+	if err := cursor.All(ctx, &settledToday); err != nil {
+		return report, err
+	}
+
+	var recordedToday []Models.LedgerEntry
+	cursor, err = getLedgerCollection().Find(ctx, bson.M{"provider": provider, "type": types, "created_at": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		return report, err
+	}
+	if err := cursor.All(ctx, &recordedToday); err != nil {
+		return report, err
+	}
+
+	refs := []string{}
+	for _, row := range settledToday {
+		refs = append(refs, row.TransactionRef)
+	}
+	for _, entry := range recordedToday {
+		refs = append(refs, entry.TransactionRef)
+	}
+
+	var entries []Models.LedgerEntry
+	cursor, err = getLedgerCollection().Find(ctx, bson.M{"provider": provider, "type": types, "transaction_ref": bson.M{"$in": refs}})
+	if err != nil {
+		return report, err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return report, err
+	}
+	var rows []Models.SettlementRow
+	cursor, err = getSettlementCollection().Find(ctx, bson.M{"provider": provider, "transaction_ref": bson.M{"$in": refs}})
+	if err != nil {
+		return report, err
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return report, err
+	}
+
+	ledger := map[string]Models.Money{}
+	for _, entry := range entries {
+		key := entry.Type + "|" + entry.TransactionRef
+		ledger[key] = ledger[key].Add(entry.Amount)
+	}
+	settled := map[string]Models.Money{}
+	for _, row := range rows {
+		key := row.Type + "|" + row.TransactionRef
+		settled[key] = settled[key].Add(row.Amount)
+	}
+
+	keys := map[string]bool{}
+	for _, row := range settledToday {
+		keys[row.Type+"|"+row.TransactionRef] = true
+	}
+	for _, entry := range recordedToday {
+		key := entry.Type + "|" + entry.TransactionRef
+		if _, ok := settled[key]; !ok {
+			keys[key] = true
+		}
+	}
+
+	for key := range keys {
+		entryType, ref, _ := strings.Cut(key, "|")
+		ledgerAmount, recorded := ledger[key]
+		settledAmount, ok := settled[key]
+		report.LedgerTotal = report.LedgerTotal.Add(signedAmount(entryType, ledgerAmount))
+		report.SettledTotal = report.SettledTotal.Add(signedAmount(entryType, settledAmount))
+		switch {
+		case !ok:
+			report.Mismatches = append(report.Mismatches, Models.ReconciliationMismatch{Kind: MismatchMissingInSettlement, Type: entryType, TransactionRef: ref, LedgerAmount: ledgerAmount})
+		case !recorded:
+			report.Mismatches = append(report.Mismatches, Models.ReconciliationMismatch{Kind: MismatchMissingInLedger, Type: entryType, TransactionRef: ref, SettlementAmount: settledAmount})
+		case settledAmount != ledgerAmount:
+			report.Mismatches = append(report.Mismatches, Models.ReconciliationMismatch{Kind: MismatchAmount, Type: entryType, TransactionRef: ref, LedgerAmount: ledgerAmount, SettlementAmount: settledAmount})
+		default:
+			report.Matched++
+		}
+	}
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		if report.Mismatches[i].TransactionRef != report.Mismatches[j].TransactionRef {
+			return report.Mismatches[i].TransactionRef < report.Mismatches[j].TransactionRef
+		}
+		return report.Mismatches[i].Type < report.Mismatches[j].Type
+	})
+
+	var existing Models.ReconciliationReport
+	if err := getReconciliationCollection().FindOne(ctx, bson.M{"provider": provider, "date": date}).Decode(&existing); err == nil {
+		report.ID = existing.ID
+	} else {
+		report.ID = primitive.NewObjectID()
+	}
+	_, err = getReconciliationCollection().ReplaceOne(ctx, bson.M{"_id": report.ID}, report, options.Replace().SetUpsert(true))
+	return report, err
+}
+
+func parseSettlementCSV(r io.Reader, provider string) ([]Models.SettlementRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("Settlement file is empty")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"transaction_ref", "amount", "settled_at"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("Settlement file is missing the %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []Models.SettlementRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(field(record, "amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: invalid amount", line)
+		}
+		settledAt, err := parseSettlementTime(field(record, "settled_at"))
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: invalid settled_at", line)
+		}
+
+		entryType := strings.ToLower(field(record, "type"))
+		switch entryType {
+		case "", "payment", "capture":
+			entryType = LedgerCapture
+		case "refund":
+		default:
+			return nil, fmt.Errorf("Line %d: unknown type %q", line, entryType)
+		}
+
+		rows = append(rows, Models.SettlementRow{
+			ID:             primitive.NewObjectID(),
+			Provider:       provider,
+			Date:           settledAt.In(bookingLocation).Format("2006-01-02"),
+			Type:           entryType,
+			TransactionRef: field(record, "transaction_ref"),
+			ProviderRef:    field(record, "provider_ref"),
+			Amount:         Models.NewMoney(math.Abs(amount)),
+			SettledAt:      settledAt,
+			ImportedAt:     time.Now(),
+		})
+	}
+	return rows, nil
+}
+
+func parseSettlementTime(value string) (time.Time, error) {
+	for _, layout := range settlementTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, bookingLocation); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid time")
+}
+
+func settlementDay(date string) (time.Time, time.Time) {
+	start, _ := time.ParseInLocation("2006-01-02", date, bookingLocation)
+	return start, start.AddDate(0, 0, 1)
+}
+
+func signedAmount(entryType string, amount Models.Money) Models.Money {
+	if entryType == LedgerRefund {
+		return amount.Neg()
+	}
+	return amount
+}
@@ -0,0 +1,103 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestReconcileSettlementMatchesNextDaySettlement(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("t+1", func(mt *mtest.T) {
+		Database = mt.DB
+		day := time.Date(2030, 1, 8, 0, 0, 0, 0, bookingLocation)
+		ledgerEntry := func(ref string, amount int64, at time.Time) bson.D {
+			return bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "provider", Value: "vnpay"},
+				{Key: "type", Value: LedgerCapture},
+				{Key: "transaction_ref", Value: ref},
+				{Key: "amount", Value: amount},
+				{Key: "created_at", Value: at},
+			}
+		}
+		settlement := bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "provider", Value: "vnpay"},
+			{Key: "date", Value: "2030-01-08"},
+			{Key: "type", Value: LedgerCapture},
+			{Key: "transaction_ref", Value: "late-evening"},
+			{Key: "amount", Value: int64(150000)},
+		}
+		lateEvening := ledgerEntry("late-evening", 150000, day.Add(-10*time.Minute))
+		unsettled := ledgerEntry("unsettled", 80000, day.Add(9*time.Hour))
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.settlements", mtest.FirstBatch, settlement),
+			mtest.CreateCursorResponse(0, "test.payment_ledger", mtest.FirstBatch, unsettled),
+			mtest.CreateCursorResponse(0, "test.payment_ledger", mtest.FirstBatch, lateEvening, unsettled),
+			mtest.CreateCursorResponse(0, "test.settlements", mtest.FirstBatch, settlement),
+			mtest.CreateCursorResponse(0, "test.reconciliation_reports", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		report, err := reconcileSettlement(context.Background(), "vnpay", "2030-01-08")
+		if err != nil {
+			mt.Fatalf("reconcileSettlement: %v", err)
+		}
+		if report.Matched != 1 {
+			mt.Fatalf("matched = %d, want the capture recorded the evening before", report.Matched)
+		}
+		want := Models.ReconciliationMismatch{Kind: MismatchMissingInSettlement, Type: LedgerCapture, TransactionRef: "unsettled", LedgerAmount: Models.Minor(80000)}
+		if len(report.Mismatches) != 1 || report.Mismatches[0] != want {
+			mt.Fatalf("mismatches = %+v, want only %+v", report.Mismatches, want)
+		}
+		if report.LedgerTotal != Models.Minor(230000) || report.SettledTotal != Models.Minor(150000) {
+			mt.Fatalf("totals = %s / %s, want 230000 / 150000", report.LedgerTotal, report.SettledTotal)
+		}
+	})
+}
+
+func TestFlushLedgerOutboxDeliversQueuedEntries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("flush", func(mt *mtest.T) {
+		Database = mt.DB
+		paymentID := primitive.NewObjectID()
+		delivered, queued := primitive.NewObjectID(), primitive.NewObjectID()
+		entry := func(id primitive.ObjectID) bson.D {
+			return bson.D{{Key: "_id", Value: id}, {Key: "payment_id", Value: paymentID}, {Key: "type", Value: LedgerCapture}}
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.payments", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: paymentID},
+				{Key: "ledger_outbox", Value: bson.A{entry(delivered), entry(queued)}},
+			}),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		if err := flushLedgerOutbox(context.Background()); err != nil {
+			mt.Fatalf("flushLedgerOutbox: %v", err)
+		}
+
+		var pulled []primitive.ObjectID
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				pulled = append(pulled, started.Command.Lookup("updates", "0", "u", "$pull", "ledger_outbox", "_id").ObjectID())
+			}
+		}
+		if len(pulled) != 2 || pulled[0] != delivered || pulled[1] != queued {
+			mt.Fatalf("pulled %v, want both entries, including the one already in the ledger", pulled)
+		}
+	})
+}
@@ -0,0 +1,420 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ReviewTargetProduct = "product"
+	ReviewTargetService = "service"
+
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+
+	maxReviewPhotos = 5
+)
+
+var errReviewExists = errors.New("You have already reviewed this item")
+
+func getReviewCollection() *mongo.Collection {
+	return Database.Collection("reviews")
+}
+
+// CreateReview records a pending review of a product or service. Customers
+// can review an item once, and only after an order or booking containing it
+// has completed.
+func CreateReview(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse multipart form"})
+		return
+	}
+
+	targetType := c.PostForm("target_type")
+	if targetType != ReviewTargetProduct && targetType != ReviewTargetService {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target type must be product or service"})
+		return
+	}
+	targetID, err := primitive.ObjectIDFromHex(c.PostForm("target_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+	rating, err := strconv.Atoi(c.PostForm("rating"))
+	if err != nil || rating < 1 || rating > 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+		return
+	}
+	comment := strings.TrimSpace(c.PostForm("comment"))
+	photoFiles := c.Request.MultipartForm.File["photos"]
+	if len(photoFiles) > maxReviewPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A review can have at most 5 photos"})
+		return
+	}
+	if err := checkFormImages(photoFiles); err != nil {
+		if err == errUnsupportedImage {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read photo"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	purchased, err := hasCompletedPurchase(ctx, claims.ID, targetType, targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check purchase history"})
+		return
+	}
+	if !purchased {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only review items from a completed order or booking"})
+		return
+	}
+
+	// A rejected review can be resubmitted, and the resubmission replaces it.
+	var previous Models.Review
+	filter := bson.M{"user_id": claims.ID, "target_type": targetType, "target_id": targetID}
+	err = getReviewCollection().FindOne(ctx, filter).Decode(&previous)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing reviews"})
+		return
+	}
+	if err == nil && previous.Status != ReviewRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": errReviewExists.Error()})
+		return
+	}
+
+	photos, err := uploadFormImages(photoFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not upload image to Cloudinary"})
+		return
+	}
+
+	review := Models.Review{
+		ID:         primitive.NewObjectID(),
+		TargetType: targetType,
+		TargetID:   targetID,
+		UserID:     claims.ID,
+		Rating:     rating,
+		Comment:    comment,
+		Photos:     photos,
+		Status:     ReviewPending,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	var user Models.User
+	if err := getUserCollection().FindOne(ctx, bson.M{"_id": claims.ID}).Decode(&user); err == nil {
+		review.UserName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	if err := saveReview(ctx, previous, &review); err != nil {
+		if err == errReviewExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// saveReview inserts a new review, or replaces the customer's rejected review
+// of the item. The unique index on customer and item, and the status in the
+// replace filter, stop concurrent submissions from creating a second review.
+func saveReview(ctx context.Context, previous Models.Review, review *Models.Review) error {
+	if previous.ID.IsZero() {
+		_, err := getReviewCollection().InsertOne(ctx, review)
+		if mongo.IsDuplicateKeyError(err) {
+			return errReviewExists
+		}
+		return err
+	}
+
+	review.ID = previous.ID
+	result, err := getReviewCollection().ReplaceOne(ctx, bson.M{"_id": previous.ID, "status": ReviewRejected}, *review)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errReviewExists
+	}
+	return nil
+}
+
+// GetReviews lists the approved reviews of a product or service, newest
+// first or most helpful first.
+func GetReviews(c *gin.Context) {
+	targetType := c.Query("target_type")
+	if targetType != ReviewTargetProduct && targetType != ReviewTargetService {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target type must be product or service"})
+		return
+	}
+	targetID, err := primitive.ObjectIDFromHex(c.Query("target_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+
+	sort := bson.D{{Key: "created_at", Value: -1}}
+	switch c.DefaultQuery("sort", "newest") {
+	case "newest":
+	case "helpful":
+		sort = bson.D{{Key: "helpful_count", Value: -1}, {Key: "created_at", Value: -1}}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sort must be newest or helpful"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"target_type": targetType, "target_id": targetID, "status": ReviewApproved}
+	total, err := getReviewCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reviews"})
+		return
+	}
+
+	reviews := []Models.Review{}
+	opts := options.Find().SetSort(sort).SetSkip(int64((page - 1) * limit)).SetLimit(int64(limit))
+	cursor, err := getReviewCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reviews"})
+		return
+	}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "total": total, "page": page, "limit": limit})
+}
+
+// GetModerationReviews is the staff moderation queue, pending reviews by
+// default.
+func GetModerationReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", ReviewPending)
+	if status != ReviewPending && status != ReviewApproved && status != ReviewRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reviews []Models.Review
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(500)
+	cursor, err := getReviewCollection().Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reviews"})
+		return
+	}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// ModerateReview approves or rejects a review and refreshes the rating of
+// the reviewed item.
+func ModerateReview(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if request.Status != ReviewApproved && request.Status != ReviewRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be approved or rejected"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var review Models.Review
+	update := bson.M{"$set": bson.M{
+		"status":          request.Status,
+		"moderated_by":    claims.ID,
+		"moderation_note": strings.TrimSpace(request.Note),
+		"updated_at":      time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := getReviewCollection().FindOneAndUpdate(ctx, bson.M{"_id": reviewID}, update, opts).Decode(&review); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	if err := refreshRating(ctx, review.TargetType, review.TargetID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rating"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// MarkReviewHelpful counts the customer's helpful vote once per review.
+func MarkReviewHelpful(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":            reviewID,
+		"status":         ReviewApproved,
+		"user_id":        bson.M{"$ne": claims.ID},
+		"helpful_voters": bson.M{"$ne": claims.ID},
+	}
+	update := bson.M{
+		"$addToSet": bson.M{"helpful_voters": claims.ID},
+		"$inc":      bson.M{"helpful_count": 1},
+	}
+	result, err := getReviewCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to vote"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot vote for this review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Marked as helpful"})
+}
+
+// DeleteReview lets customers remove their own review and staff remove any.
+func DeleteReview(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": reviewID}
+	if claims.Role == Middleware.Customer {
+		filter["user_id"] = claims.ID
+	}
+	var review Models.Review
+	if err := getReviewCollection().FindOneAndDelete(ctx, filter).Decode(&review); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	if review.Status == ReviewApproved {
+		if err := refreshRating(ctx, review.TargetType, review.TargetID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rating"})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// hasCompletedPurchase reports whether the user has a completed product
+// order containing the product, or a completed booking of the service.
+func hasCompletedPurchase(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	var count int64
+	var err error
+	if targetType == ReviewTargetProduct {
+		count, err = getOrderCollection().CountDocuments(ctx, bson.M{
+			"user_id":          userID,
+			"status":           "completed",
+			"items.product_id": targetID,
+		})
+	} else {
+		count, err = getOrderBookingServiceCollection().CountDocuments(ctx, bson.M{
+			"user_id":    userID,
+			"service_id": targetID,
+			"status":     BookingCompleted,
+		})
+	}
+	return count > 0, err
+}
+
+// refreshRating recomputes the average and count of the approved reviews of
+// an item and stores them on the product or service, so listings can show
+// ratings without reading reviews.
+func refreshRating(ctx context.Context, targetType string, targetID primitive.ObjectID) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"target_type": targetType, "target_id": targetID, "status": ReviewApproved}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "average": bson.M{"$avg": "$rating"}, "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := getReviewCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	var results []struct {
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return err
+	}
+
+	var average float64
+	var count int
+	if len(results) > 0 {
+		average = math.Round(results[0].Average*10) / 10
+		count = results[0].Count
+	}
+
+	collection := getProductCollection()
+	if targetType == ReviewTargetService {
+		collection = getServiceCollection()
+	}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": targetID}, bson.M{"$set": bson.M{"rating_average": average, "rating_count": count}})
+	return err
+}
@@ -0,0 +1,127 @@
+package Controllers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Server/Middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func reviewRequest(t *testing.T, userID, productID primitive.ObjectID, photos map[string][]byte) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("target_type", ReviewTargetProduct)
+	writer.WriteField("target_id", productID.Hex())
+	writer.WriteField("rating", "4")
+	writer.WriteField("comment", "Works well")
+	for name, data := range photos {
+		part, err := writer.CreateFormFile("photos", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	writer.Close()
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/reviews", &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	c.Set("user", &Middleware.UserClaims{ID: userID, Role: Middleware.Customer})
+	return c, recorder
+}
+
+func TestCreateReviewRejectsNonImagePhotos(t *testing.T) {
+	c, recorder := reviewRequest(t, primitive.NewObjectID(), primitive.NewObjectID(), map[string][]byte{
+		"photo.png": []byte("<html><script>alert(1)</script>"),
+	})
+
+	CreateReview(c)
+
+	if recorder.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status = %d, want 415 for an HTML file named .png", recorder.Code)
+	}
+}
+
+func TestCreateReviewReplacesARejectedReview(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	existing := func(status string) bson.D {
+		return bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "status", Value: status}}
+	}
+
+	mt.Run("rejected", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, productID := primitive.NewObjectID(), primitive.NewObjectID()
+		rejected := existing(ReviewRejected)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.product_order", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, "test.reviews", mtest.FirstBatch, rejected),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		c, recorder := reviewRequest(mt.T, userID, productID, nil)
+		CreateReview(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d (%s), want a resubmission after rejection to be accepted", recorder.Code, recorder.Body)
+		}
+		var replaced bool
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "insert" {
+				mt.Fatal("the resubmission must replace the rejected review, not add a second one")
+			}
+			if started.CommandName == "update" {
+				update := started.Command.Lookup("updates", "0").Document()
+				replaced = update.Lookup("q", "_id").ObjectID() == rejected[0].Value.(primitive.ObjectID) &&
+					update.Lookup("q", "status").StringValue() == ReviewRejected &&
+					update.Lookup("u", "status").StringValue() == ReviewPending
+			}
+		}
+		if !replaced {
+			mt.Fatal("the rejected review should be replaced by a pending one")
+		}
+	})
+
+	mt.Run("pending", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.product_order", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, "test.reviews", mtest.FirstBatch, existing(ReviewPending)),
+		)
+
+		c, recorder := reviewRequest(mt.T, primitive.NewObjectID(), primitive.NewObjectID(), nil)
+		CreateReview(c)
+
+		if recorder.Code != http.StatusConflict {
+			mt.Fatalf("status = %d, want 409 while the first review is still pending", recorder.Code)
+		}
+	})
+
+	mt.Run("concurrent insert", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.product_order", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, "test.reviews", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}),
+		)
+
+		c, recorder := reviewRequest(mt.T, primitive.NewObjectID(), primitive.NewObjectID(), nil)
+		CreateReview(c)
+
+		if recorder.Code != http.StatusConflict {
+			mt.Fatalf("status = %d, want 409 when the unique index rejects a second review", recorder.Code)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"Server/Middleware"
 	"Server/Models"
@@ -47,11 +48,15 @@ func CreateService(c *gin.Context) {
 	}
 
 	service.Name = c.PostForm("name")
-	service.Price, _ = strconv.ParseFloat(c.PostForm("price"), 64)
+	price, _ := strconv.ParseFloat(c.PostForm("price"), 64)
+	service.Price = Models.NewMoney(price)
 	service.Description = c.PostForm("description")
 	service.ServiceCategory, _ = primitive.ObjectIDFromHex(c.PostForm("servicecategory"))
+	service.DurationMinutes, _ = strconv.Atoi(c.PostForm("duration_minutes"))
+	service.SlotCapacity, _ = strconv.Atoi(c.PostForm("slot_capacity"))
+	service.TaxClass = c.PostForm("tax_class")
 
-	if service.Name == "" || service.Price <= 0 {
+	if service.Name == "" || service.Price.Sign() <= 0 || service.DurationMinutes < 0 || service.SlotCapacity < 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
@@ -156,7 +161,7 @@ func UpdateService(c *gin.Context) {
 		existingService.Name = name
 	}
 	if price, err := strconv.ParseFloat(c.PostForm("price"), 64); err == nil && price > 0 {
-		existingService.Price = price
+		existingService.Price = Models.NewMoney(price)
 	}
 	if description := c.PostForm("description"); description != "" {
 		existingService.Description = description
@@ -166,19 +171,31 @@ func UpdateService(c *gin.Context) {
 			existingService.ServiceCategory = serviceCategory
 		}
 	}
+	if duration, err := strconv.Atoi(c.PostForm("duration_minutes")); err == nil && duration > 0 {
+		existingService.DurationMinutes = duration
+	}
+	if capacity, err := strconv.Atoi(c.PostForm("slot_capacity")); err == nil && capacity >= 0 {
+		existingService.SlotCapacity = capacity
+	}
+	if class := c.PostForm("tax_class"); class != "" {
+		existingService.TaxClass = class
+	}
 
-	if existingService.Name == "" || existingService.Price <= 0 {
+	if existingService.Name == "" || existingService.Price.Sign() <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
 	update := bson.M{
 		"$set": bson.M{
-			"name":            existingService.Name,
-			"price":           existingService.Price,
-			"description":     existingService.Description,
-			"servicecategory": existingService.ServiceCategory,
-			"imageurl":        existingService.ImageURL,
+			"name":             existingService.Name,
+			"price":            existingService.Price,
+			"description":      existingService.Description,
+			"servicecategory":  existingService.ServiceCategory,
+			"imageurl":         existingService.ImageURL,
+			"duration_minutes": existingService.DurationMinutes,
+			"slot_capacity":    existingService.SlotCapacity,
+			"tax_class":        existingService.TaxClass,
 		},
 	}
 
@@ -224,3 +241,160 @@ func DeleteService(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func GetServiceAvailability(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	today := time.Now().In(bookingLocation)
+	from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, bookingLocation)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, bookingLocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 6)
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, bookingLocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, 30)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must be between 1 and 31 days"})
+		return
+	}
+	var hours float64
+	if value := c.Query("hours"); value != "" {
+		if hours, err = strconv.ParseFloat(value, 64); err != nil || hours < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hours"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var service Models.Service
+	if err := getServiceCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&service); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	days, err := serviceAvailability(ctx, service, hours, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id":       service.ID,
+		"duration_minutes": int(serviceDuration(service, hours) / time.Minute),
+		"days":             days,
+	})
+}
+
+func QuoteService(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request quoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var service Models.Service
+	if err := getServiceCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&service); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	// The quote goes through the same pricing as a booking so the area
+	// surcharge shows up before the customer books. Without an address yet
+	// the customer gets the service price alone.
+	booking := Models.OrderBookingService{
+		ServiceID: service.ID,
+		Quantity:  request.Quantity,
+		Area:      request.Area,
+		Hours:     request.Hours,
+		AddOns:    request.AddOns,
+		Address:   request.Address,
+		Location:  request.Location,
+	}
+	if !request.BookingDate.IsZero() {
+		booking.BookingDate = primitive.NewDateTimeFromTime(request.BookingDate)
+	}
+	if request.AddressID != primitive.NilObjectID {
+		claims, err := optionalClaims(c)
+		if err != nil || claims == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to quote a saved address"})
+			return
+		}
+		saved, err := loadSavedAddress(ctx, claims.ID, request.AddressID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		booking.Location = &saved.Location
+	}
+
+	if err := quoteBooking(&booking, service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if booking.Address != "" || booking.Location != nil {
+		if err := applyCoverage(ctx, &booking, service); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id": service.ID,
+		"lines":      booking.PriceLines,
+		"total":      booking.TotalPrice,
+	})
+}
+
+func UpdateServicePricing(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var pricing Models.ServicePricing
+	if err := c.ShouldBindJSON(&pricing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateServicePricing(pricing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := getServiceCollection().UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"pricing": pricing}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service pricing"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pricing)
+}
@@ -0,0 +1,114 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestQuoteServiceIncludesAreaSurcharge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("surcharge", func(mt *mtest.T) {
+		Database = mt.DB
+		serviceID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.services", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: serviceID},
+				{Key: "name", Value: "Deep clean"},
+				{Key: "price", Value: int64(300000)},
+				{Key: "servicecategory", Value: categoryID},
+			}),
+			mtest.CreateCursorResponse(0, "test.service_areas", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "servicecategory", Value: categoryID},
+				{Key: "province", Value: "Hồ Chí Minh"},
+				{Key: "district", Value: "Quận 7"},
+				{Key: "surcharge", Value: int64(25000)},
+				{Key: "active", Value: true},
+			}),
+		)
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		body := `{"location": {"street": "1 Nguyen Huu Tho", "district": "Quan 7", "province": "TP.HCM"}}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/services/"+serviceID.Hex()+"/quote", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: serviceID.Hex()}}
+
+		QuoteService(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+		}
+		var response struct {
+			Lines []Models.PriceLine `json:"lines"`
+			Total Models.Money       `json:"total"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Total != Models.Minor(325000) {
+			mt.Fatalf("total = %s, want the base price plus the area surcharge", response.Total)
+		}
+		if len(response.Lines) != 2 || response.Lines[1].Code != "area" {
+			mt.Fatalf("lines = %+v, want a base line and an area line", response.Lines)
+		}
+	})
+}
+
+func TestQuoteServiceWithoutAddressReturnsTheBasePrice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("no address", func(mt *mtest.T) {
+		Database = mt.DB
+		serviceID := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.services", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: serviceID},
+				{Key: "name", Value: "Deep clean"},
+				{Key: "price", Value: int64(300000)},
+				{Key: "servicecategory", Value: primitive.NewObjectID()},
+			}),
+		)
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/services/"+serviceID.Hex()+"/quote", strings.NewReader(`{"quantity": 2}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: serviceID.Hex()}}
+
+		QuoteService(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+		}
+		var response struct {
+			Lines []Models.PriceLine `json:"lines"`
+			Total Models.Money       `json:"total"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Total != Models.Minor(600000) || len(response.Lines) != 1 {
+			mt.Fatalf("quote = %s %+v, want the base price for 2", response.Total, response.Lines)
+		}
+		if n := len(mt.GetAllStartedEvents()); n != 1 {
+			mt.Fatalf("commands = %d, want only the service lookup", n)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var areaPrefixes = []string{"thanh pho", "thi xa", "thi tran", "quan", "huyen", "tinh", "phuong", "xa", "tp", "q", "p"}
+
+var provinceAliases = map[string]string{
+	"hcm":     "ho chi minh",
+	"tphcm":   "ho chi minh",
+	"sai gon": "ho chi minh",
+	"saigon":  "ho chi minh",
+	"hn":      "ha noi",
+	"hanoi":   "ha noi",
+	"danang":  "da nang",
+}
+
+type coverage struct {
+	Covered   bool                `json:"covered"`
+	Surcharge Models.Money        `json:"surcharge"`
+	Area      *Models.ServiceArea `json:"area,omitempty"`
+	Reason    string              `json:"reason,omitempty"`
+}
+
+func getServiceAreaCollection() *mongo.Collection {
+	return Database.Collection("service_areas")
+}
+
+func CreateServiceArea(c *gin.Context) {
+	var area Models.ServiceArea
+	if err := c.ShouldBindJSON(&area); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateServiceArea(area); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	area.ID = primitive.NewObjectID()
+	area.CreatedAt = time.Now()
+	area.UpdatedAt = time.Now()
+
+	if _, err := getServiceAreaCollection().InsertOne(context.Background(), area); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service area"})
+		return
+	}
+
+	c.JSON(http.StatusOK, area)
+}
+
+func GetServiceAreas(c *gin.Context) {
+	filter := bson.M{}
+	if category := c.Query("servicecategory"); category != "" {
+		categoryID, err := primitive.ObjectIDFromHex(category)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service category ID"})
+			return
+		}
+		filter["servicecategory"] = categoryID
+	}
+
+	var areas []Models.ServiceArea
+	cursor, err := getServiceAreaCollection().Find(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get service areas"})
+		return
+	}
+	if err := cursor.All(context.Background(), &areas); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode service areas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, areas)
+}
+
+func UpdateServiceArea(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var area Models.ServiceArea
+	if err := c.ShouldBindJSON(&area); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateServiceArea(area); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"servicecategory": area.ServiceCategory,
+		"province":        area.Province,
+		"district":        area.District,
+		"surcharge":       area.Surcharge,
+		"active":          area.Active,
+		"updated_at":      time.Now(),
+	}}
+	result, err := getServiceAreaCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service area"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service area not found"})
+		return
+	}
+
+	area.ID = id
+	c.JSON(http.StatusOK, area)
+}
+
+func DeleteServiceArea(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getServiceAreaCollection().DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service area"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service area not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func CheckServiceCoverage(c *gin.Context) {
+	var request struct {
+		ServiceID       primitive.ObjectID `json:"service_id"`
+		ServiceCategory primitive.ObjectID `json:"servicecategory"`
+		Location        Models.Address     `json:"location"`
+		Address         string             `json:"address"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	category := request.ServiceCategory
+	if request.ServiceID != primitive.NilObjectID {
+		var service Models.Service
+		if err := getServiceCollection().FindOne(ctx, bson.M{"_id": request.ServiceID}).Decode(&service); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		category = service.ServiceCategory
+	}
+	if category == primitive.NilObjectID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service or service category is required"})
+		return
+	}
+
+	result, err := checkCoverage(ctx, category, &request.Location, request.Address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check coverage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func validateServiceArea(area Models.ServiceArea) error {
+	if area.ServiceCategory == primitive.NilObjectID {
+		return errors.New("Service category is required")
+	}
+	if normalizeAreaName(area.District) == "" {
+		return errors.New("District is required")
+	}
+	if area.Surcharge.Sign() < 0 {
+		return errors.New("Surcharge cannot be negative")
+	}
+	return nil
+}
+
+// checkCoverage looks up the service area of a booking address. Categories
+// without any configured area are served everywhere so existing services keep
+// working until admins fill in the table.
+func checkCoverage(ctx context.Context, category primitive.ObjectID, location *Models.Address, address string) (coverage, error) {
+	var areas []Models.ServiceArea
+	cursor, err := getServiceAreaCollection().Find(ctx, bson.M{"servicecategory": category})
+	if err != nil {
+		return coverage{}, err
+	}
+	if err := cursor.All(ctx, &areas); err != nil {
+		return coverage{}, err
+	}
+	if len(areas) == 0 {
+		return coverage{Covered: true}, nil
+	}
+
+	for i, area := range areas {
+		if area.Active && areaMatches(area, location, address) {
+			return coverage{Covered: true, Surcharge: area.Surcharge, Area: &areas[i]}, nil
+		}
+	}
+	return coverage{Reason: "We do not serve this district yet"}, nil
+}
+
+func areaMatches(area Models.ServiceArea, location *Models.Address, address string) bool {
+	district := normalizeAreaName(area.District)
+	province := normalizeProvince(area.Province)
+
+	if location != nil && location.District != "" {
+		if normalizeAreaName(location.District) != district {
+			return false
+		}
+		return province == "" || location.Province == "" || normalizeProvince(location.Province) == province
+	}
+
+	// Free-text addresses from older clients are split on commas and matched
+	// part by part, so "Quận 1" does not match "Quận 10".
+	districtFound, provinceFound := false, province == ""
+	for _, part := range strings.Split(address, ",") {
+		if normalizeAreaName(part) == district {
+			districtFound = true
+		}
+		if !provinceFound && normalizeProvince(part) == province {
+			provinceFound = true
+		}
+	}
+	return districtFound && provinceFound
+}
+
+// normalizeAreaName folds diacritics, punctuation and administrative prefixes
+// so "Quận 1", "Q.1" and "quan 1" compare equal.
+func normalizeAreaName(name string) string {
+	name = strings.ToLower(foldVietnamese(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	name = strings.Join(words, " ")
+
+	for _, prefix := range areaPrefixes {
+		if rest := strings.TrimPrefix(name, prefix+" "); rest != name {
+			name = rest
+			break
+		}
+	}
+	return name
+}
+
+func normalizeProvince(name string) string {
+	name = normalizeAreaName(name)
+	if alias, ok := provinceAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// applyCoverage rejects bookings outside the served districts and adds the
+// area surcharge to the price lines of the booking.
+func applyCoverage(ctx context.Context, booking *Models.OrderBookingService, service Models.Service) error {
+	if booking.Location != nil {
+		if booking.Location.District == "" || booking.Location.Province == "" {
+			return errors.New("District and province are required")
+		}
+		booking.Address = booking.Location.String()
+	}
+	if strings.TrimSpace(booking.Address) == "" {
+		return errors.New("Address is required")
+	}
+
+	result, err := checkCoverage(ctx, service.ServiceCategory, booking.Location, booking.Address)
+	if err != nil {
+		return err
+	}
+	if !result.Covered {
+		return errors.New(result.Reason)
+	}
+	if result.Surcharge.Sign() > 0 {
+		booking.PriceLines = append(booking.PriceLines, Models.PriceLine{
+			Code:   "area",
+			Label:  fmt.Sprintf("Travel surcharge (%s)", result.Area.District),
+			Amount: result.Surcharge,
+		})
+		booking.TotalPrice = booking.TotalPrice.Add(result.Surcharge)
+	}
+	return nil
+}
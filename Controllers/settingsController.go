@@ -0,0 +1,296 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	bookingSettingsKey      = "booking"
+	cancellationSettingsKey = "cancellation"
+	loyaltySettingsKey      = "loyalty"
+	taxSettingsKey          = "tax"
+)
+
+func getSettingsCollection() *mongo.Collection {
+	return Database.Collection("settings")
+}
+
+func loadSettings(ctx context.Context, key string, out interface{}) error {
+	err := getSettingsCollection().FindOne(ctx, bson.M{"_id": key}).Decode(out)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+func saveSettings(ctx context.Context, key string, value interface{}) error {
+	_, err := getSettingsCollection().ReplaceOne(ctx, bson.M{"_id": key}, value, options.Replace().SetUpsert(true))
+	return err
+}
+
+func defaultBookingSettings() Models.BookingSettings {
+	return Models.BookingSettings{
+		OpenTime:       "08:00",
+		CloseTime:      "18:00",
+		WorkingDays:    []int{0, 1, 2, 3, 4, 5, 6},
+		SlotMinutes:    30,
+		SlotCapacity:   3,
+		MinLeadMinutes: 120,
+	}
+}
+
+func loadBookingSettings(ctx context.Context) (Models.BookingSettings, error) {
+	settings := defaultBookingSettings()
+	err := loadSettings(ctx, bookingSettingsKey, &settings)
+	return settings, err
+}
+
+func GetBookingSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings, err := loadBookingSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load booking settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateBookingSettings(c *gin.Context) {
+	settings := defaultBookingSettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateBookingSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := saveSettings(ctx, bookingSettingsKey, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save booking settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func defaultCancellationSettings() Models.CancellationSettings {
+	return Models.CancellationSettings{
+		FreeCancelHours:       24,
+		LateCancelFeePercent:  30,
+		RescheduleCutoffHours: 12,
+		MaxReschedules:        2,
+	}
+}
+
+func loadCancellationSettings(ctx context.Context) (Models.CancellationSettings, error) {
+	settings := defaultCancellationSettings()
+	err := loadSettings(ctx, cancellationSettingsKey, &settings)
+	return settings, err
+}
+
+func validateCancellationSettings(settings Models.CancellationSettings) error {
+	if settings.FreeCancelHours < 0 || settings.RescheduleCutoffHours < 0 || settings.MaxReschedules < 0 {
+		return errors.New("Hours and reschedule limit cannot be negative")
+	}
+	if settings.LateCancelFeePercent < 0 || settings.LateCancelFeePercent > 100 {
+		return errors.New("Late cancellation fee must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+func GetCancellationSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings, err := loadCancellationSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cancellation settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateCancellationSettings(c *gin.Context) {
+	settings := defaultCancellationSettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateCancellationSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := saveSettings(ctx, cancellationSettingsKey, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save cancellation settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func defaultLoyaltySettings() Models.LoyaltySettings {
+	return Models.LoyaltySettings{
+		EarnRate:         1,
+		PointValue:       10,
+		MaxRedeemPercent: 50,
+		ExpiryDays:       365,
+	}
+}
+
+func loadLoyaltySettings(ctx context.Context) (Models.LoyaltySettings, error) {
+	settings := defaultLoyaltySettings()
+	err := loadSettings(ctx, loyaltySettingsKey, &settings)
+	return settings, err
+}
+
+func validateLoyaltySettings(settings Models.LoyaltySettings) error {
+	if settings.EarnRate < 0 || settings.PointValue < 0 || settings.ExpiryDays < 0 {
+		return errors.New("Rates, point value and expiry cannot be negative")
+	}
+	for _, rate := range settings.CategoryRates {
+		if rate.EarnRate < 0 {
+			return errors.New("Category earn rates cannot be negative")
+		}
+	}
+	if settings.MaxRedeemPercent < 0 || settings.MaxRedeemPercent > 100 {
+		return errors.New("Maximum redemption must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+func GetLoyaltySettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings, err := loadLoyaltySettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load loyalty settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateLoyaltySettings(c *gin.Context) {
+	settings := defaultLoyaltySettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateLoyaltySettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := saveSettings(ctx, loyaltySettingsKey, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save loyalty settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func defaultTaxSettings() Models.TaxSettings {
+	return Models.TaxSettings{
+		PricesIncludeTax: true,
+		DefaultClass:     "standard",
+		Classes: []Models.TaxClass{
+			{Code: "standard", Name: "VAT 10%", Rate: 10},
+			{Code: "reduced", Name: "VAT 8%", Rate: 8},
+			{Code: "essential", Name: "VAT 5%", Rate: 5},
+			{Code: "zero", Name: "VAT 0%", Rate: 0},
+			{Code: "exempt", Name: "Not subject to VAT", Rate: 0},
+		},
+		InvoicePrefix: "INV",
+	}
+}
+
+func loadTaxSettings(ctx context.Context) (Models.TaxSettings, error) {
+	settings := defaultTaxSettings()
+	err := loadSettings(ctx, taxSettingsKey, &settings)
+	return settings, err
+}
+
+func validateTaxSettings(settings Models.TaxSettings) error {
+	codes := map[string]bool{}
+	for _, class := range settings.Classes {
+		if class.Code == "" || codes[class.Code] {
+			return errors.New("Each tax class needs a unique code")
+		}
+		if class.Rate < 0 || class.Rate > 100 {
+			return errors.New("Tax rates must be between 0 and 100 percent")
+		}
+		codes[class.Code] = true
+	}
+	if !codes[settings.DefaultClass] {
+		return errors.New("Default tax class must be one of the tax classes")
+	}
+	if settings.InvoicePrefix == "" {
+		return errors.New("Invoice prefix is required")
+	}
+	return nil
+}
+
+func GetTaxSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings, err := loadTaxSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tax settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateTaxSettings(c *gin.Context) {
+	settings := defaultTaxSettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateTaxSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := saveSettings(ctx, taxSettingsKey, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tax settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
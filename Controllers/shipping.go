@@ -0,0 +1,46 @@
+package Controllers
+
+import (
+	"context"
+	"sync"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Shipment is what a carrier needs to price and book a delivery.
+type Shipment struct {
+	OrderID   primitive.ObjectID
+	Recipient string
+	Phone     string
+	Address   Models.Address
+	Weight    int
+	Value     Models.Money
+}
+
+// ShippingProvider is a carrier integration. Methods with the "carrier" rate
+// type ask the provider for a price, and shipping an order without a
+// tracking number books the delivery with the method's carrier.
+type ShippingProvider interface {
+	Quote(ctx context.Context, shipment Shipment) (Models.Money, error)
+	CreateShipment(ctx context.Context, shipment Shipment) (string, error)
+}
+
+var (
+	shippingProvidersMu sync.RWMutex
+	shippingProviders   = map[string]ShippingProvider{}
+)
+
+func RegisterShippingProvider(carrier string, provider ShippingProvider) {
+	shippingProvidersMu.Lock()
+	defer shippingProvidersMu.Unlock()
+	shippingProviders[carrier] = provider
+}
+
+func shippingProvider(carrier string) (ShippingProvider, bool) {
+	shippingProvidersMu.RLock()
+	defer shippingProvidersMu.RUnlock()
+	provider, ok := shippingProviders[carrier]
+	return provider, ok
+}
@@ -0,0 +1,336 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	RateFlat    = "flat"
+	RateWeight  = "weight"
+	RateCarrier = "carrier"
+)
+
+type shippingQuote struct {
+	MethodID      primitive.ObjectID `json:"method_id"`
+	Code          string             `json:"code"`
+	Name          string             `json:"name"`
+	Carrier       string             `json:"carrier"`
+	Fee           Models.Money       `json:"fee"`
+	EstimatedDays int                `json:"estimated_days"`
+}
+
+func getShippingMethodCollection() *mongo.Collection {
+	return Database.Collection("shipping_methods")
+}
+
+func GetShippingMethods(c *gin.Context) {
+	filter := bson.M{}
+	if c.Query("all") != "true" {
+		filter["active"] = true
+	}
+
+	var methods []Models.ShippingMethod
+	cursor, err := getShippingMethodCollection().Find(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get shipping methods"})
+		return
+	}
+	if err := cursor.All(context.Background(), &methods); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode shipping methods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, methods)
+}
+
+func CreateShippingMethod(c *gin.Context) {
+	var method Models.ShippingMethod
+	if err := c.ShouldBindJSON(&method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateShippingMethod(method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method.ID = primitive.NewObjectID()
+	method.CreatedAt = time.Now()
+	method.UpdatedAt = time.Now()
+
+	if _, err := getShippingMethodCollection().InsertOne(context.Background(), method); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipping method"})
+		return
+	}
+
+	c.JSON(http.StatusOK, method)
+}
+
+func UpdateShippingMethod(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var method Models.ShippingMethod
+	if err := c.ShouldBindJSON(&method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := validateShippingMethod(method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"code":           method.Code,
+		"name":           method.Name,
+		"carrier":        method.Carrier,
+		"rate_type":      method.RateType,
+		"flat_fee":       method.FlatFee,
+		"weight_rates":   method.WeightRates,
+		"free_above":     method.FreeAbove,
+		"provinces":      method.Provinces,
+		"estimated_days": method.EstimatedDays,
+		"active":         method.Active,
+		"updated_at":     time.Now(),
+	}}
+	result, err := getShippingMethodCollection().UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipping method"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+
+	method.ID = id
+	c.JSON(http.StatusOK, method)
+}
+
+func DeleteShippingMethod(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getShippingMethodCollection().DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shipping method"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func GetShippingQuote(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		AddressID primitive.ObjectID `json:"address_id"`
+		Address   *Models.Address    `json:"address"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if request.AddressID != primitive.NilObjectID {
+		saved, err := loadSavedAddress(ctx, claims.ID, request.AddressID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		request.Address = &saved.Location
+	}
+	if request.Address == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Address is required"})
+		return
+	}
+
+	var selectedItems Models.SelectedItems
+	if err := getSelectedItemsCollection().FindOne(ctx, bson.M{"user_id": claims.ID}).Decode(&selectedItems); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No selected items found"})
+		return
+	}
+
+	var subtotal Models.Money
+	weight := 0
+	for _, item := range selectedItems.Items {
+		var product Models.Product
+		if err := getProductCollection().FindOne(ctx, bson.M{"_id": item.ProductID}).Decode(&product); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		subtotal = subtotal.Add(product.Price.Mul(item.Quantity))
+		weight += product.Weight * item.Quantity
+	}
+
+	var methods []Models.ShippingMethod
+	cursor, err := getShippingMethodCollection().Find(ctx, bson.M{"active": true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get shipping methods"})
+		return
+	}
+	if err := cursor.All(ctx, &methods); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode shipping methods"})
+		return
+	}
+
+	quotes := []shippingQuote{}
+	shipment := Shipment{Address: *request.Address, Weight: weight, Value: subtotal}
+	for _, method := range methods {
+		fee, err := shippingFee(ctx, method, shipment)
+		if err != nil {
+			continue
+		}
+		quotes = append(quotes, shippingQuote{
+			MethodID:      method.ID,
+			Code:          method.Code,
+			Name:          method.Name,
+			Carrier:       method.Carrier,
+			Fee:           fee,
+			EstimatedDays: method.EstimatedDays,
+		})
+	}
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].Fee.Cmp(quotes[j].Fee) < 0 })
+
+	c.JSON(http.StatusOK, gin.H{"subtotal": subtotal, "weight": weight, "quotes": quotes})
+}
+
+func validateShippingMethod(method Models.ShippingMethod) error {
+	if method.Code == "" || method.Name == "" {
+		return errors.New("Code and name are required")
+	}
+	if method.FlatFee.Sign() < 0 || method.FreeAbove.Sign() < 0 {
+		return errors.New("Fees cannot be negative")
+	}
+
+	switch method.RateType {
+	case RateFlat:
+	case RateWeight:
+		if len(method.WeightRates) == 0 {
+			return errors.New("Weight-based methods need at least one weight rate")
+		}
+		for _, rate := range method.WeightRates {
+			if rate.MaxWeight <= 0 || rate.Fee.Sign() < 0 {
+				return errors.New("Weight rates need a positive weight and a non-negative fee")
+			}
+		}
+	case RateCarrier:
+		if _, ok := shippingProvider(method.Carrier); !ok {
+			return fmt.Errorf("Unknown carrier %q", method.Carrier)
+		}
+	default:
+		return errors.New("Rate type must be flat, weight or carrier")
+	}
+	return nil
+}
+
+// shippingFee prices a shipment with a method's rate table, or with its
+// carrier for carrier-rated methods. Orders above the free threshold ship free.
+func shippingFee(ctx context.Context, method Models.ShippingMethod, shipment Shipment) (Models.Money, error) {
+	if len(method.Provinces) > 0 {
+		province := normalizeProvince(shipment.Address.Province)
+		served := false
+		for _, p := range method.Provinces {
+			if normalizeProvince(p) == province {
+				served = true
+				break
+			}
+		}
+		if !served {
+			return Models.Money{}, errors.New(method.Name + " does not deliver to " + shipment.Address.Province)
+		}
+	}
+
+	if method.FreeAbove.Sign() > 0 && shipment.Value.Cmp(method.FreeAbove) >= 0 {
+		return Models.Money{}, nil
+	}
+
+	switch method.RateType {
+	case RateWeight:
+		rates := append([]Models.WeightRate(nil), method.WeightRates...)
+		sort.Slice(rates, func(i, j int) bool { return rates[i].MaxWeight < rates[j].MaxWeight })
+		for _, rate := range rates {
+			if shipment.Weight <= rate.MaxWeight {
+				return rate.Fee, nil
+			}
+		}
+		return Models.Money{}, errors.New("Order is too heavy for " + method.Name)
+	case RateCarrier:
+		provider, ok := shippingProvider(method.Carrier)
+		if !ok {
+			return Models.Money{}, fmt.Errorf("Unknown carrier %q", method.Carrier)
+		}
+		return provider.Quote(ctx, shipment)
+	default:
+		return method.FlatFee, nil
+	}
+}
+
+// shipOrder fills in the carrier and tracking number of an order moving to
+// shipped, booking the delivery with the carrier when none was given.
+func shipOrder(ctx context.Context, order Models.Order, carrier, trackingNumber string) (Models.OrderShipping, error) {
+	shipping := Models.OrderShipping{}
+	if order.Shipping != nil {
+		shipping = *order.Shipping
+	}
+	if carrier = strings.TrimSpace(carrier); carrier != "" {
+		shipping.Carrier = carrier
+	}
+	if trackingNumber = strings.TrimSpace(trackingNumber); trackingNumber != "" {
+		shipping.TrackingNumber = trackingNumber
+	}
+
+	if shipping.TrackingNumber == "" {
+		provider, ok := shippingProvider(shipping.Carrier)
+		if !ok || order.Address == nil {
+			return shipping, errors.New("Tracking number is required")
+		}
+		number, err := provider.CreateShipment(ctx, Shipment{
+			OrderID:   order.ID,
+			Recipient: order.Recipient,
+			Phone:     order.Phone,
+			Address:   *order.Address,
+			Weight:    shipping.Weight,
+			Value:     order.TotalPrice,
+		})
+		if err != nil {
+			return shipping, err
+		}
+		shipping.TrackingNumber = number
+	}
+	if shipping.Carrier == "" {
+		return shipping, errors.New("Carrier is required")
+	}
+
+	shipping.ShippedAt = time.Now()
+	return shipping, nil
+}
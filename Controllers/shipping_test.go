@@ -0,0 +1,109 @@
+package Controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeShippingProvider prices by weight and hands out sequential tracking
+// numbers without calling any carrier.
+type fakeShippingProvider struct {
+	BaseFee  Models.Money
+	PerKgFee Models.Money
+
+	mu        sync.Mutex
+	next      int
+	Shipments map[string]Shipment
+}
+
+func newFakeShippingProvider() *fakeShippingProvider {
+	return &fakeShippingProvider{
+		BaseFee:   Models.Minor(20000),
+		PerKgFee:  Models.Minor(5000),
+		Shipments: make(map[string]Shipment),
+	}
+}
+
+func (p *fakeShippingProvider) Quote(ctx context.Context, shipment Shipment) (Models.Money, error) {
+	kilograms := (shipment.Weight + 999) / 1000
+	return p.BaseFee.Add(p.PerKgFee.Mul(kilograms)), nil
+}
+
+func (p *fakeShippingProvider) CreateShipment(ctx context.Context, shipment Shipment) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.next++
+	trackingNumber := fmt.Sprintf("FAKE%08d", p.next)
+	p.Shipments[trackingNumber] = shipment
+	return trackingNumber, nil
+}
+
+func TestNoCarrierIsRegisteredByDefault(t *testing.T) {
+	if _, ok := shippingProvider("fake"); ok {
+		t.Fatal("the fake carrier must only be registered by tests")
+	}
+}
+
+func TestShippingFeeAsksTheCarrier(t *testing.T) {
+	provider := newFakeShippingProvider()
+	RegisterShippingProvider("test-carrier", provider)
+
+	method := Models.ShippingMethod{Name: "Express", Carrier: "test-carrier", RateType: RateCarrier, Provinces: []string{"Hồ Chí Minh"}}
+	fee, err := shippingFee(context.Background(), method, Shipment{Address: Models.Address{Province: "TP.HCM"}, Weight: 2500})
+	if err != nil {
+		t.Fatalf("shippingFee: %v", err)
+	}
+	if want := Models.Minor(35000); fee != want {
+		t.Fatalf("fee = %s, want %s for 3 kg", fee, want)
+	}
+
+	if _, err := shippingFee(context.Background(), method, Shipment{Address: Models.Address{Province: "Hà Nội"}}); err == nil {
+		t.Fatal("a province the method does not serve should be rejected")
+	}
+
+	method.FreeAbove = Models.Minor(500000)
+	if fee, _ := shippingFee(context.Background(), method, Shipment{Address: Models.Address{Province: "TP.HCM"}, Value: Models.Minor(500000)}); !fee.IsZero() {
+		t.Fatalf("fee = %s above the free threshold, want 0", fee)
+	}
+
+	method.Carrier = "unknown"
+	method.FreeAbove = Models.Money{}
+	if _, err := shippingFee(context.Background(), method, Shipment{Address: Models.Address{Province: "TP.HCM"}}); err == nil {
+		t.Fatal("an unregistered carrier should be rejected")
+	}
+}
+
+func TestShipOrderBooksWithTheCarrier(t *testing.T) {
+	provider := newFakeShippingProvider()
+	RegisterShippingProvider("test-booking", provider)
+
+	order := Models.Order{
+		ID:        primitive.NewObjectID(),
+		Recipient: "Lan",
+		Address:   &Models.Address{Street: "12 Le Loi", District: "Quận 1", Province: "Hồ Chí Minh"},
+		Shipping:  &Models.OrderShipping{Carrier: "test-booking", Weight: 1200},
+	}
+	shipping, err := shipOrder(context.Background(), order, "", "")
+	if err != nil {
+		t.Fatalf("shipOrder: %v", err)
+	}
+	booked, ok := provider.Shipments[shipping.TrackingNumber]
+	if !ok || booked.OrderID != order.ID || booked.Weight != 1200 {
+		t.Fatalf("tracking number %q was not booked for the order: %+v", shipping.TrackingNumber, provider.Shipments)
+	}
+	if shipping.ShippedAt.IsZero() {
+		t.Fatal("the shipping time should be set")
+	}
+
+	order.Shipping = &Models.OrderShipping{Carrier: "manual"}
+	if _, err := shipOrder(context.Background(), order, "", ""); err == nil {
+		t.Fatal("an unknown carrier without a tracking number should be rejected")
+	}
+}
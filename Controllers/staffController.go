@@ -0,0 +1,614 @@
+package Controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	staffCheckInWindow = time.Hour
+	staffLockLease     = 10 * time.Second
+)
+
+type staffSuggestion struct {
+	Profile     Models.StaffProfile `json:"profile"`
+	JobsThatDay int                 `json:"jobs_that_day"`
+}
+
+func getStaffProfileCollection() *mongo.Collection {
+	return Database.Collection("staff_profiles")
+}
+
+func GetStaffProfiles(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var profiles []Models.StaffProfile
+	cursor, err := getStaffProfileCollection().Find(ctx, bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get staff profiles"})
+		return
+	}
+	if err := cursor.All(ctx, &profiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode staff profiles"})
+		return
+	}
+
+	for i := range profiles {
+		attachStaffUser(ctx, &profiles[i])
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+func GetStaffProfile(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var profile Models.StaffProfile
+	if err := getStaffProfileCollection().FindOne(ctx, bson.M{"user_id": userID}).Decode(&profile); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff profile not found"})
+		return
+	}
+	attachStaffUser(ctx, &profile)
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func UpsertStaffProfile(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var profile Models.StaffProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	for _, shift := range profile.Schedule {
+		start, err := parseClock(shift.StartTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		end, err := parseClock(shift.EndTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if shift.Weekday < 0 || shift.Weekday > 6 || end <= start {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid working shift"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user Models.User
+	if err := getUserCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role != Models.Staff && user.Role != Models.Admin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not a staff member"})
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"skills":        profile.Skills,
+			"service_areas": profile.ServiceAreas,
+			"schedule":      profile.Schedule,
+			"active":        profile.Active,
+			"updated_at":    time.Now(),
+		},
+		"$setOnInsert": bson.M{
+			"user_id":    userID,
+			"created_at": time.Now(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := getStaffProfileCollection().FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save staff profile"})
+		return
+	}
+	profile.User = &user
+	profile.User.Password = ""
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func AssignBookingStaff(c *gin.Context) {
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		StaffID primitive.ObjectID `json:"staff_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.StaffID == primitive.NilObjectID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	booking, service, err := loadBookingWithService(ctx, bookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if booking.Status != BookingPending && booking.Status != BookingConfirmed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff can only be assigned before the job starts"})
+		return
+	}
+
+	var profile Models.StaffProfile
+	if err := getStaffProfileCollection().FindOne(ctx, bson.M{"user_id": request.StaffID}).Decode(&profile); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff profile not found"})
+		return
+	}
+
+	unlock, err := lockStaff(ctx, profile.UserID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	defer unlock()
+
+	if reason, err := staffUnavailableReason(ctx, profile, booking, service); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check staff availability"})
+		return
+	} else if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": reason})
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"staff_id":    profile.UserID,
+			"assigned_at": primitive.NewDateTimeFromTime(time.Now()),
+			"updated_at":  primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+	if _, err := getOrderBookingServiceCollection().UpdateOne(ctx, bson.M{"_id": bookingID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign staff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff assigned", "staff_id": profile.UserID})
+}
+
+func SuggestBookingStaff(c *gin.Context) {
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	booking, service, err := loadBookingWithService(ctx, bookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	suggestions, err := suggestStaff(ctx, booking, service)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest staff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+func GetStaffJobs(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	filter := bson.M{"staff_id": claims.ID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var jobs []Models.OrderBookingService
+	opts := options.Find().SetSort(bson.D{{Key: "booking_date", Value: 1}})
+	cursor, err := getOrderBookingServiceCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get jobs"})
+		return
+	}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+func CheckInStaffJob(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var booking Models.OrderBookingService
+	if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": bookingID, "staff_id": claims.ID}).Decode(&booking); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if booking.Status != BookingConfirmed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only confirmed jobs can be checked in"})
+		return
+	}
+	if time.Now().Before(booking.BookingDate.Time().Add(-staffCheckInWindow)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "It is too early to check in for this job"})
+		return
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	if err := transitionBooking(ctx, bookingID, BookingConfirmed, BookingInProgress, bson.M{"check_in_at": now}); err != nil {
+		if err == errBookingStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checked in", "check_in_at": now})
+}
+
+func CheckOutStaffJob(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse multipart form"})
+		return
+	}
+
+	note := strings.TrimSpace(c.PostForm("note"))
+	if note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Completion note is required"})
+		return
+	}
+
+	beforeFiles := c.Request.MultipartForm.File["before_photos"]
+	afterFiles := c.Request.MultipartForm.File["after_photos"]
+	if len(beforeFiles) == 0 || len(afterFiles) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Before and after photos are required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var booking Models.OrderBookingService
+	if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": bookingID, "staff_id": claims.ID}).Decode(&booking); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if booking.Status != BookingInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only jobs in progress can be checked out"})
+		return
+	}
+
+	for _, files := range [][]*multipart.FileHeader{beforeFiles, afterFiles} {
+		if err := checkFormImages(files); err != nil {
+			if err == errUnsupportedImage {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read photo"})
+			return
+		}
+	}
+
+	beforePhotos, err := uploadFormImages(beforeFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not upload image to Cloudinary"})
+		return
+	}
+	afterPhotos, err := uploadFormImages(afterFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not upload image to Cloudinary"})
+		return
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	set := bson.M{
+		"check_out_at":    now,
+		"finish_at":       now,
+		"completion_note": note,
+		"before_photos":   beforePhotos,
+		"after_photos":    afterPhotos,
+	}
+	if err := transitionBooking(ctx, bookingID, BookingInProgress, BookingAwaitingConfirmation, set); err != nil {
+		if err == errBookingStatusChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Checked out, waiting for customer confirmation",
+		"finish_at":     now,
+		"before_photos": beforePhotos,
+		"after_photos":  afterPhotos,
+	})
+}
+
+var errUnsupportedImage = errors.New("Photos must be JPEG, PNG, GIF or WebP images of at most 10MB")
+
+// checkFormImages sniffs every photo the way chat attachments are checked,
+// before any of them is uploaded.
+func checkFormImages(files []*multipart.FileHeader) error {
+	for _, file := range files {
+		if file.Size > maxAttachmentSize {
+			return errUnsupportedImage
+		}
+		fileContent, err := file.Open()
+		if err != nil {
+			return err
+		}
+		mimeType, err := detectMimeType(fileContent)
+		fileContent.Close()
+		if err != nil {
+			return err
+		}
+		if !allowedAttachmentTypes[mimeType] || !strings.HasPrefix(mimeType, "image/") {
+			return errUnsupportedImage
+		}
+	}
+	return nil
+}
+
+// uploadFormImages uploads photos the handler has already passed through
+// checkFormImages.
+func uploadFormImages(files []*multipart.FileHeader) ([]string, error) {
+	var urls []string
+	for _, file := range files {
+		fileContent, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		url, err := uploadToCloudinary(fileContent, fmt.Sprintf("%d-%s", time.Now().UnixNano(), file.Filename))
+		fileContent.Close()
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+func loadBookingWithService(ctx context.Context, bookingID primitive.ObjectID) (Models.OrderBookingService, Models.Service, error) {
+	var booking Models.OrderBookingService
+	var service Models.Service
+	if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": bookingID}).Decode(&booking); err != nil {
+		return booking, service, err
+	}
+	err := getServiceCollection().FindOne(ctx, bson.M{"_id": booking.ServiceID}).Decode(&service)
+	return booking, service, err
+}
+
+func attachStaffUser(ctx context.Context, profile *Models.StaffProfile) {
+	var user Models.User
+	if err := getUserCollection().FindOne(ctx, bson.M{"_id": profile.UserID}).Decode(&user); err == nil {
+		user.Password = ""
+		profile.User = &user
+	}
+}
+
+// staffUnavailableReason explains why a staff member cannot take a booking,
+// or returns an empty string when they are qualified and free for the slot.
+func staffUnavailableReason(ctx context.Context, profile Models.StaffProfile, booking Models.OrderBookingService, service Models.Service) (string, error) {
+	if !profile.Active {
+		return "Staff member is not active", nil
+	}
+
+	qualified := false
+	for _, skill := range profile.Skills {
+		if skill == service.ServiceCategory {
+			qualified = true
+			break
+		}
+	}
+	if !qualified {
+		return "Staff member is not qualified for this service", nil
+	}
+
+	if !staffCoversAddress(profile, booking) {
+		return "Booking address is outside the staff member's service areas", nil
+	}
+
+	start := booking.BookingDate.Time()
+	end := booking.BookingEnd.Time()
+	if booking.BookingEnd == 0 {
+		end = start.Add(serviceDuration(service, booking.Hours))
+	}
+	if !staffWorksDuring(profile, start, end) {
+		return "Staff member is not working at the booking time", nil
+	}
+
+	filter := bson.M{
+		"_id":      bson.M{"$ne": booking.ID},
+		"staff_id": profile.UserID,
+		"status":   bson.M{"$nin": []string{BookingCancelled, BookingAwaitingConfirmation, BookingCompleted}},
+	}
+	jobs, err := findOverlappingBookings(ctx, filter, start, end)
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) > 0 {
+		return "Staff member already has a job at this time", nil
+	}
+
+	return "", nil
+}
+
+// lockStaff takes a short lease on a staff profile so that checking whether
+// the person is free and assigning them a job cannot interleave with another
+// assignment of the same person on any instance. The lease expires on its own
+// if the holder dies before releasing it.
+func lockStaff(ctx context.Context, staffID primitive.ObjectID) (func(), error) {
+	for {
+		now := time.Now()
+		lease := now.Add(staffLockLease)
+		filter := bson.M{
+			"user_id": staffID,
+			"$or": []bson.M{
+				{"assign_lock": bson.M{"$exists": false}},
+				{"assign_lock": bson.M{"$lt": now}},
+			},
+		}
+		result, err := getStaffProfileCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"assign_lock": lease}})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount > 0 {
+			return func() {
+				release := bson.M{"user_id": staffID, "assign_lock": lease}
+				if _, err := getStaffProfileCollection().UpdateOne(context.Background(), release, bson.M{"$unset": bson.M{"assign_lock": ""}}); err != nil {
+					log.Println("Error releasing staff lock:", err)
+				}
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("Staff member is being assigned to another job, please retry")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func staffCoversAddress(profile Models.StaffProfile, booking Models.OrderBookingService) bool {
+	if len(profile.ServiceAreas) == 0 {
+		return true
+	}
+	for _, area := range profile.ServiceAreas {
+		if areaMatches(Models.ServiceArea{District: area}, booking.Location, booking.Address) {
+			return true
+		}
+	}
+	return false
+}
+
+func staffWorksDuring(profile Models.StaffProfile, start, end time.Time) bool {
+	local := start.In(bookingLocation)
+	startMinute := local.Hour()*60 + local.Minute()
+	endMinute := startMinute + int(end.Sub(start)/time.Minute)
+
+	for _, shift := range profile.Schedule {
+		if time.Weekday(shift.Weekday) != local.Weekday() {
+			continue
+		}
+		shiftStart, err := parseClock(shift.StartTime)
+		if err != nil {
+			continue
+		}
+		shiftEnd, err := parseClock(shift.EndTime)
+		if err != nil {
+			continue
+		}
+		if startMinute >= shiftStart && endMinute <= shiftEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func suggestStaff(ctx context.Context, booking Models.OrderBookingService, service Models.Service) ([]staffSuggestion, error) {
+	var profiles []Models.StaffProfile
+	cursor, err := getStaffProfileCollection().Find(ctx, bson.M{"active": true, "skills": service.ServiceCategory})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, err
+	}
+
+	local := booking.BookingDate.Time().In(bookingLocation)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, bookingLocation)
+
+	suggestions := []staffSuggestion{}
+	for _, profile := range profiles {
+		reason, err := staffUnavailableReason(ctx, profile, booking, service)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			continue
+		}
+
+		jobs, err := getOrderBookingServiceCollection().CountDocuments(ctx, bson.M{
+			"staff_id":     profile.UserID,
+			"status":       bson.M{"$ne": BookingCancelled},
+			"booking_date": bson.M{"$gte": primitive.NewDateTimeFromTime(dayStart), "$lt": primitive.NewDateTimeFromTime(dayStart.AddDate(0, 0, 1))},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		attachStaffUser(ctx, &profile)
+		suggestions = append(suggestions, staffSuggestion{Profile: profile, JobsThatDay: int(jobs)})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].JobsThatDay != suggestions[j].JobsThatDay {
+			return suggestions[i].JobsThatDay < suggestions[j].JobsThatDay
+		}
+		return suggestions[i].Profile.RatingAverage > suggestions[j].Profile.RatingAverage
+	})
+	return suggestions, nil
+}
@@ -0,0 +1,90 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestOverlapTreatsMissingEndAsServiceDuration(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("legacy", func(mt *mtest.T) {
+		Database = mt.DB
+		from := time.Date(2030, 1, 7, 10, 0, 0, 0, bookingLocation)
+		serviceID := primitive.NewObjectID()
+		overlapping := primitive.NewObjectID()
+		legacy := func(id primitive.ObjectID, start time.Time) bson.D {
+			return bson.D{
+				{Key: "_id", Value: id},
+				{Key: "service_id", Value: serviceID},
+				{Key: "booking_date", Value: primitive.NewDateTimeFromTime(start)},
+			}
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.order_booking_service", mtest.FirstBatch,
+				legacy(overlapping, from.Add(-time.Hour)),
+				legacy(primitive.NewObjectID(), from.Add(-3*time.Hour)),
+			),
+			mtest.CreateCursorResponse(0, "test.services", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: serviceID},
+				{Key: "duration_minutes", Value: 120},
+			}),
+		)
+
+		jobs, err := findOverlappingBookings(context.Background(), bson.M{"staff_id": primitive.NewObjectID()}, from, from.Add(2*time.Hour))
+		if err != nil {
+			mt.Fatalf("findOverlappingBookings: %v", err)
+		}
+		if len(jobs) != 1 || jobs[0].ID != overlapping {
+			mt.Fatalf("got %d overlapping jobs, want only the one still running at %s", len(jobs), from)
+		}
+		if want := from.Add(time.Hour); !jobs[0].BookingEnd.Time().Equal(want) {
+			mt.Fatalf("end = %s, want %s", jobs[0].BookingEnd.Time(), want)
+		}
+	})
+}
+
+func formFiles(t *testing.T, contents map[string][]byte) []*multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, data := range contents {
+		part, err := writer.CreateFormFile("photos", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["photos"]
+}
+
+func TestCheckFormImagesSniffsContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := checkFormImages(formFiles(t, map[string][]byte{"before.png": png})); err != nil {
+		t.Fatalf("PNG photo rejected: %v", err)
+	}
+
+	disguised := formFiles(t, map[string][]byte{"before.png": png, "after.png": []byte("<html><script>alert(1)</script>")})
+	if err := checkFormImages(disguised); err != errUnsupportedImage {
+		t.Fatalf("HTML named .png got %v, want errUnsupportedImage", err)
+	}
+
+	pdf := formFiles(t, map[string][]byte{"after.pdf": []byte("%PDF-1.7\n")})
+	if err := checkFormImages(pdf); err != errUnsupportedImage {
+		t.Fatalf("PDF photo got %v, want errUnsupportedImage", err)
+	}
+}
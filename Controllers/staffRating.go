@@ -0,0 +1,269 @@
+package Controllers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type staffRatingReportRow struct {
+	StaffID       primitive.ObjectID `bson:"_id" json:"staff_id"`
+	Staff         *Models.User       `bson:"-" json:"staff,omitempty"`
+	CompletedJobs int                `bson:"completed_jobs" json:"completed_jobs"`
+	RatingCount   int                `bson:"rating_count" json:"rating_count"`
+	RatingAverage float64            `bson:"rating_average" json:"rating_average"`
+}
+
+// GetStaffRatingPrompts lists the customer's completed bookings whose staff
+// member has not been rated yet.
+func GetStaffRatingPrompts(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":      claims.ID,
+		"status":       BookingCompleted,
+		"staff_id":     bson.M{"$exists": true},
+		"staff_rating": bson.M{"$exists": false},
+	}
+	var bookings []Models.OrderBookingService
+	opts := options.Find().SetSort(bson.D{{Key: "finish_at", Value: -1}}).SetLimit(20)
+	cursor, err := getOrderBookingServiceCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bookings"})
+		return
+	}
+	if err := cursor.All(ctx, &bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookings)
+}
+
+// RateBookingStaff records the customer's rating of the staff member who did
+// a completed booking. Each booking can be rated once.
+func RateBookingStaff(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		Rating  int    `json:"rating"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if request.Rating < 1 || request.Rating > 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var booking Models.OrderBookingService
+	if err := getOrderBookingServiceCollection().FindOne(ctx, bson.M{"_id": bookingID, "user_id": claims.ID}).Decode(&booking); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if booking.Status != BookingCompleted || booking.StaffID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only completed bookings with an assigned staff member can be rated"})
+		return
+	}
+
+	filter := bson.M{"_id": bookingID, "status": BookingCompleted, "staff_rating": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{
+		"staff_rating":   request.Rating,
+		"staff_comment":  strings.TrimSpace(request.Comment),
+		"staff_rated_at": primitive.NewDateTimeFromTime(time.Now()),
+	}}
+	result, err := getOrderBookingServiceCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rate staff"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking has already been rated"})
+		return
+	}
+
+	if err := refreshStaffRating(ctx, booking.StaffID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update staff rating"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Thanks for rating your cleaner"})
+}
+
+// GetStaffRatings returns a staff member's overall score and the comments
+// customers left, newest first.
+func GetStaffRatings(c *gin.Context) {
+	staffID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var profile Models.StaffProfile
+	if err := getStaffProfileCollection().FindOne(ctx, bson.M{"user_id": staffID}).Decode(&profile); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff profile not found"})
+		return
+	}
+
+	type ratingEntry struct {
+		BookingID primitive.ObjectID `bson:"_id" json:"booking_id"`
+		ServiceID primitive.ObjectID `bson:"service_id" json:"service_id"`
+		Rating    int                `bson:"staff_rating" json:"rating"`
+		Comment   string             `bson:"staff_comment" json:"comment"`
+		RatedAt   primitive.DateTime `bson:"staff_rated_at" json:"rated_at"`
+	}
+	var history []ratingEntry
+	opts := options.Find().
+		SetSort(bson.D{{Key: "staff_rated_at", Value: -1}}).
+		SetProjection(bson.M{"service_id": 1, "staff_rating": 1, "staff_comment": 1, "staff_rated_at": 1}).
+		SetLimit(200)
+	cursor, err := getOrderBookingServiceCollection().Find(ctx, bson.M{"staff_id": staffID, "status": BookingCompleted, "staff_rating": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ratings"})
+		return
+	}
+	if err := cursor.All(ctx, &history); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode ratings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"staff_id":       staffID,
+		"rating_average": profile.RatingAverage,
+		"rating_count":   profile.RatingCount,
+		"ratings":        history,
+	})
+}
+
+// GetStaffRatingReport ranks staff by average rating and then by completed
+// jobs, for bookings scheduled within the date range. The range defaults to
+// the last 30 days.
+func GetStaffRatingReport(c *gin.Context) {
+	var err error
+	today := time.Now().In(bookingLocation)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, bookingLocation).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -30)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, bookingLocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, bookingLocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The to date must not be before the from date"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":       BookingCompleted,
+			"staff_id":     bson.M{"$exists": true},
+			"booking_date": bson.M{"$gte": primitive.NewDateTimeFromTime(from), "$lt": primitive.NewDateTimeFromTime(to)},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$staff_id",
+			"completed_jobs": bson.M{"$sum": 1},
+			"rating_count":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$staff_rating", 0}}, 1, 0}}},
+			"rating_average": bson.M{"$avg": "$staff_rating"},
+		}}},
+	}
+	cursor, err := getOrderBookingServiceCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+	rows := []staffRatingReportRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode report"})
+		return
+	}
+
+	for i := range rows {
+		rows[i].RatingAverage = math.Round(rows[i].RatingAverage*10) / 10
+		var user Models.User
+		if err := getUserCollection().FindOne(ctx, bson.M{"_id": rows[i].StaffID}).Decode(&user); err == nil {
+			user.Password = ""
+			rows[i].Staff = &user
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].RatingAverage != rows[j].RatingAverage {
+			return rows[i].RatingAverage > rows[j].RatingAverage
+		}
+		return rows[i].CompletedJobs > rows[j].CompletedJobs
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from.Format("2006-01-02"),
+		"to":    to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"staff": rows,
+	})
+}
+
+// refreshStaffRating stores the average and count of all ratings of a staff
+// member on their profile, so assignment and listings can use them directly.
+func refreshStaffRating(ctx context.Context, staffID primitive.ObjectID) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"staff_id": staffID, "status": BookingCompleted, "staff_rating": bson.M{"$gt": 0}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "average": bson.M{"$avg": "$staff_rating"}, "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := getOrderBookingServiceCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	var results []struct {
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return err
+	}
+
+	var average float64
+	var count int
+	if len(results) > 0 {
+		average = math.Round(results[0].Average*10) / 10
+		count = results[0].Count
+	}
+	_, err = getStaffProfileCollection().UpdateOne(ctx, bson.M{"user_id": staffID}, bson.M{"$set": bson.M{"rating_average": average, "rating_count": count}})
+	return err
+}
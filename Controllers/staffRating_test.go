@@ -0,0 +1,38 @@
+package Controllers
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRefreshStaffRatingCountsCompletedBookingsOnly(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("refresh", func(mt *mtest.T) {
+		Database = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.order_booking_service", mtest.FirstBatch, bson.D{
+				{Key: "average", Value: 4.44},
+				{Key: "count", Value: 9},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		if err := refreshStaffRating(context.Background(), primitive.NewObjectID()); err != nil {
+			mt.Fatalf("refreshStaffRating: %v", err)
+		}
+
+		match := mt.GetAllStartedEvents()[0].Command.Lookup("pipeline", "0", "$match").Document()
+		if status, ok := match.Lookup("status").StringValueOK(); !ok || status != BookingCompleted {
+			mt.Fatalf("$match = %s, want only completed bookings", match)
+		}
+		update := updatesSent(mt)[0].Lookup("updates", "0", "u", "$set").Document()
+		if update.Lookup("rating_average").Double() != 4.4 || update.Lookup("rating_count").AsInt64() != 9 {
+			mt.Fatalf("profile update = %s, want 4.4 from 9 ratings", update)
+		}
+	})
+}
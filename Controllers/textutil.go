@@ -0,0 +1,34 @@
+package Controllers
+
+import "strings"
+
+var vietnameseFolds = map[string]string{
+	"a": "àáảãạăằắẳẵặâầấẩẫậ",
+	"A": "ÀÁẢÃẠĂẰẮẲẴẶÂẦẤẨẪẬ",
+	"e": "èéẻẽẹêềếểễệ",
+	"E": "ÈÉẺẼẸÊỀẾỂỄỆ",
+	"i": "ìíỉĩị",
+	"I": "ÌÍỈĨỊ",
+	"o": "òóỏõọôồốổỗộơờớởỡợ",
+	"O": "ÒÓỎÕỌÔỒỐỔỖỘƠỜỚỞỠỢ",
+	"u": "ùúủũụưừứửữự",
+	"U": "ÙÚỦŨỤƯỪỨỬỮỰ",
+	"y": "ỳýỷỹỵ",
+	"Y": "ỲÝỶỸỴ",
+	"d": "đ",
+	"D": "Đ",
+}
+
+var vietnameseReplacer = func() *strings.Replacer {
+	var pairs []string
+	for ascii, accented := range vietnameseFolds {
+		for _, r := range accented {
+			pairs = append(pairs, string(r), ascii)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+func foldVietnamese(text string) string {
+	return vietnameseReplacer.Replace(text)
+}
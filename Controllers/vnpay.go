@@ -0,0 +1,192 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Server/Models"
+)
+
+const (
+	vnpayVersion     = "2.1.0"
+	vnpayTimeLayout  = "20060102150405"
+	vnpayPaymentPath = "/paymentv2/vpcpay.html"
+	vnpayAPIPath     = "/merchant_webapi/api/transaction"
+)
+
+var vnpayLocation = time.FixedZone("GMT+7", 7*60*60)
+
+// VNPayProvider implements the VNPay redirect protocol: request parameters
+// are sorted, URL-encoded and signed with HMAC-SHA512 of the merchant secret,
+// and the gateway signs its return and IPN parameters the same way.
+type VNPayProvider struct {
+	TmnCode    string
+	HashSecret string
+	PayURL     string
+	APIURL     string
+	Client     *http.Client
+}
+
+func NewVNPayProvider(tmnCode, hashSecret, payURL, apiURL string) *VNPayProvider {
+	if payURL == "" {
+		payURL = "https://sandbox.vnpayment.vn" + vnpayPaymentPath
+	}
+	if apiURL == "" {
+		apiURL = "https://sandbox.vnpayment.vn" + vnpayAPIPath
+	}
+	return &VNPayProvider{
+		TmnCode:    tmnCode,
+		HashSecret: hashSecret,
+		PayURL:     payURL,
+		APIURL:     apiURL,
+		Client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *VNPayProvider) CreatePaymentURL(ctx context.Context, payment Models.Payment, returnURL, clientIP string) (string, error) {
+	now := time.Now().In(vnpayLocation)
+	params := url.Values{}
+	params.Set("vnp_Version", vnpayVersion)
+	params.Set("vnp_Command", "pay")
+	params.Set("vnp_TmnCode", p.TmnCode)
+	params.Set("vnp_Amount", vnpayAmount(payment.Amount))
+	params.Set("vnp_CurrCode", "VND")
+	params.Set("vnp_TxnRef", payment.TransactionRef)
+	params.Set("vnp_OrderInfo", "Thanh toan "+payment.TargetType+" "+payment.TargetID.Hex())
+	params.Set("vnp_OrderType", "other")
+	params.Set("vnp_Locale", "vn")
+	params.Set("vnp_ReturnUrl", returnURL)
+	params.Set("vnp_IpAddr", clientIP)
+	params.Set("vnp_CreateDate", now.Format(vnpayTimeLayout))
+	params.Set("vnp_ExpireDate", now.Add(15*time.Minute).Format(vnpayTimeLayout))
+
+	query := vnpaySignedQuery(params)
+	return p.PayURL + "?" + query + "&vnp_SecureHash=" + vnpaySign(p.HashSecret, query), nil
+}
+
+func (p *VNPayProvider) VerifyCallback(params url.Values) (PaymentResult, error) {
+	if !vnpayVerify(p.HashSecret, params) {
+		return PaymentResult{}, errInvalidSignature
+	}
+
+	amount, err := strconv.ParseInt(params.Get("vnp_Amount"), 10, 64)
+	if err != nil {
+		return PaymentResult{}, errors.New("Invalid payment amount")
+	}
+
+	result := PaymentResult{
+		TransactionRef: params.Get("vnp_TxnRef"),
+		ProviderRef:    params.Get("vnp_TransactionNo"),
+		Amount:         Models.Minor(amount / 100),
+		Success:        params.Get("vnp_ResponseCode") == "00" && params.Get("vnp_TransactionStatus") == "00",
+		Message:        "VNPay response code " + params.Get("vnp_ResponseCode"),
+	}
+	return result, nil
+}
+
+func (p *VNPayProvider) Refund(ctx context.Context, payment Models.Payment, amount Models.Money, reason string) (string, error) {
+	transactionType := "02"
+	if amount.Cmp(payment.Amount) < 0 {
+		transactionType = "03"
+	}
+
+	request := map[string]string{
+		"vnp_RequestId":       strconv.FormatInt(time.Now().UnixNano(), 36),
+		"vnp_Version":         vnpayVersion,
+		"vnp_Command":         "refund",
+		"vnp_TmnCode":         p.TmnCode,
+		"vnp_TransactionType": transactionType,
+		"vnp_TxnRef":          payment.TransactionRef,
+		"vnp_Amount":          vnpayAmount(amount),
+		"vnp_TransactionNo":   payment.ProviderRef,
+		"vnp_TransactionDate": payment.CreatedAt.In(vnpayLocation).Format(vnpayTimeLayout),
+		"vnp_CreateBy":        "admin",
+		"vnp_CreateDate":      time.Now().In(vnpayLocation).Format(vnpayTimeLayout),
+		"vnp_IpAddr":          "127.0.0.1",
+		"vnp_OrderInfo":       reason,
+	}
+	request["vnp_SecureHash"] = vnpaySign(p.HashSecret, vnpayRefundChecksumData(request))
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	response, err := p.Client.Do(httpRequest)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var result map[string]string
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("Invalid refund response: %v", err)
+	}
+	if result["vnp_ResponseCode"] != "00" {
+		return "", fmt.Errorf("Refund rejected with code %s: %s", result["vnp_ResponseCode"], result["vnp_Message"])
+	}
+	return result["vnp_TransactionNo"], nil
+}
+
+func vnpayAmount(amount Models.Money) string {
+	return strconv.FormatInt(amount.MinorUnits()*100, 10)
+}
+
+// vnpaySignedQuery builds the canonical query string that gets signed:
+// vnp_ parameters in key order, without the hash fields themselves.
+func vnpaySignedQuery(params url.Values) string {
+	var keys []string
+	for key := range params {
+		if strings.HasPrefix(key, "vnp_") && key != "vnp_SecureHash" && key != "vnp_SecureHashType" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(params.Get(key)))
+	}
+	return strings.Join(parts, "&")
+}
+
+func vnpaySign(secret, data string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func vnpayVerify(secret string, params url.Values) bool {
+	expected := vnpaySign(secret, vnpaySignedQuery(params))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(params.Get("vnp_SecureHash"))))
+}
+
+func vnpayRefundChecksumData(request map[string]string) string {
+	fields := []string{
+		"vnp_RequestId", "vnp_Version", "vnp_Command", "vnp_TmnCode", "vnp_TransactionType",
+		"vnp_TxnRef", "vnp_Amount", "vnp_TransactionNo", "vnp_TransactionDate",
+		"vnp_CreateBy", "vnp_CreateDate", "vnp_IpAddr", "vnp_OrderInfo",
+	}
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		values[i] = request[field]
+	}
+	return strings.Join(values, "|")
+}
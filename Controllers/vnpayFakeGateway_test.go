@@ -0,0 +1,136 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeVNPayGateway is a local stand-in for the VNPay sandbox. It checks the
+// merchant signature, approves or declines the payment and redirects back
+// with signed parameters, and accepts signed refund requests. Tests serve it
+// with httptest.NewServer.
+type fakeVNPayGateway struct {
+	TmnCode    string
+	HashSecret string
+
+	// ResponseCode is returned for every payment; "00" approves, anything
+	// else declines.
+	ResponseCode string
+	// IPNURL, when set, receives the same signed parameters as the return
+	// URL, like the server-to-server notification of the real gateway.
+	IPNURL string
+
+	mu           sync.Mutex
+	next         int
+	Transactions map[string]url.Values
+	Refunds      []map[string]string
+}
+
+func newFakeVNPayGateway(tmnCode, hashSecret string) *fakeVNPayGateway {
+	return &fakeVNPayGateway{
+		TmnCode:      tmnCode,
+		HashSecret:   hashSecret,
+		ResponseCode: "00",
+		Transactions: make(map[string]url.Values),
+	}
+}
+
+func (g *fakeVNPayGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case vnpayPaymentPath:
+		g.pay(w, r)
+	case vnpayAPIPath:
+		g.refund(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *fakeVNPayGateway) pay(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("vnp_TmnCode") != g.TmnCode || !vnpayVerify(g.HashSecret, params) {
+		http.Error(w, "invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	g.mu.Lock()
+	g.next++
+	transactionNo := fmt.Sprintf("%08d", g.next)
+	g.mu.Unlock()
+
+	status := "00"
+	if g.ResponseCode != "00" {
+		status = "02"
+	}
+
+	result := url.Values{}
+	result.Set("vnp_TmnCode", g.TmnCode)
+	result.Set("vnp_Amount", params.Get("vnp_Amount"))
+	result.Set("vnp_BankCode", "NCB")
+	result.Set("vnp_OrderInfo", params.Get("vnp_OrderInfo"))
+	result.Set("vnp_PayDate", time.Now().In(vnpayLocation).Format(vnpayTimeLayout))
+	result.Set("vnp_ResponseCode", g.ResponseCode)
+	result.Set("vnp_TransactionNo", transactionNo)
+	result.Set("vnp_TransactionStatus", status)
+	result.Set("vnp_TxnRef", params.Get("vnp_TxnRef"))
+	query := vnpaySignedQuery(result)
+	query += "&vnp_SecureHash=" + vnpaySign(g.HashSecret, query)
+
+	g.mu.Lock()
+	g.Transactions[params.Get("vnp_TxnRef")] = result
+	g.mu.Unlock()
+
+	if g.IPNURL != "" {
+		go func() {
+			if response, err := http.Get(g.IPNURL + "?" + query); err == nil {
+				response.Body.Close()
+			}
+		}()
+	}
+
+	returnURL := params.Get("vnp_ReturnUrl")
+	if strings.Contains(returnURL, "?") {
+		returnURL += "&" + query
+	} else {
+		returnURL += "?" + query
+	}
+	http.Redirect(w, r, returnURL, http.StatusFound)
+}
+
+func (g *fakeVNPayGateway) refund(w http.ResponseWriter, r *http.Request) {
+	var request map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	respond := func(code, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"vnp_ResponseCode":  code,
+			"vnp_Message":       message,
+			"vnp_TxnRef":        request["vnp_TxnRef"],
+			"vnp_TransactionNo": request["vnp_TransactionNo"],
+		})
+	}
+
+	expected := vnpaySign(g.HashSecret, vnpayRefundChecksumData(request))
+	if request["vnp_TmnCode"] != g.TmnCode || expected != request["vnp_SecureHash"] {
+		respond("97", "Invalid checksum")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.Transactions[request["vnp_TxnRef"]]; !ok {
+		respond("91", "Transaction not found")
+		return
+	}
+	g.Refunds = append(g.Refunds, request)
+	respond("00", "Refund success")
+}
@@ -0,0 +1,101 @@
+package Controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"Server/Models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestVNPay(t *testing.T) (*fakeVNPayGateway, *VNPayProvider) {
+	t.Helper()
+	gateway := newFakeVNPayGateway("TESTTMN", "test-secret")
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	provider := NewVNPayProvider(gateway.TmnCode, gateway.HashSecret, server.URL+vnpayPaymentPath, server.URL+vnpayAPIPath)
+	provider.Client = server.Client()
+	provider.Client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return gateway, provider
+}
+
+// payThroughGateway follows the payment URL and returns the signed
+// parameters the gateway sends back on the return URL.
+func payThroughGateway(t *testing.T, provider *VNPayProvider, payment Models.Payment) url.Values {
+	t.Helper()
+	paymentURL, err := provider.CreatePaymentURL(context.Background(), payment, "http://shop.test/return", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("CreatePaymentURL: %v", err)
+	}
+	response, err := provider.Client.Get(paymentURL)
+	if err != nil {
+		t.Fatalf("paying: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("gateway answered %d, want a redirect", response.StatusCode)
+	}
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
+
+func TestVNPayPaymentRoundTrip(t *testing.T) {
+	gateway, provider := newTestVNPay(t)
+	payment := Models.Payment{
+		ID:         primitive.NewObjectID(),
+		TargetType: PaymentTargetOrder,
+		TargetID:   primitive.NewObjectID(),
+		Amount:     Models.Minor(150000),
+		CreatedAt:  time.Now(),
+	}
+	payment.TransactionRef = payment.ID.Hex()
+
+	result, err := provider.VerifyCallback(payThroughGateway(t, provider, payment))
+	if err != nil {
+		t.Fatalf("VerifyCallback: %v", err)
+	}
+	if !result.Success || result.Amount != payment.Amount || result.TransactionRef != payment.TransactionRef {
+		t.Fatalf("result = %+v, want a successful payment of %s", result, payment.Amount)
+	}
+
+	payment.ProviderRef = result.ProviderRef
+	refundRef, err := provider.Refund(context.Background(), payment, Models.Minor(50000), "Damaged item")
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if refundRef != result.ProviderRef || len(gateway.Refunds) != 1 || gateway.Refunds[0]["vnp_TransactionType"] != "03" {
+		t.Fatalf("refund %q was not recorded as partial: %+v", refundRef, gateway.Refunds)
+	}
+}
+
+func TestVNPayRejectsTamperedCallbacks(t *testing.T) {
+	gateway, provider := newTestVNPay(t)
+	gateway.ResponseCode = "24"
+	payment := Models.Payment{ID: primitive.NewObjectID(), Amount: Models.Minor(90000), CreatedAt: time.Now()}
+	payment.TransactionRef = payment.ID.Hex()
+
+	params := payThroughGateway(t, provider, payment)
+	result, err := provider.VerifyCallback(params)
+	if err != nil || result.Success {
+		t.Fatalf("a declined payment should verify as unsuccessful, got %+v, %v", result, err)
+	}
+
+	params.Set("vnp_Amount", "1")
+	if _, err := provider.VerifyCallback(params); err != errInvalidSignature {
+		t.Fatalf("VerifyCallback of a tampered amount = %v, want %v", err, errInvalidSignature)
+	}
+
+	other := Models.Payment{TransactionRef: "unknown", Amount: Models.Minor(1000), CreatedAt: time.Now()}
+	if _, err := provider.Refund(context.Background(), other, Models.Minor(1000), ""); err == nil {
+		t.Fatal("refunding a transaction the gateway never saw should fail")
+	}
+}
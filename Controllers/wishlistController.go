@@ -0,0 +1,530 @@
+package Controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"Server/Middleware"
+	"Server/Models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// savedForLaterList is the wishlist that cart items are moved to by
+// SaveCartItemForLater.
+const savedForLaterList = "Saved for later"
+
+var (
+	errWishlistExists   = errors.New("You already have a wishlist with this name")
+	errWishlistNotFound = errors.New("Wishlist not found")
+)
+
+func getWishlistCollection() *mongo.Collection {
+	return Database.Collection("wishlists")
+}
+
+func GetWishlists(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	wishlists := []Models.Wishlist{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := getWishlistCollection().Find(ctx, bson.M{"user_id": claims.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wishlists"})
+		return
+	}
+	if err := cursor.All(ctx, &wishlists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode wishlists"})
+		return
+	}
+
+	for i := range wishlists {
+		if err := fillWishlistItems(ctx, &wishlists[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, wishlists)
+}
+
+func CreateWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wishlist, err := createWishlist(ctx, claims.ID, strings.TrimSpace(request.Name))
+	if err == errWishlistExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func RenameWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := getWishlistCollection().CountDocuments(ctx, bson.M{"user_id": claims.ID, "name": name, "_id": bson.M{"$ne": id}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename wishlist"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errWishlistExists.Error()})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"name": name, "updated_at": time.Now()}}
+	result, err := getWishlistCollection().UpdateOne(ctx, bson.M{"_id": id, "user_id": claims.ID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename wishlist"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist renamed"})
+}
+
+func DeleteWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := getWishlistCollection().DeleteOne(context.Background(), bson.M{"_id": id, "user_id": claims.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete wishlist"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func AddToWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request struct {
+		ProductID primitive.ObjectID `json:"product_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.ProductID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := addWishlistItem(ctx, bson.M{"_id": id, "user_id": claims.ID}, request.ProductID); err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case errWishlistNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to wishlist"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product added to wishlist"})
+}
+
+func RemoveFromWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	productID, err := primitive.ObjectIDFromHex(c.Param("productId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	removed, err := removeWishlistItem(ctx, id, claims.ID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from wishlist"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found in wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product removed from wishlist"})
+}
+
+// MoveWishlistItemToCart puts a wishlist product into the cart at its current
+// price and takes it off the list.
+func MoveWishlistItemToCart(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	productID, err := primitive.ObjectIDFromHex(c.Param("productId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var request struct {
+		Quantity int `json:"quantity"`
+	}
+	c.ShouldBindJSON(&request)
+	if request.Quantity <= 0 {
+		request.Quantity = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := getWishlistCollection().CountDocuments(ctx, bson.M{"_id": id, "user_id": claims.ID, "items.product_id": productID})
+	if err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found in wishlist"})
+		return
+	}
+
+	var product Models.Product
+	if err := getProductCollection().FindOne(ctx, bson.M{"_id": productID}).Decode(&product); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	inCart, err := cartQuantity(ctx, claims.ID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart"})
+		return
+	}
+	if product.Stock < inCart+request.Quantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough stock for " + product.Name})
+		return
+	}
+
+	cart, err := addCartItem(ctx, claims.ID, Models.CartItem{ProductID: productID, Quantity: request.Quantity, Price: product.Price})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to cart"})
+		return
+	}
+	if _, err := removeWishlistItem(ctx, id, claims.ID, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product moved to cart", "cart": cart})
+}
+
+// SaveCartItemForLater moves a product from the cart to the customer's
+// "Saved for later" list, creating the list on first use.
+func SaveCartItemForLater(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	var request struct {
+		ProductID primitive.ObjectID `json:"product_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.ProductID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := getCartCollection().CountDocuments(ctx, bson.M{"user_id": claims.ID, "items.product_id": request.ProductID})
+	if err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found in cart"})
+		return
+	}
+
+	if _, err := createWishlist(ctx, claims.ID, savedForLaterList); err != nil && err != errWishlistExists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wishlist"})
+		return
+	}
+	if err := addWishlistItem(ctx, bson.M{"user_id": claims.ID, "name": savedForLaterList}, request.ProductID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to wishlist"})
+		return
+	}
+
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"product_id": request.ProductID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	if _, err := getCartCollection().UpdateOne(ctx, bson.M{"user_id": claims.ID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+		return
+	}
+	getCartCollection().DeleteOne(ctx, bson.M{"user_id": claims.ID, "items": bson.M{"$size": 0}})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product saved for later"})
+}
+
+// ShareWishlist gives the wishlist a public read-only link. Sharing again
+// keeps the existing link.
+func ShareWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wishlist Models.Wishlist
+	if err := getWishlistCollection().FindOne(ctx, bson.M{"_id": id, "user_id": claims.ID}).Decode(&wishlist); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	if wishlist.ShareToken == "" {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share wishlist"})
+			return
+		}
+		wishlist.ShareToken = hex.EncodeToString(token)
+		// Only set the token if a concurrent request has not, and hand out
+		// whichever token was stored so every caller gets the same link.
+		filter := bson.M{"_id": id, "share_token": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"share_token": wishlist.ShareToken, "updated_at": time.Now()}}
+		result, err := getWishlistCollection().UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share wishlist"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			if err := getWishlistCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&wishlist); err != nil || wishlist.ShareToken == "" {
+				c.JSON(http.StatusConflict, gin.H{"error": "Wishlist changed while sharing, please try again"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"share_token": wishlist.ShareToken,
+		"url":         clientURL("/wishlist/shared/" + wishlist.ShareToken),
+	})
+}
+
+// UnshareWishlist revokes the public link so it stops working.
+func UnshareWishlist(c *gin.Context) {
+	claims := c.MustGet("user").(*Middleware.UserClaims)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"share_token": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	result, err := getWishlistCollection().UpdateOne(context.Background(), bson.M{"_id": id, "user_id": claims.ID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unshare wishlist"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedWishlist shows a shared wishlist to anyone with the link, without
+// the owner's account details.
+func GetSharedWishlist(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wishlist Models.Wishlist
+	if err := getWishlistCollection().FindOne(ctx, bson.M{"share_token": token}).Decode(&wishlist); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+	if err := fillWishlistItems(ctx, &wishlist); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":       wishlist.Name,
+		"items":      wishlist.Items,
+		"updated_at": wishlist.UpdatedAt,
+	})
+}
+
+func createWishlist(ctx context.Context, userID primitive.ObjectID, name string) (Models.Wishlist, error) {
+	wishlist := Models.Wishlist{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		Items:     []Models.WishlistItem{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	count, err := getWishlistCollection().CountDocuments(ctx, bson.M{"user_id": userID, "name": name})
+	if err != nil {
+		return wishlist, err
+	}
+	if count > 0 {
+		return wishlist, errWishlistExists
+	}
+	_, err = getWishlistCollection().InsertOne(ctx, wishlist)
+	return wishlist, err
+}
+
+// addWishlistItem saves the product with its current price and stock to the
+// wishlist matched by filter. Saving a product twice keeps the first entry.
+func addWishlistItem(ctx context.Context, filter bson.M, productID primitive.ObjectID) error {
+	var product Models.Product
+	if err := getProductCollection().FindOne(ctx, bson.M{"_id": productID}).Decode(&product); err != nil {
+		return err
+	}
+
+	item := Models.WishlistItem{
+		ProductID:    productID,
+		SavedPrice:   product.Price,
+		SavedInStock: product.Stock > 0,
+		AddedAt:      time.Now(),
+	}
+	filter["items.product_id"] = bson.M{"$ne": productID}
+	update := bson.M{"$push": bson.M{"items": item}, "$set": bson.M{"updated_at": time.Now()}}
+	result, err := getWishlistCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		delete(filter, "items.product_id")
+		if count, err := getWishlistCollection().CountDocuments(ctx, filter); err != nil || count == 0 {
+			return errWishlistNotFound
+		}
+	}
+	return nil
+}
+
+func removeWishlistItem(ctx context.Context, id, userID, productID primitive.ObjectID) (bool, error) {
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"product_id": productID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := getWishlistCollection().UpdateOne(ctx, bson.M{"_id": id, "user_id": userID, "items.product_id": productID}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// fillWishlistItems attaches the current product details to each item and
+// flags price drops and restocks since the item was saved. Products that no
+// longer exist are shown as unavailable.
+func fillWishlistItems(ctx context.Context, wishlist *Models.Wishlist) error {
+	if len(wishlist.Items) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(wishlist.Items))
+	for i, item := range wishlist.Items {
+		ids[i] = item.ProductID
+	}
+	var products []Models.Product
+	cursor, err := getProductCollection().Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &products); err != nil {
+		return err
+	}
+	byID := make(map[primitive.ObjectID]Models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for i, item := range wishlist.Items {
+		product, ok := byID[item.ProductID]
+		if !ok {
+			continue
+		}
+		wishlist.Items[i].Name = product.Name
+		wishlist.Items[i].ImageURL = product.ImageURL
+		wishlist.Items[i].Price = product.Price
+		wishlist.Items[i].Stock = product.Stock
+		wishlist.Items[i].Available = product.Stock > 0
+		wishlist.Items[i].PriceDropped = product.Price.Cmp(item.SavedPrice) < 0
+		wishlist.Items[i].BackInStock = !item.SavedInStock && product.Stock > 0
+	}
+	return nil
+}
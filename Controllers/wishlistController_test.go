@@ -0,0 +1,96 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Server/Middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func wishlistContext(userID primitive.ObjectID, body string, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/wishlists", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("user", &Middleware.UserClaims{ID: userID, Role: Middleware.Customer})
+	return c, recorder
+}
+
+func TestMoveWishlistItemToCartCountsWhatIsAlreadyInTheCart(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("over stock", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, wishlistID, productID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.wishlists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, "test.products", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: productID},
+				{Key: "name", Value: "Mop"},
+				{Key: "stock", Value: 3},
+			}),
+			mtest.CreateCursorResponse(0, "test.carts", mtest.FirstBatch, bson.D{
+				{Key: "user_id", Value: userID},
+				{Key: "items", Value: bson.A{bson.D{{Key: "product_id", Value: productID}, {Key: "quantity", Value: 2}}}},
+			}),
+		)
+
+		c, recorder := wishlistContext(userID, `{"quantity":2}`,
+			gin.Param{Key: "id", Value: wishlistID.Hex()}, gin.Param{Key: "productId", Value: productID.Hex()})
+		MoveWishlistItemToCart(c)
+
+		if recorder.Code != http.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400 when the cart already holds 2 of the 3 in stock", recorder.Code)
+		}
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" || started.CommandName == "insert" {
+				mt.Fatal("nothing should be added to the cart")
+			}
+		}
+	})
+}
+
+func TestShareWishlistReturnsTheTokenThatWon(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("concurrent share", func(mt *mtest.T) {
+		Database = mt.DB
+		userID, wishlistID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.wishlists", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: wishlistID},
+				{Key: "user_id", Value: userID},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+			mtest.CreateCursorResponse(0, "test.wishlists", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: wishlistID},
+				{Key: "user_id", Value: userID},
+				{Key: "share_token", Value: "first"},
+			}),
+		)
+
+		c, recorder := wishlistContext(userID, "", gin.Param{Key: "id", Value: wishlistID.Hex()})
+		ShareWishlist(c)
+
+		var response struct {
+			ShareToken string `json:"share_token"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		if recorder.Code != http.StatusOK || response.ShareToken != "first" {
+			mt.Fatalf("got %d %s, want the token stored by the first request", recorder.Code, recorder.Body)
+		}
+		filter := updatesSent(mt)[0].Lookup("updates", "0", "q").Document()
+		if _, err := filter.LookupErr("share_token", "$exists"); err != nil {
+			mt.Fatalf("filter = %s, want the token set only when none exists", filter)
+		}
+	})
+}
@@ -38,12 +38,8 @@ func AuthMiddleware(requiredRole Role) gin.HandlerFunc {
 
 		tokenString := strings.TrimSpace(strings.Replace(authHeader, "Bearer", "", 1))
 
-		claims := &UserClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseToken(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
@@ -60,6 +56,31 @@ func AuthMiddleware(requiredRole Role) gin.HandlerFunc {
 	}
 }
 
+func ParseToken(tokenString string) (*UserClaims, error) {
+	claims := &UserClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return claims, nil
+}
+
+func (r Role) String() string {
+	switch r {
+	case Admin:
+		return "Admin"
+	case Staff:
+		return "Staff"
+	default:
+		return "Customer"
+	}
+}
+
 func GenerateJWT(userID primitive.ObjectID, role Role) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 
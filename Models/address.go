@@ -0,0 +1,48 @@
+package Models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Address struct {
+	Street   string `bson:"street" json:"street"`
+	Ward     string `bson:"ward" json:"ward"`
+	District string `bson:"district" json:"district"`
+	Province string `bson:"province" json:"province"`
+}
+
+func (a Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Street, a.Ward, a.District, a.Province} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type ServiceArea struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ServiceCategory primitive.ObjectID `bson:"servicecategory" json:"servicecategory"`
+	Province        string             `bson:"province" json:"province"`
+	District        string             `bson:"district" json:"district"`
+	Surcharge       Money              `bson:"surcharge" json:"surcharge"`
+	Active          bool               `bson:"active" json:"active"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type SavedAddress struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Label     string             `bson:"label" json:"label"`
+	Recipient string             `bson:"recipient" json:"recipient"`
+	Phone     string             `bson:"phone" json:"phone"`
+	Location  Address            `bson:"location" json:"location"`
+	IsDefault bool               `bson:"is_default" json:"is_default"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BookingPlan struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ServiceID        primitive.ObjectID `bson:"service_id" json:"service_id"`
+	Quantity         int                `bson:"quantity" json:"quantity"`
+	Area             float64            `bson:"area,omitempty" json:"area,omitempty"`
+	Hours            float64            `bson:"hours,omitempty" json:"hours,omitempty"`
+	AddOns           []string           `bson:"add_ons,omitempty" json:"add_ons,omitempty"`
+	RRule            string             `bson:"rrule,omitempty" json:"rrule,omitempty"`
+	Frequency        string             `bson:"frequency" json:"frequency"`
+	Interval         int                `bson:"interval" json:"interval"`
+	ByWeekday        []int              `bson:"by_weekday,omitempty" json:"by_weekday,omitempty"`
+	StartDate        string             `bson:"start_date" json:"start_date"`
+	PreferredTime    string             `bson:"preferred_time" json:"preferred_time"`
+	EndDate          string             `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	Count            int                `bson:"count,omitempty" json:"count,omitempty"`
+	PreferredStaffID primitive.ObjectID `bson:"preferred_staff_id,omitempty" json:"preferred_staff_id,omitempty"`
+	ContactName      string             `bson:"contact_name" json:"contact_name"`
+	ContactPhone     string             `bson:"contact_phone" json:"contact_phone"`
+	Address          string             `bson:"address" json:"address"`
+	Location         *Address           `bson:"location,omitempty" json:"location,omitempty"`
+	Note             string             `bson:"note" json:"note"`
+	Status           string             `bson:"status" json:"status"`
+	SkippedDates     []string           `bson:"skipped_dates" json:"skipped_dates"`
+	Conflicts        []PlanConflict     `bson:"conflicts,omitempty" json:"conflicts,omitempty"`
+	GeneratedUntil   time.Time          `bson:"generated_until" json:"generated_until"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type PlanConflict struct {
+	Date   string `bson:"date" json:"date"`
+	Reason string `bson:"reason" json:"reason"`
+}
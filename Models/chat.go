@@ -6,6 +6,24 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	MessageText        = "text"
+	MessageImage       = "image"
+	MessageFile        = "file"
+	MessageProductCard = "product_card"
+	MessageServiceCard = "service_card"
+	MessageBookingLink = "booking_link"
+
+	// MessageHeartbeat keeps a websocket connection marked online and is
+	// never stored.
+	MessageHeartbeat = "heartbeat"
+)
+
+const (
+	ChatEventMessage  = "message"
+	ChatEventPresence = "presence"
+)
+
 type SupportChat struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	CustomerID primitive.ObjectID `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
@@ -20,14 +38,49 @@ type SupportChat struct {
 }
 
 type Message struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChatID      primitive.ObjectID `bson:"chat_id" json:"chat_id"`
+	SenderID    primitive.ObjectID `bson:"sender_id,omitempty" json:"sender_id,omitempty"`
+	GuestName   string             `bson:"guest_name,omitempty" json:"guest_name,omitempty"`
+	SenderRole  string             `bson:"sender_role" json:"sender_role"`
+	Type        string             `bson:"type,omitempty" json:"type,omitempty"`
+	Content     string             `bson:"content" json:"content"`
+	Attachments []Attachment       `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	ProductID   primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
+	ServiceID   primitive.ObjectID `bson:"service_id,omitempty" json:"service_id,omitempty"`
+	Product     *Product           `bson:"-" json:"product,omitempty"`
+	Service     *Service           `bson:"-" json:"service,omitempty"`
+	Booking     *BookingLink       `bson:"-" json:"booking,omitempty"`
+	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`
+	Seen        bool               `bson:"seen" json:"seen"`
+}
+
+type Attachment struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL      string             `bson:"url" json:"url"`
+	FileName string             `bson:"file_name" json:"file_name"`
+	MimeType string             `bson:"mime_type" json:"mime_type"`
+	Size     int64              `bson:"size" json:"size"`
+}
+
+// ChatUpload is a file uploaded to a chat. Messages refer to uploads by ID
+// and the server copies the stored metadata into the message.
+type ChatUpload struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	ChatID     primitive.ObjectID `bson:"chat_id" json:"chat_id"`
-	SenderID   primitive.ObjectID `bson:"sender_id,omitempty" json:"sender_id,omitempty"`
-	GuestName  string             `bson:"guest_name,omitempty" json:"guest_name,omitempty"`
-	SenderRole string             `bson:"sender_role" json:"sender_role"`
-	Content    string             `bson:"content" json:"content"`
-	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
-	Seen       bool               `bson:"seen" json:"seen"`
+	UploaderID primitive.ObjectID `bson:"uploader_id,omitempty" json:"uploader_id,omitempty"`
+	URL        string             `bson:"url" json:"url"`
+	FileName   string             `bson:"file_name" json:"file_name"`
+	MimeType   string             `bson:"mime_type" json:"mime_type"`
+	Size       int64              `bson:"size" json:"size"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type BookingLink struct {
+	ServiceID   primitive.ObjectID `json:"service_id"`
+	ServiceName string             `json:"service_name"`
+	Price       Money              `json:"price"`
+	URL         string             `json:"url"`
 }
 
 type ChatNotification struct {
@@ -38,3 +91,18 @@ type ChatNotification struct {
 	LastMessage string             `bson:"last_message" json:"last_message"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 }
+
+type ChatEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type      string             `bson:"type" json:"type"`
+	Message   *Message           `bson:"message,omitempty" json:"message,omitempty"`
+	Presence  *PresenceEvent     `bson:"presence,omitempty" json:"presence,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PresenceEvent struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role     Role               `bson:"role" json:"role"`
+	IsOnline bool               `bson:"is_online" json:"is_online"`
+	LastSeen time.Time          `bson:"last_seen" json:"last_seen"`
+}
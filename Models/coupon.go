@@ -0,0 +1,48 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Coupon struct {
+	ID                primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Code              string               `bson:"code" json:"code"`
+	Description       string               `bson:"description" json:"description"`
+	Type              string               `bson:"type" json:"type"`
+	Value             float64              `bson:"value" json:"value"`
+	Amount            Money                `bson:"amount" json:"amount"`
+	MaxDiscount       Money                `bson:"max_discount" json:"max_discount"`
+	MinSpend          Money                `bson:"min_spend" json:"min_spend"`
+	StartsAt          time.Time            `bson:"starts_at" json:"starts_at"`
+	EndsAt            time.Time            `bson:"ends_at" json:"ends_at"`
+	UsageLimit        int                  `bson:"usage_limit" json:"usage_limit"`
+	PerUserLimit      int                  `bson:"per_user_limit" json:"per_user_limit"`
+	UsedCount         int                  `bson:"used_count" json:"used_count"`
+	ProductCategories []primitive.ObjectID `bson:"product_categories" json:"product_categories"`
+	ServiceCategories []primitive.ObjectID `bson:"service_categories" json:"service_categories"`
+	Active            bool                 `bson:"active" json:"active"`
+	CreatedAt         time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+type CouponRedemption struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CouponID   primitive.ObjectID `bson:"coupon_id" json:"coupon_id"`
+	Code       string             `bson:"code" json:"code"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TargetType string             `bson:"target_type" json:"target_type"`
+	TargetID   primitive.ObjectID `bson:"target_id" json:"target_id"`
+	Discount   Money              `bson:"discount" json:"discount"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ReleasedAt time.Time          `bson:"released_at,omitempty" json:"released_at,omitempty"`
+}
+
+type DiscountLine struct {
+	Source string             `bson:"source" json:"source"`
+	Code   string             `bson:"code" json:"code"`
+	Label  string             `bson:"label" json:"label"`
+	ItemID primitive.ObjectID `bson:"item_id,omitempty" json:"item_id,omitempty"`
+	Amount Money              `bson:"amount" json:"amount"`
+}
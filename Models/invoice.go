@@ -0,0 +1,41 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invoice is an immutable snapshot of a completed order or booking, so it
+// still reads the same after prices, tax rates or company details change.
+type Invoice struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Number       string             `bson:"number" json:"number"`
+	TargetType   string             `bson:"target_type" json:"target_type"`
+	TargetID     primitive.ObjectID `bson:"target_id" json:"target_id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Company      CompanyDetails     `bson:"company" json:"company"`
+	CustomerName string             `bson:"customer_name" json:"customer_name"`
+	Email        string             `bson:"email" json:"email"`
+	Phone        string             `bson:"phone" json:"phone"`
+	Address      string             `bson:"address" json:"address"`
+	Lines        []InvoiceLine      `bson:"lines" json:"lines"`
+	TaxInclusive bool               `bson:"tax_inclusive" json:"tax_inclusive"`
+	Currency     string             `bson:"currency" json:"currency"`
+	Subtotal     Money              `bson:"subtotal" json:"subtotal"`
+	Discount     Money              `bson:"discount" json:"discount"`
+	Shipping     Money              `bson:"shipping" json:"shipping"`
+	TaxTotal     Money              `bson:"tax_total" json:"tax_total"`
+	Total        Money              `bson:"total" json:"total"`
+	IssuedAt     time.Time          `bson:"issued_at" json:"issued_at"`
+}
+
+type InvoiceLine struct {
+	Description string  `bson:"description" json:"description"`
+	Quantity    int     `bson:"quantity" json:"quantity"`
+	UnitPrice   Money   `bson:"unit_price" json:"unit_price"`
+	Discount    Money   `bson:"discount" json:"discount"`
+	TaxRate     float64 `bson:"tax_rate" json:"tax_rate"`
+	TaxAmount   Money   `bson:"tax_amount" json:"tax_amount"`
+	Amount      Money   `bson:"amount" json:"amount"`
+}
@@ -0,0 +1,35 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PointsEntry is one movement on a customer's points ledger. Points is
+// signed; Remaining tracks how much of a credit has not yet been spent or
+// expired so redemptions and expiry can consume the oldest points first.
+type PointsEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Type        string             `bson:"type" json:"type"`
+	Points      int                `bson:"points" json:"points"`
+	Remaining   int                `bson:"remaining" json:"remaining"`
+	TargetType  string             `bson:"target_type,omitempty" json:"target_type,omitempty"`
+	TargetID    primitive.ObjectID `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Description string             `bson:"description" json:"description"`
+	ExpiresAt   time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	// Sources lists the credits a redemption was taken from, so returning
+	// the points keeps their original expiry.
+	Sources []PointsSource `bson:"sources,omitempty" json:"-"`
+	// ReversedAt is set when an earned credit is taken back or a redemption
+	// is returned, so that happens only once.
+	ReversedAt time.Time `bson:"reversed_at,omitempty" json:"-"`
+}
+
+type PointsSource struct {
+	CreditID  primitive.ObjectID `bson:"credit_id"`
+	Points    int                `bson:"points"`
+	ExpiresAt time.Time          `bson:"expires_at,omitempty"`
+}
@@ -0,0 +1,248 @@
+package Models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DefaultCurrency is the currency of every amount that does not say
+// otherwise.
+const DefaultCurrency = "VND"
+
+// Money is an amount in the minor unit of a currency. The currency defaults
+// to VND, whose minor unit is the đồng itself, so a VND amount is a whole
+// number of đồng.
+//
+// VND amounts are written to JSON and BSON as plain numbers, the same as the
+// float64 prices they replaced, so existing clients and documents keep
+// working. Amounts in any other currency are written as an object with
+// amount and currency. JSON accepts decimals and numeric strings from older
+// clients, and amounts stored as doubles before the migration are read back
+// rounded.
+//
+// Arithmetic between amounts in different currencies is a programming error
+// and panics. A zero amount takes the currency of the other operand.
+type Money struct {
+	amount   int64
+	currency string // empty for DefaultCurrency
+}
+
+// NewMoney converts a decimal amount in the default currency to Money. All
+// rounding of money goes through here: to the nearest minor unit, halves away
+// from zero.
+func NewMoney(amount float64) Money {
+	return Money{amount: int64(math.Round(amount))}
+}
+
+// Minor returns an amount of minor units of the default currency.
+func Minor(amount int64) Money {
+	return Money{amount: amount}
+}
+
+// MinorIn returns an amount of minor units of a currency.
+func MinorIn(amount int64, currency string) Money {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == DefaultCurrency {
+		currency = ""
+	}
+	return Money{amount: amount, currency: currency}
+}
+
+// MinorUnits is the amount as a whole number of minor units.
+func (m Money) MinorUnits() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() string {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+func (m Money) Float() float64 {
+	return float64(m.amount)
+}
+
+// with returns an amount in the currency of m, or of n when m is zero.
+func (m Money) with(n Money, amount int64) Money {
+	currency := m.currency
+	switch {
+	case m.amount == 0:
+		currency = n.currency
+	case n.amount != 0 && n.currency != m.currency:
+		panic(fmt.Sprintf("money: mixing %s and %s", m.Currency(), n.Currency()))
+	}
+	return Money{amount: amount, currency: currency}
+}
+
+func (m Money) Add(n Money) Money {
+	return m.with(n, m.amount+n.amount)
+}
+
+func (m Money) Sub(n Money) Money {
+	return m.with(n, m.amount-n.amount)
+}
+
+func (m Money) Neg() Money {
+	return Money{amount: -m.amount, currency: m.currency}
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than n.
+func (m Money) Cmp(n Money) int {
+	return m.Sub(n).Sign()
+}
+
+// Sign returns -1, 0 or +1 as the amount is negative, zero or positive.
+func (m Money) Sign() int {
+	switch {
+	case m.amount < 0:
+		return -1
+	case m.amount > 0:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+// Mul is the price of quantity units.
+func (m Money) Mul(quantity int) Money {
+	return Money{amount: m.amount * int64(quantity), currency: m.currency}
+}
+
+// Percent returns percent per cent of the amount, rounded.
+func (m Money) Percent(percent float64) Money {
+	return m.Scale(percent / 100)
+}
+
+// Scale multiplies the amount by a factor such as a number of hours,
+// rounded.
+func (m Money) Scale(factor float64) Money {
+	return Money{amount: NewMoney(float64(m.amount) * factor).amount, currency: m.currency}
+}
+
+// Share returns the part of the amount proportional to part/whole, rounded,
+// for splitting a discount across lines.
+func (m Money) Share(part, whole Money) Money {
+	if whole.amount == 0 {
+		return Money{currency: m.currency}
+	}
+	return m.Scale(float64(part.amount) / float64(whole.amount))
+}
+
+// NonNegative clamps the amount at zero.
+func (m Money) NonNegative() Money {
+	if m.amount < 0 {
+		return Money{currency: m.currency}
+	}
+	return m
+}
+
+func MinMoney(a, b Money) Money {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+// String formats a VND amount in đồng with dot thousands separators, e.g.
+// "1.250.000 đ". Other currencies are followed by their code.
+func (m Money) String() string {
+	amount, sign := m.amount, ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	if m.currency != "" {
+		return sign + b.String() + " " + m.currency
+	}
+	return sign + b.String() + " đ"
+}
+
+// moneyDocument is how an amount in a currency other than the default is
+// written.
+type moneyDocument struct {
+	Amount   int64  `bson:"amount" json:"amount"`
+	Currency string `bson:"currency" json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.currency == "" {
+		return strconv.AppendInt(nil, m.amount, 10), nil
+	}
+	return json.Marshal(moneyDocument{Amount: m.amount, Currency: m.currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if text := strings.TrimSpace(string(data)); strings.HasPrefix(text, "{") {
+		var document moneyDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("invalid money amount %s", data)
+		}
+		*m = MinorIn(document.Amount, document.Currency)
+		return nil
+	}
+
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money amount %s", data)
+	}
+	*m = NewMoney(amount)
+	return nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if m.currency == "" {
+		return bson.MarshalValue(m.amount)
+	}
+	return bson.MarshalValue(moneyDocument{Amount: m.amount, Currency: m.currency})
+}
+
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bson.TypeInt64:
+		*m = Minor(value.Int64())
+	case bson.TypeInt32:
+		*m = Minor(int64(value.Int32()))
+	case bson.TypeDouble:
+		*m = NewMoney(value.Double())
+	case bson.TypeDecimal128:
+		amount, err := strconv.ParseFloat(value.Decimal128().String(), 64)
+		if err != nil {
+			return err
+		}
+		*m = NewMoney(amount)
+	case bson.TypeEmbeddedDocument:
+		var document moneyDocument
+		if err := value.Unmarshal(&document); err != nil {
+			return err
+		}
+		*m = MinorIn(document.Amount, document.Currency)
+	case bson.TypeNull, bson.TypeUndefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("cannot decode %s into Money", t)
+	}
+	return nil
+}
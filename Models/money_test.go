@@ -0,0 +1,75 @@
+package Models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMoneyJSONKeepsPlainNumbersForVND(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{Minor(125000)})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"price":125000}` {
+		t.Fatalf("got %s, want a plain number", data)
+	}
+
+	for input, want := range map[string]Money{
+		`125000`:                             Minor(125000),
+		`"125000"`:                           Minor(125000),
+		`1250.5`:                             Minor(1251),
+		`{"amount":1999,"currency":"usd"}`:   MinorIn(1999, "USD"),
+		`{"amount":125000,"currency":"VND"}`: Minor(125000),
+	} {
+		var got Money
+		if err := json.Unmarshal([]byte(input), &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("Unmarshal(%s) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestMoneyBSONRoundTripsCurrency(t *testing.T) {
+	type document struct {
+		Price Money `bson:"price"`
+	}
+	for _, price := range []Money{Minor(125000), MinorIn(1999, "USD")} {
+		data, err := bson.Marshal(document{price})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var decoded document
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if decoded.Price != price {
+			t.Fatalf("round trip = %s, want %s", decoded.Price, price)
+		}
+	}
+
+	data, _ := bson.Marshal(bson.M{"price": 1250.5})
+	var legacy document
+	if err := bson.Unmarshal(data, &legacy); err != nil || legacy.Price != Minor(1251) {
+		t.Fatalf("legacy double = %s (%v), want it rounded to 1.251 đ", legacy.Price, err)
+	}
+}
+
+func TestMoneyArithmeticChecksCurrency(t *testing.T) {
+	usd := MinorIn(500, "USD")
+	if got := (Money{}).Add(usd); got != usd {
+		t.Fatalf("zero + %s = %s, want the zero to take the currency", usd, got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("adding VND to USD should panic")
+		}
+	}()
+	Minor(1000).Add(usd)
+}
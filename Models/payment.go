@@ -0,0 +1,75 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Payment struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TargetType     string             `bson:"target_type" json:"target_type"`
+	TargetID       primitive.ObjectID `bson:"target_id" json:"target_id"`
+	Provider       string             `bson:"provider" json:"provider"`
+	Amount         Money              `bson:"amount" json:"amount"`
+	RefundedAmount Money              `bson:"refunded_amount" json:"refunded_amount"`
+	Currency       string             `bson:"currency" json:"currency"`
+	Status         string             `bson:"status" json:"status"`
+	TransactionRef string             `bson:"transaction_ref" json:"transaction_ref"`
+	ProviderRef    string             `bson:"provider_ref,omitempty" json:"provider_ref,omitempty"`
+	PaymentURL     string             `bson:"payment_url,omitempty" json:"payment_url,omitempty"`
+	FailureReason  string             `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	PaidAt         time.Time          `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	// LedgerOutbox holds ledger entries written together with a status
+	// change that have not reached the ledger yet.
+	LedgerOutbox []LedgerEntry `bson:"ledger_outbox,omitempty" json:"-"`
+}
+
+type LedgerEntry struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PaymentID      primitive.ObjectID `bson:"payment_id" json:"payment_id"`
+	TargetType     string             `bson:"target_type" json:"target_type"`
+	TargetID       primitive.ObjectID `bson:"target_id" json:"target_id"`
+	Provider       string             `bson:"provider" json:"provider"`
+	Type           string             `bson:"type" json:"type"`
+	Amount         Money              `bson:"amount" json:"amount"`
+	TransactionRef string             `bson:"transaction_ref" json:"transaction_ref"`
+	ProviderRef    string             `bson:"provider_ref,omitempty" json:"provider_ref,omitempty"`
+	Note           string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedBy      primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type SettlementRow struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Provider       string             `bson:"provider" json:"provider"`
+	Date           string             `bson:"date" json:"date"`
+	Type           string             `bson:"type" json:"type"`
+	TransactionRef string             `bson:"transaction_ref" json:"transaction_ref"`
+	ProviderRef    string             `bson:"provider_ref" json:"provider_ref"`
+	Amount         Money              `bson:"amount" json:"amount"`
+	SettledAt      time.Time          `bson:"settled_at" json:"settled_at"`
+	ImportedAt     time.Time          `bson:"imported_at" json:"imported_at"`
+}
+
+type ReconciliationReport struct {
+	ID           primitive.ObjectID       `bson:"_id,omitempty" json:"id"`
+	Provider     string                   `bson:"provider" json:"provider"`
+	Date         string                   `bson:"date" json:"date"`
+	LedgerTotal  Money                    `bson:"ledger_total" json:"ledger_total"`
+	SettledTotal Money                    `bson:"settled_total" json:"settled_total"`
+	Matched      int                      `bson:"matched" json:"matched"`
+	Mismatches   []ReconciliationMismatch `bson:"mismatches" json:"mismatches"`
+	CreatedAt    time.Time                `bson:"created_at" json:"created_at"`
+}
+
+type ReconciliationMismatch struct {
+	Kind             string `bson:"kind" json:"kind"`
+	Type             string `bson:"type" json:"type"`
+	TransactionRef   string `bson:"transaction_ref" json:"transaction_ref"`
+	LedgerAmount     Money  `bson:"ledger_amount" json:"ledger_amount"`
+	SettlementAmount Money  `bson:"settlement_amount" json:"settlement_amount"`
+}
@@ -7,8 +7,12 @@ import (
 type Product struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name            string             `bson:"name" json:"name"`
-	Price           float64            `bson:"price" json:"price"`
+	Price           Money              `bson:"price" json:"price"`
 	Stock           int                `bson:"stock" json:"stock"`
+	Weight          int                `bson:"weight" json:"weight"`
 	ProductCategory primitive.ObjectID `bson:"productcategory" json:"productcategory"`
 	ImageURL        string             `bson:"imageurl" json:"imageurl"`
+	TaxClass        string             `bson:"tax_class,omitempty" json:"tax_class,omitempty"`
+	RatingAverage   float64            `bson:"rating_average" json:"rating_average"`
+	RatingCount     int                `bson:"rating_count" json:"rating_count"`
 }
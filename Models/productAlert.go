@@ -0,0 +1,68 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductAlert is a customer's subscription to a restock or a price drop of
+// a product. A price drop alert with a target price only fires once the
+// price reaches it.
+type ProductAlert struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ProductID      primitive.ObjectID `bson:"product_id" json:"product_id"`
+	Type           string             `bson:"type" json:"type"`
+	TargetPrice    Money              `bson:"target_price,omitempty" json:"target_price,omitempty"`
+	Active         bool               `bson:"active" json:"active"`
+	LastEventID    primitive.ObjectID `bson:"last_event_id,omitempty" json:"-"`
+	LastNotifiedAt time.Time          `bson:"last_notified_at,omitempty" json:"last_notified_at,omitempty"`
+	SendingUntil   time.Time          `bson:"sending_until,omitempty" json:"-"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ProductEvent records a change to a product that customers may be waiting
+// for. Events are stored so the notifier can deliver them after a restart.
+type ProductEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type      string             `bson:"type" json:"type"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	OldPrice  Money              `bson:"old_price" json:"old_price"`
+	NewPrice  Money              `bson:"new_price" json:"new_price"`
+	OldStock  int                `bson:"old_stock" json:"old_stock"`
+	NewStock  int                `bson:"new_stock" json:"new_stock"`
+	Status    string             `bson:"status" json:"status"`
+	ClaimedAt time.Time          `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ProductAlertDelivery is one alert owed to a subscriber for one event. It
+// stays pending until the alert is sent, is retried when sending fails and is
+// held back while the customer is over their daily limit.
+type ProductAlertDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AlertID       primitive.ObjectID `bson:"alert_id" json:"alert_id"`
+	EventID       primitive.ObjectID `bson:"event_id" json:"event_id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Status        string             `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	ClaimedAt     time.Time          `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Notification is an in-app message shown to a user.
+type Notification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Type      string             `bson:"type" json:"type"`
+	Title     string             `bson:"title" json:"title"`
+	Body      string             `bson:"body" json:"body"`
+	Link      string             `bson:"link,omitempty" json:"link,omitempty"`
+	ProductID primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
+	Read      bool               `bson:"read" json:"read"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Promotion struct {
+	ID                primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name              string               `bson:"name" json:"name"`
+	Description       string               `bson:"description" json:"description"`
+	Type              string               `bson:"type" json:"type"`
+	TargetType        string               `bson:"target_type" json:"target_type"`
+	ProductCategories []primitive.ObjectID `bson:"product_categories" json:"product_categories"`
+	ServiceCategories []primitive.ObjectID `bson:"service_categories" json:"service_categories"`
+	BuyQuantity       int                  `bson:"buy_quantity" json:"buy_quantity"`
+	FreeQuantity      int                  `bson:"free_quantity" json:"free_quantity"`
+	Value             float64              `bson:"value" json:"value"`
+	Amount            Money                `bson:"amount" json:"amount"`
+	MaxDiscount       Money                `bson:"max_discount" json:"max_discount"`
+	MinSpend          Money                `bson:"min_spend" json:"min_spend"`
+	WithOrderHours    int                  `bson:"with_order_hours" json:"with_order_hours"`
+	StartsAt          time.Time            `bson:"starts_at" json:"starts_at"`
+	EndsAt            time.Time            `bson:"ends_at" json:"ends_at"`
+	Priority          int                  `bson:"priority" json:"priority"`
+	Active            bool                 `bson:"active" json:"active"`
+	CreatedAt         time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// AppliedPromotion explains the outcome of one promotion against a cart.
+type AppliedPromotion struct {
+	PromotionID primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	Name        string             `bson:"name" json:"name"`
+	Applied     bool               `bson:"applied" json:"applied"`
+	Reason      string             `bson:"reason" json:"reason"`
+	Discount    Money              `bson:"discount" json:"discount"`
+}
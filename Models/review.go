@@ -0,0 +1,27 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Review is a customer's rating of a product or service they bought. Only
+// approved reviews are public and count toward the rating of the item.
+type Review struct {
+	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	TargetType     string               `bson:"target_type" json:"target_type"`
+	TargetID       primitive.ObjectID   `bson:"target_id" json:"target_id"`
+	UserID         primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	UserName       string               `bson:"user_name" json:"user_name"`
+	Rating         int                  `bson:"rating" json:"rating"`
+	Comment        string               `bson:"comment" json:"comment"`
+	Photos         []string             `bson:"photos,omitempty" json:"photos,omitempty"`
+	Status         string               `bson:"status" json:"status"`
+	ModeratedBy    primitive.ObjectID   `bson:"moderated_by,omitempty" json:"moderated_by,omitempty"`
+	ModerationNote string               `bson:"moderation_note,omitempty" json:"moderation_note,omitempty"`
+	HelpfulCount   int                  `bson:"helpful_count" json:"helpful_count"`
+	HelpfulVoters  []primitive.ObjectID `bson:"helpful_voters,omitempty" json:"-"`
+	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+}
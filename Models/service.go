@@ -7,8 +7,41 @@ import (
 type Service struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name            string             `bson:"name" json:"name"`
-	Price           float64            `bson:"price" json:"price"`
+	Price           Money              `bson:"price" json:"price"`
 	Description     string             `bson:"description" json:"description"`
 	ServiceCategory primitive.ObjectID `bson:"servicecategory" json:"servicecategory"`
 	ImageURL        string             `bson:"imageurl" json:"imageurl"`
+	DurationMinutes int                `bson:"duration_minutes" json:"duration_minutes"`
+	SlotCapacity    int                `bson:"slot_capacity,omitempty" json:"slot_capacity,omitempty"`
+	Pricing         *ServicePricing    `bson:"pricing,omitempty" json:"pricing,omitempty"`
+	TaxClass        string             `bson:"tax_class,omitempty" json:"tax_class,omitempty"`
+	RatingAverage   float64            `bson:"rating_average" json:"rating_average"`
+	RatingCount     int                `bson:"rating_count" json:"rating_count"`
+}
+
+type ServicePricing struct {
+	AreaTiers               []AreaTier     `bson:"area_tiers" json:"area_tiers"`
+	HourlyRate              Money          `bson:"hourly_rate" json:"hourly_rate"`
+	MinHours                float64        `bson:"min_hours" json:"min_hours"`
+	AddOns                  []ServiceAddOn `bson:"add_ons" json:"add_ons"`
+	WeekendSurchargePercent float64        `bson:"weekend_surcharge_percent" json:"weekend_surcharge_percent"`
+	HolidaySurchargePercent float64        `bson:"holiday_surcharge_percent" json:"holiday_surcharge_percent"`
+	Holidays                []string       `bson:"holidays" json:"holidays"`
+}
+
+type AreaTier struct {
+	MaxArea float64 `bson:"max_area" json:"max_area"`
+	Price   Money   `bson:"price" json:"price"`
+}
+
+type ServiceAddOn struct {
+	Code  string `bson:"code" json:"code"`
+	Name  string `bson:"name" json:"name"`
+	Price Money  `bson:"price" json:"price"`
+}
+
+type PriceLine struct {
+	Code   string `bson:"code" json:"code"`
+	Label  string `bson:"label" json:"label"`
+	Amount Money  `bson:"amount" json:"amount"`
 }
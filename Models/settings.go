@@ -0,0 +1,56 @@
+package Models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type BookingSettings struct {
+	OpenTime       string   `bson:"open_time" json:"open_time"`
+	CloseTime      string   `bson:"close_time" json:"close_time"`
+	WorkingDays    []int    `bson:"working_days" json:"working_days"`
+	BlackoutDates  []string `bson:"blackout_dates" json:"blackout_dates"`
+	SlotMinutes    int      `bson:"slot_minutes" json:"slot_minutes"`
+	SlotCapacity   int      `bson:"slot_capacity" json:"slot_capacity"`
+	MinLeadMinutes int      `bson:"min_lead_minutes" json:"min_lead_minutes"`
+}
+
+type CancellationSettings struct {
+	FreeCancelHours       int     `bson:"free_cancel_hours" json:"free_cancel_hours"`
+	LateCancelFeePercent  float64 `bson:"late_cancel_fee_percent" json:"late_cancel_fee_percent"`
+	RescheduleCutoffHours int     `bson:"reschedule_cutoff_hours" json:"reschedule_cutoff_hours"`
+	MaxReschedules        int     `bson:"max_reschedules" json:"max_reschedules"`
+}
+
+type LoyaltySettings struct {
+	EarnRate         float64        `bson:"earn_rate" json:"earn_rate"`
+	CategoryRates    []CategoryRate `bson:"category_rates" json:"category_rates"`
+	PointValue       float64        `bson:"point_value" json:"point_value"`
+	MaxRedeemPercent float64        `bson:"max_redeem_percent" json:"max_redeem_percent"`
+	ExpiryDays       int            `bson:"expiry_days" json:"expiry_days"`
+}
+
+// CategoryRate overrides the earn rate for a product or service category.
+type CategoryRate struct {
+	Category primitive.ObjectID `bson:"category" json:"category"`
+	EarnRate float64            `bson:"earn_rate" json:"earn_rate"`
+}
+
+type TaxSettings struct {
+	PricesIncludeTax bool           `bson:"prices_include_tax" json:"prices_include_tax"`
+	DefaultClass     string         `bson:"default_class" json:"default_class"`
+	Classes          []TaxClass     `bson:"classes" json:"classes"`
+	InvoicePrefix    string         `bson:"invoice_prefix" json:"invoice_prefix"`
+	Company          CompanyDetails `bson:"company" json:"company"`
+}
+
+type TaxClass struct {
+	Code string  `bson:"code" json:"code"`
+	Name string  `bson:"name" json:"name"`
+	Rate float64 `bson:"rate" json:"rate"`
+}
+
+type CompanyDetails struct {
+	Name    string `bson:"name" json:"name"`
+	TaxCode string `bson:"tax_code" json:"tax_code"`
+	Address string `bson:"address" json:"address"`
+	Phone   string `bson:"phone" json:"phone"`
+	Email   string `bson:"email" json:"email"`
+}
@@ -0,0 +1,38 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShippingMethod struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code          string             `bson:"code" json:"code"`
+	Name          string             `bson:"name" json:"name"`
+	Carrier       string             `bson:"carrier" json:"carrier"`
+	RateType      string             `bson:"rate_type" json:"rate_type"`
+	FlatFee       Money              `bson:"flat_fee" json:"flat_fee"`
+	WeightRates   []WeightRate       `bson:"weight_rates" json:"weight_rates"`
+	FreeAbove     Money              `bson:"free_above" json:"free_above"`
+	Provinces     []string           `bson:"provinces" json:"provinces"`
+	EstimatedDays int                `bson:"estimated_days" json:"estimated_days"`
+	Active        bool               `bson:"active" json:"active"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type WeightRate struct {
+	MaxWeight int   `bson:"max_weight" json:"max_weight"`
+	Fee       Money `bson:"fee" json:"fee"`
+}
+
+type OrderShipping struct {
+	MethodID       primitive.ObjectID `bson:"method_id" json:"method_id"`
+	Method         string             `bson:"method" json:"method"`
+	Carrier        string             `bson:"carrier" json:"carrier"`
+	Fee            Money              `bson:"fee" json:"fee"`
+	Weight         int                `bson:"weight" json:"weight"`
+	TrackingNumber string             `bson:"tracking_number,omitempty" json:"tracking_number,omitempty"`
+	ShippedAt      time.Time          `bson:"shipped_at,omitempty" json:"shipped_at,omitempty"`
+}
@@ -0,0 +1,27 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StaffProfile struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	User          *User                `bson:"-" json:"user,omitempty"`
+	Skills        []primitive.ObjectID `bson:"skills" json:"skills"`
+	ServiceAreas  []string             `bson:"service_areas" json:"service_areas"`
+	Schedule      []WorkingShift       `bson:"schedule" json:"schedule"`
+	Active        bool                 `bson:"active" json:"active"`
+	RatingAverage float64              `bson:"rating_average" json:"rating_average"`
+	RatingCount   int                  `bson:"rating_count" json:"rating_count"`
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+type WorkingShift struct {
+	Weekday   int    `bson:"weekday" json:"weekday"`
+	StartTime string `bson:"start_time" json:"start_time"`
+	EndTime   string `bson:"end_time" json:"end_time"`
+}
@@ -35,12 +35,17 @@ type Cart struct {
 	Items     []CartItem         `bson:"items,omitempty" json:"items,omitempty"`
 	CreatedAt time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
 	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+
+	Subtotal   Money              `bson:"-" json:"subtotal,omitempty"`
+	Discounts  []DiscountLine     `bson:"-" json:"discounts,omitempty"`
+	Promotions []AppliedPromotion `bson:"-" json:"promotions,omitempty"`
+	Total      Money              `bson:"-" json:"total,omitempty"`
 }
 
 type CartItem struct {
 	ProductID primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
 	Quantity  int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
-	Price     float64            `bson:"price,omitempty" json:"price,omitempty"`
+	Price     Money              `bson:"price,omitempty" json:"price,omitempty"`
 	Name      string             `bson:"-" json:"name,omitempty"`
 	ImageURL  string             `bson:"-" json:"imageurl,omitempty"`
 }
@@ -56,43 +61,103 @@ type SelectedItems struct {
 type SelectedItem struct {
 	ProductID primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
 	Quantity  int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
-	Price     float64            `bson:"price,omitempty" json:"price,omitempty"`
+	Price     Money              `bson:"price,omitempty" json:"price,omitempty"`
 	Name      string             `bson:"name,omitempty" json:"name,omitempty"`
 	ImageURL  string             `bson:"imageurl,omitempty" json:"imageurl,omitempty"`
 }
 
 type Order struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID     primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
-	User       User               `bson:"-" json:"user,omitempty"`
-	Items      []OrderItem        `bson:"items,omitempty" json:"items,omitempty"`
-	TotalPrice float64            `bson:"total_price,omitempty" json:"total_price,omitempty"`
-	Status     string             `bson:"status,omitempty" json:"status,omitempty"`
-	CreatedAt  time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt  time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	User          User               `bson:"-" json:"user,omitempty"`
+	Items         []OrderItem        `bson:"items,omitempty" json:"items,omitempty"`
+	TotalPrice    Money              `bson:"total_price,omitempty" json:"total_price,omitempty"`
+	AddressID     primitive.ObjectID `bson:"address_id,omitempty" json:"address_id,omitempty"`
+	Recipient     string             `bson:"recipient,omitempty" json:"recipient,omitempty"`
+	Phone         string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	Address       *Address           `bson:"address,omitempty" json:"address,omitempty"`
+	Subtotal      Money              `bson:"subtotal,omitempty" json:"subtotal,omitempty"`
+	Shipping      *OrderShipping     `bson:"shipping,omitempty" json:"shipping,omitempty"`
+	CouponCode    string             `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	Discounts     []DiscountLine     `bson:"discounts,omitempty" json:"discounts,omitempty"`
+	DiscountTotal Money              `bson:"discount_total,omitempty" json:"discount_total,omitempty"`
+	Promotions    []AppliedPromotion `bson:"promotions,omitempty" json:"promotions,omitempty"`
+	PointsUsed    int                `bson:"points_used,omitempty" json:"points_used,omitempty"`
+	TaxTotal      Money              `bson:"tax_total,omitempty" json:"tax_total,omitempty"`
+	TaxInclusive  bool               `bson:"tax_inclusive,omitempty" json:"tax_inclusive,omitempty"`
+	Currency      string             `bson:"currency,omitempty" json:"currency,omitempty"`
+	InvoiceNumber string             `bson:"invoice_number,omitempty" json:"invoice_number,omitempty"`
+	PaymentMethod string             `bson:"payment_method,omitempty" json:"payment_method,omitempty"`
+	PaymentStatus string             `bson:"payment_status,omitempty" json:"payment_status,omitempty"`
+	Status        string             `bson:"status,omitempty" json:"status,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
 }
 
 type OrderItem struct {
 	ProductID primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
 	Quantity  int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
-	Price     float64            `bson:"price,omitempty" json:"price,omitempty"`
+	Price     Money              `bson:"price,omitempty" json:"price,omitempty"`
 	Name      string             `bson:"name,omitempty" json:"name,omitempty"`
 	ImageURL  string             `bson:"imageurl,omitempty" json:"imageurl,omitempty"`
+	TaxClass  string             `bson:"tax_class,omitempty" json:"tax_class,omitempty"`
+	TaxRate   float64            `bson:"tax_rate,omitempty" json:"tax_rate,omitempty"`
+	TaxAmount Money              `bson:"tax_amount,omitempty" json:"tax_amount,omitempty"`
 }
 
 type OrderBookingService struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
-	ServiceID    primitive.ObjectID `bson:"service_id" json:"service_id"`
-	Quantity     int                `bson:"quantity" json:"quantity"`
-	TotalPrice   float64            `bson:"total_price" json:"total_price"`
-	BookingDate  primitive.DateTime `bson:"booking_date" json:"booking_date"`
-	ContactName  string             `bson:"contact_name" json:"contact_name"`
-	ContactPhone string             `bson:"contact_phone" json:"contact_phone"`
-	Address      string             `bson:"address" json:"address"`
-	Status       string             `bson:"status" json:"status"`
-	CreatedAt    primitive.DateTime `bson:"created_at" json:"created_at"`
-	UpdatedAt    primitive.DateTime `bson:"updated_at" json:"updated_at"`
-	FinishAt     primitive.DateTime `bson:"finish_at" json:"finish_at"`
-	Note         string             `bson:"note" json:"note"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ServiceID     primitive.ObjectID `bson:"service_id" json:"service_id"`
+	Quantity      int                `bson:"quantity" json:"quantity"`
+	TotalPrice    Money              `bson:"total_price" json:"total_price"`
+	Area          float64            `bson:"area,omitempty" json:"area,omitempty"`
+	Hours         float64            `bson:"hours,omitempty" json:"hours,omitempty"`
+	AddOns        []string           `bson:"add_ons,omitempty" json:"add_ons,omitempty"`
+	PriceLines    []PriceLine        `bson:"price_lines,omitempty" json:"price_lines,omitempty"`
+	CouponCode    string             `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	Discounts     []DiscountLine     `bson:"discounts,omitempty" json:"discounts,omitempty"`
+	Promotions    []AppliedPromotion `bson:"promotions,omitempty" json:"promotions,omitempty"`
+	PointsUsed    int                `bson:"points_used,omitempty" json:"points_used,omitempty"`
+	TaxClass      string             `bson:"tax_class,omitempty" json:"tax_class,omitempty"`
+	TaxRate       float64            `bson:"tax_rate,omitempty" json:"tax_rate,omitempty"`
+	TaxAmount     Money              `bson:"tax_amount,omitempty" json:"tax_amount,omitempty"`
+	TaxInclusive  bool               `bson:"tax_inclusive,omitempty" json:"tax_inclusive,omitempty"`
+	Currency      string             `bson:"currency,omitempty" json:"currency,omitempty"`
+	InvoiceNumber string             `bson:"invoice_number,omitempty" json:"invoice_number,omitempty"`
+	BookingDate   primitive.DateTime `bson:"booking_date" json:"booking_date"`
+	BookingEnd    primitive.DateTime `bson:"booking_end" json:"booking_end"`
+	SlotKeys      []string           `bson:"slot_keys,omitempty" json:"-"`
+	ContactName   string             `bson:"contact_name" json:"contact_name"`
+	ContactPhone  string             `bson:"contact_phone" json:"contact_phone"`
+	Address       string             `bson:"address" json:"address"`
+	Location      *Address           `bson:"location,omitempty" json:"location,omitempty"`
+	AddressID     primitive.ObjectID `bson:"address_id,omitempty" json:"address_id,omitempty"`
+	Status        string             `bson:"status" json:"status"`
+	PaymentMethod string             `bson:"payment_method,omitempty" json:"payment_method,omitempty"`
+	PaymentStatus string             `bson:"payment_status,omitempty" json:"payment_status,omitempty"`
+	StaffID       primitive.ObjectID `bson:"staff_id,omitempty" json:"staff_id,omitempty"`
+	PlanID        primitive.ObjectID `bson:"plan_id,omitempty" json:"plan_id,omitempty"`
+	Occurrence    string             `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	AssignedAt    primitive.DateTime `bson:"assigned_at,omitempty" json:"assigned_at,omitempty"`
+	CreatedAt     primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt     primitive.DateTime `bson:"updated_at" json:"updated_at"`
+	FinishAt      primitive.DateTime `bson:"finish_at" json:"finish_at"`
+	Note          string             `bson:"note" json:"note"`
+
+	CheckInAt      primitive.DateTime `bson:"check_in_at,omitempty" json:"check_in_at,omitempty"`
+	CheckOutAt     primitive.DateTime `bson:"check_out_at,omitempty" json:"check_out_at,omitempty"`
+	CompletionNote string             `bson:"completion_note,omitempty" json:"completion_note,omitempty"`
+	BeforePhotos   []string           `bson:"before_photos,omitempty" json:"before_photos,omitempty"`
+	AfterPhotos    []string           `bson:"after_photos,omitempty" json:"after_photos,omitempty"`
+	ConfirmedAt    primitive.DateTime `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+
+	StaffRating  int                `bson:"staff_rating,omitempty" json:"staff_rating,omitempty"`
+	StaffComment string             `bson:"staff_comment,omitempty" json:"staff_comment,omitempty"`
+	StaffRatedAt primitive.DateTime `bson:"staff_rated_at,omitempty" json:"staff_rated_at,omitempty"`
+
+	RescheduleCount    int                `bson:"reschedule_count,omitempty" json:"reschedule_count,omitempty"`
+	CancelledAt        primitive.DateTime `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CancellationReason string             `bson:"cancellation_reason,omitempty" json:"cancellation_reason,omitempty"`
+	CancellationFee    Money              `bson:"cancellation_fee,omitempty" json:"cancellation_fee,omitempty"`
 }
@@ -0,0 +1,36 @@
+package Models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wishlist is a named list of products a customer wants to buy later. A list
+// with a share token can be viewed read-only by anyone with the link.
+type Wishlist struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Items      []WishlistItem     `bson:"items" json:"items"`
+	ShareToken string             `bson:"share_token,omitempty" json:"share_token,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WishlistItem remembers the price and stock of a product when it was saved,
+// so the list can point out price drops and restocks since then.
+type WishlistItem struct {
+	ProductID    primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SavedPrice   Money              `bson:"saved_price" json:"saved_price"`
+	SavedInStock bool               `bson:"saved_in_stock" json:"saved_in_stock"`
+	AddedAt      time.Time          `bson:"added_at" json:"added_at"`
+
+	Name         string `bson:"-" json:"name,omitempty"`
+	ImageURL     string `bson:"-" json:"imageurl,omitempty"`
+	Price        Money  `bson:"-" json:"price"`
+	Stock        int    `bson:"-" json:"stock"`
+	Available    bool   `bson:"-" json:"available"`
+	PriceDropped bool   `bson:"-" json:"price_dropped"`
+	BackInStock  bool   `bson:"-" json:"back_in_stock"`
+}
@@ -31,6 +31,7 @@ func SetupRoutes(router *gin.Engine) {
 		api.POST("/product", Middleware.AuthMiddleware(Middleware.Staff), Controllers.CreateProduct)
 		api.PUT("/product/:id", Middleware.AuthMiddleware(Middleware.Staff), Controllers.UpdateProduct)
 		api.DELETE("/product/:id", Middleware.AuthMiddleware(Middleware.Staff), Controllers.DeleteProduct)
+		api.POST("/product/:id/alerts", Middleware.AuthMiddleware(Middleware.Customer), Controllers.SubscribeProductAlert)
 
 		// ServiceCategory routes
 		api.GET("/servicecategories", Controllers.GetAllServiceCategories)
@@ -45,18 +46,104 @@ func SetupRoutes(router *gin.Engine) {
 		api.POST("/service", Middleware.AuthMiddleware(Middleware.Staff), Controllers.CreateService)
 		api.PUT("/service/:id", Middleware.AuthMiddleware(Middleware.Staff), Controllers.UpdateService)
 		api.DELETE("/service/:id", Middleware.AuthMiddleware(Middleware.Staff), Controllers.DeleteService)
+		api.GET("/services/:id/availability", Controllers.GetServiceAvailability)
+		api.POST("/services/:id/quote", Controllers.QuoteService)
+		api.PUT("/service/:id/pricing", Middleware.AuthMiddleware(Middleware.Staff), Controllers.UpdateServicePricing)
+
+		// Settings routes
+		api.GET("/settings/booking", Controllers.GetBookingSettings)
+		api.PUT("/settings/booking", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateBookingSettings)
+		api.GET("/settings/cancellation", Controllers.GetCancellationSettings)
+		api.PUT("/settings/cancellation", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateCancellationSettings)
+		api.GET("/settings/loyalty", Controllers.GetLoyaltySettings)
+		api.PUT("/settings/loyalty", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateLoyaltySettings)
+		api.GET("/settings/tax", Controllers.GetTaxSettings)
+		api.PUT("/settings/tax", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateTaxSettings)
 
 		// Cart routes
 		api.GET("/cart", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetCart)
 		api.POST("/cart/add", Middleware.AuthMiddleware(Middleware.Customer), Controllers.AddToCart)
 		api.DELETE("/cart/remove", Middleware.AuthMiddleware(Middleware.Customer), Controllers.RemoveFromCart)
 		api.POST("/cart/update", Middleware.AuthMiddleware(Middleware.Customer), Controllers.UpdateCart)
+		api.POST("/cart/save-for-later", Middleware.AuthMiddleware(Middleware.Customer), Controllers.SaveCartItemForLater)
+
+		// Wishlist routes
+		api.GET("/wishlists", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetWishlists)
+		api.GET("/wishlists/shared/:token", Controllers.GetSharedWishlist)
+		api.POST("/wishlist", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateWishlist)
+		api.PUT("/wishlist/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.RenameWishlist)
+		api.DELETE("/wishlist/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.DeleteWishlist)
+		api.POST("/wishlist/:id/items", Middleware.AuthMiddleware(Middleware.Customer), Controllers.AddToWishlist)
+		api.DELETE("/wishlist/:id/items/:productId", Middleware.AuthMiddleware(Middleware.Customer), Controllers.RemoveFromWishlist)
+		api.POST("/wishlist/:id/items/:productId/move-to-cart", Middleware.AuthMiddleware(Middleware.Customer), Controllers.MoveWishlistItemToCart)
+		api.POST("/wishlist/:id/share", Middleware.AuthMiddleware(Middleware.Customer), Controllers.ShareWishlist)
+		api.DELETE("/wishlist/:id/share", Middleware.AuthMiddleware(Middleware.Customer), Controllers.UnshareWishlist)
+
+		// Address routes
+		api.GET("/addresses", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetAddresses)
+		api.POST("/address", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateAddress)
+		api.PUT("/address/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.UpdateAddress)
+		api.DELETE("/address/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.DeleteAddress)
+		api.POST("/address/:id/default", Middleware.AuthMiddleware(Middleware.Customer), Controllers.SetDefaultAddress)
 
 		// Order routes
 		api.POST("/order", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateOrder)
 		api.GET("/orders", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetOrders)
 		api.PATCH("/order/:id/status", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateOrderStatus)
 		api.GET("/order-management", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetAllOrders)
+		api.GET("/order/:id/invoice", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetOrderInvoice)
+
+		// Invoice routes
+		api.GET("/invoices", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetInvoices)
+
+		// Shipping routes
+		api.GET("/shipping-methods", Controllers.GetShippingMethods)
+		api.POST("/shipping-method", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CreateShippingMethod)
+		api.PUT("/shipping-method/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateShippingMethod)
+		api.DELETE("/shipping-method/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.DeleteShippingMethod)
+		api.POST("/shipping/quote", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetShippingQuote)
+
+		// Coupon routes
+		api.GET("/coupons", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetCoupons)
+		api.POST("/coupon", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CreateCoupon)
+		api.PUT("/coupon/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateCoupon)
+		api.DELETE("/coupon/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.DeleteCoupon)
+		api.POST("/coupon/validate", Middleware.AuthMiddleware(Middleware.Customer), Controllers.ValidateCoupon)
+
+		// Loyalty routes
+		api.GET("/me/points", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetMyPoints)
+
+		// Notification routes
+		api.GET("/me/notifications", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetMyNotifications)
+		api.POST("/me/notifications/read", Middleware.AuthMiddleware(Middleware.Customer), Controllers.MarkNotificationsRead)
+		api.GET("/me/product-alerts", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetMyProductAlerts)
+		api.DELETE("/product-alert/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.DeleteProductAlert)
+
+		// Promotion routes
+		api.GET("/promotions", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetPromotions)
+		api.GET("/promotions/active", Controllers.GetActivePromotions)
+		api.POST("/promotion", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CreatePromotion)
+		api.PUT("/promotion/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdatePromotion)
+		api.DELETE("/promotion/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.DeletePromotion)
+
+		// Review routes
+		api.GET("/reviews", Controllers.GetReviews)
+		api.POST("/review", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateReview)
+		api.POST("/review/:id/helpful", Middleware.AuthMiddleware(Middleware.Customer), Controllers.MarkReviewHelpful)
+		api.DELETE("/review/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.DeleteReview)
+		api.GET("/reviews/moderation", Middleware.AuthMiddleware(Middleware.Staff), Controllers.GetModerationReviews)
+		api.PATCH("/review/:id/moderate", Middleware.AuthMiddleware(Middleware.Staff), Controllers.ModerateReview)
+
+		// Payment routes
+		api.POST("/payments", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreatePayment)
+		api.GET("/payments", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetPayments)
+		api.GET("/payments/:provider/return", Controllers.PaymentReturn)
+		api.GET("/payments/:provider/ipn", Controllers.PaymentIPN)
+		api.POST("/payment/:id/refund", Middleware.AuthMiddleware(Middleware.Admin), Controllers.RefundPayment)
+		api.GET("/payments/ledger", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetPaymentLedger)
+		api.POST("/reconciliation/import", Middleware.AuthMiddleware(Middleware.Admin), Controllers.ImportSettlement)
+		api.GET("/reconciliation/reports", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetReconciliationReports)
+		api.GET("/reconciliation/report/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetReconciliationReport)
 
 		// SelectedItems routes
 		api.GET("/selecteditems", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetSelectedItems)
@@ -66,11 +153,45 @@ func SetupRoutes(router *gin.Engine) {
 		api.POST("/selecteditems/update", Middleware.AuthMiddleware(Middleware.Customer), Controllers.UpdateSelectedItems)
 		api.DELETE("/selecteditems/clear", Middleware.AuthMiddleware(Middleware.Customer), Controllers.ClearSelectedItems)
 
+		// ServiceArea routes
+		api.GET("/service-areas", Controllers.GetServiceAreas)
+		api.POST("/service-areas/check", Controllers.CheckServiceCoverage)
+		api.POST("/service-area", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CreateServiceArea)
+		api.PUT("/service-area/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateServiceArea)
+		api.DELETE("/service-area/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.DeleteServiceArea)
+
 		// OrderBookingService routes
 		api.POST("/orderbookingservice", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateOrderBookingService)
 		api.GET("/orderbookingservices", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetOrderBookingServices)
 		api.GET("/orderbookingservices/all", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetAllOrderBookingServices)
+		api.GET("/orderbookingservices/rating-prompts", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetStaffRatingPrompts)
 		api.PATCH("/orderbookingservice/:id/status", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateOrderBookingServiceStatus)
+		api.POST("/orderbookingservice/:id/confirm", Middleware.AuthMiddleware(Middleware.Customer), Controllers.ConfirmOrderBookingServiceCompletion)
+		api.POST("/orderbookingservice/:id/reschedule", Middleware.AuthMiddleware(Middleware.Customer), Controllers.RescheduleOrderBookingService)
+		api.POST("/orderbookingservice/:id/cancel", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CancelOrderBookingService)
+		api.GET("/orderbookingservice/:id/invoice", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetBookingInvoice)
+		api.PATCH("/orderbookingservice/:id/assign", Middleware.AuthMiddleware(Middleware.Admin), Controllers.AssignBookingStaff)
+		api.GET("/orderbookingservice/:id/suggested-staff", Middleware.AuthMiddleware(Middleware.Admin), Controllers.SuggestBookingStaff)
+		api.POST("/orderbookingservice/:id/rate-staff", Middleware.AuthMiddleware(Middleware.Customer), Controllers.RateBookingStaff)
+
+		// BookingPlan routes
+		api.POST("/booking-plan", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CreateBookingPlan)
+		api.GET("/booking-plans", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetBookingPlans)
+		api.GET("/booking-plan/:id", Middleware.AuthMiddleware(Middleware.Customer), Controllers.GetBookingPlan)
+		api.POST("/booking-plan/:id/pause", Middleware.AuthMiddleware(Middleware.Customer), Controllers.PauseBookingPlan)
+		api.POST("/booking-plan/:id/resume", Middleware.AuthMiddleware(Middleware.Customer), Controllers.ResumeBookingPlan)
+		api.POST("/booking-plan/:id/cancel", Middleware.AuthMiddleware(Middleware.Customer), Controllers.CancelBookingPlan)
+		api.POST("/booking-plan/:id/skip", Middleware.AuthMiddleware(Middleware.Customer), Controllers.SkipBookingPlanOccurrence)
+
+		// Staff routes
+		api.GET("/staff/profiles", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetStaffProfiles)
+		api.GET("/staff/:id/profile", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetStaffProfile)
+		api.PUT("/staff/:id/profile", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpsertStaffProfile)
+		api.GET("/staff/:id/ratings", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetStaffRatings)
+		api.GET("/staff/ratings/report", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetStaffRatingReport)
+		api.GET("/staff/jobs", Middleware.AuthMiddleware(Middleware.Staff), Controllers.GetStaffJobs)
+		api.POST("/staff/jobs/:id/check-in", Middleware.AuthMiddleware(Middleware.Staff), Controllers.CheckInStaffJob)
+		api.POST("/staff/jobs/:id/check-out", Middleware.AuthMiddleware(Middleware.Staff), Controllers.CheckOutStaffJob)
 
 		// Chat routes
 		api.POST("/create-chat", Controllers.CreateChat)
@@ -80,5 +201,18 @@ func SetupRoutes(router *gin.Engine) {
 		api.GET("/admin/notifications", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetNewChatRequests)
 		api.GET("/chat/:chatId/messages", Controllers.GetChatMessages)
 		api.GET("/chat/:chatId/info", Controllers.GetChatInfo)
+		api.POST("/chat/:chatId/attachments", Controllers.UploadChatAttachment)
+		api.GET("/chat/:chatId/transcript", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetChatTranscript)
+		api.POST("/chat/:chatId/close", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CloseChat)
+
+		// CannedResponse routes
+		api.GET("/canned-responses", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetAllCannedResponses)
+		api.GET("/canned-response/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.GetCannedResponseByID)
+		api.POST("/canned-response", Middleware.AuthMiddleware(Middleware.Admin), Controllers.CreateCannedResponse)
+		api.PUT("/canned-response/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.UpdateCannedResponse)
+		api.DELETE("/canned-response/:id", Middleware.AuthMiddleware(Middleware.Admin), Controllers.DeleteCannedResponse)
+
+		// Presence routes
+		api.GET("/presence", Controllers.GetOnlineStaff)
 	}
 }
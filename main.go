@@ -53,8 +53,35 @@ func main() {
 	database := client.Database("golang_project")
 	Controllers.Database = database
 
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	if err := Controllers.MigrateMoney(migrateCtx); err != nil {
+		log.Fatal("Money migration failed: ", err)
+	}
+	migrateCancel()
+
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), time.Minute)
+	Controllers.EnsureIndexes(indexCtx)
+	indexCancel()
+
+	if os.Getenv("CHAT_BROKER") == "mongo" {
+		Controllers.ChatHub = Controllers.NewHub(Controllers.NewMongoBroker(database.Collection("chat_events")))
+	}
+	go func() {
+		if err := Controllers.ChatHub.Run(context.Background()); err != nil {
+			log.Fatal("Chat hub stopped: ", err)
+		}
+	}()
+	go Controllers.Presence.Run(context.Background())
+	go Controllers.RunBookingPlanScheduler(context.Background())
+	go Controllers.RunReconciliationScheduler(context.Background())
+	go Controllers.RunProductAlertNotifier(context.Background())
+
 	router := gin.Default()
 
+	if secret := os.Getenv("VNPAY_HASH_SECRET"); secret != "" {
+		Controllers.RegisterPaymentProvider("vnpay", Controllers.NewVNPayProvider(os.Getenv("VNPAY_TMN_CODE"), secret, os.Getenv("VNPAY_PAY_URL"), os.Getenv("VNPAY_API_URL")))
+	}
+
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://cleeny.onrender.com"},
 		AllowMethods:     []string{"POST", "GET", "PUT", "PATCH", "DELETE"},